@@ -0,0 +1,192 @@
+// Command prep prepares a party's input files for the GWAS protocol:
+//
+//	prep genocounts -pgen "geno/chr%d" -keep sample_keep.txt -out dir   # all.gcount.transpose.bin
+//	prep snpinfo -pgen "geno/chr%d" -out dir                            # snp_pos.txt, snp_ids.txt, chrom_sizes.txt
+//	prep bed2bin -bed data.bed -samples 1000 -snps 4546 -out geno.bin   # .bed to an int8 matrix file
+//	prep filter -in geno.bin -rows 1000 -cols 4546 -row-filter rows.bin -col-filter cols.bin -out out.bin
+//	prep transpose -in geno.bin -rows 1000 -cols 4546 -out geno_t.bin
+//	prep merge -in-prefix geno -rows 1000 -sizes chrom_sizes.txt -out merged.bin  # geno.0.bin, geno.1.bin, ...
+//
+// Matrix files are sample-major with one int8 per entry (-1 if missing), as read by
+// gwas.NewGenoFileStream; filter files have one byte per row/column (nonzero to keep).
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/hhcho/sfgwas-private/gwas"
+)
+
+var commands = map[string]func(args []string) error{
+	"genocounts": genoCounts,
+	"snpinfo":    snpInfo,
+	"bed2bin":    bedToBinary,
+	"filter":     filterMatrix,
+	"transpose":  transposeMatrix,
+	"merge":      mergeMatrices,
+}
+
+func main() {
+	if len(os.Args) < 2 || commands[os.Args[1]] == nil {
+		fmt.Fprintln(os.Stderr, "usage: prep <genocounts|snpinfo|bed2bin|filter|transpose|merge> [flags]")
+		os.Exit(2)
+	}
+
+	if err := commands[os.Args[1]](os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, "prep:", err)
+		os.Exit(1)
+	}
+}
+
+func genoCounts(args []string) error {
+	fs := flag.NewFlagSet("genocounts", flag.ExitOnError)
+	pgen := fs.String("pgen", "", "pgen fileset of each chromosome, with a '%d' placeholder for chrom")
+	keep := fs.String("keep", "", "samples to keep (plink2 --keep format); all if empty")
+	chroms := fs.Int("chroms", 22, "number of chromosomes")
+	out := fs.String("out", "", "output directory, created if it does not exist")
+	fs.Parse(args)
+
+	if *pgen == "" || *out == "" {
+		return fmt.Errorf("genocounts: -pgen and -out are required")
+	}
+	if err := os.MkdirAll(*out, 0755); err != nil {
+		return err
+	}
+	gwas.ComputeGenoCounts(*pgen, *keep, *chroms, *out)
+	return nil
+}
+
+func snpInfo(args []string) error {
+	fs := flag.NewFlagSet("snpinfo", flag.ExitOnError)
+	pgen := fs.String("pgen", "", "pgen fileset of each chromosome, with a '%d' placeholder for chrom")
+	chroms := fs.Int("chroms", 22, "number of chromosomes")
+	out := fs.String("out", "", "output directory, created if it does not exist")
+	fs.Parse(args)
+
+	if *pgen == "" || *out == "" {
+		return fmt.Errorf("snpinfo: -pgen and -out are required")
+	}
+	if err := os.MkdirAll(*out, 0755); err != nil {
+		return err
+	}
+	gwas.WriteSnpInfoFiles(*pgen, *chroms, *out)
+	return nil
+}
+
+func bedToBinary(args []string) error {
+	fs := flag.NewFlagSet("bed2bin", flag.ExitOnError)
+	bed := fs.String("bed", "", "PLINK1 .bed file (SNP-major)")
+	samples := fs.Int("samples", 0, "number of samples (lines of the .fam)")
+	snps := fs.Int("snps", 0, "number of SNPs (lines of the .bim)")
+	out := fs.String("out", "", "output matrix file")
+	fs.Parse(args)
+
+	if *bed == "" || *out == "" || *samples <= 0 || *snps <= 0 {
+		return fmt.Errorf("bed2bin: -bed, -samples, -snps and -out are required")
+	}
+	gwas.BedToGenoFile(*bed, *samples, *snps, gwas.OnesBool(*samples), 0, *snps, *out)
+	return nil
+}
+
+func filterMatrix(args []string) error {
+	fs := flag.NewFlagSet("filter", flag.ExitOnError)
+	in := fs.String("in", "", "input matrix file")
+	rows := fs.Int("rows", 0, "number of rows")
+	cols := fs.Int("cols", 0, "number of columns")
+	rowFilt := fs.String("row-filter", "", "row filter file")
+	colFilt := fs.String("col-filter", "", "column filter file")
+	out := fs.String("out", "", "output matrix file")
+	fs.Parse(args)
+
+	if *in == "" || *out == "" || *rowFilt == "" || *colFilt == "" || *rows <= 0 || *cols <= 0 {
+		return fmt.Errorf("filter: -in, -rows, -cols, -row-filter, -col-filter and -out are required")
+	}
+	rowKeep, err := readByteFilter(*rowFilt, *rows)
+	if err != nil {
+		return err
+	}
+	colKeep, err := readByteFilter(*colFilt, *cols)
+	if err != nil {
+		return err
+	}
+	gwas.FilterMatrixFile(*in, *rows, *cols, rowKeep, colKeep, *out)
+	return nil
+}
+
+func transposeMatrix(args []string) error {
+	fs := flag.NewFlagSet("transpose", flag.ExitOnError)
+	in := fs.String("in", "", "input matrix file")
+	rows := fs.Int("rows", 0, "number of rows")
+	cols := fs.Int("cols", 0, "number of columns")
+	out := fs.String("out", "", "output matrix file")
+	fs.Parse(args)
+
+	if *in == "" || *out == "" || *rows <= 0 || *cols <= 0 {
+		return fmt.Errorf("transpose: -in, -rows, -cols and -out are required")
+	}
+	gwas.TransposeMatrixFile(*in, *rows, *cols, *out)
+	return nil
+}
+
+func mergeMatrices(args []string) error {
+	fs := flag.NewFlagSet("merge", flag.ExitOnError)
+	inPrefix := fs.String("in-prefix", "", "input matrix files are <in-prefix>.<i>.bin, i = 0, 1, ...")
+	rows := fs.Int("rows", 0, "number of rows")
+	sizes := fs.String("sizes", "", "number of columns of each input file, one per line")
+	out := fs.String("out", "", "output matrix file")
+	fs.Parse(args)
+
+	if *inPrefix == "" || *sizes == "" || *out == "" || *rows <= 0 {
+		return fmt.Errorf("merge: -in-prefix, -rows, -sizes and -out are required")
+	}
+	ncols, err := readInts(*sizes)
+	if err != nil {
+		return err
+	}
+	gwas.MergeBlockFiles(*inPrefix, *rows, ncols, *out)
+	return nil
+}
+
+func readByteFilter(filename string, n int) ([]bool, error) {
+	buf, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if len(buf) != n {
+		return nil, fmt.Errorf("%s has %d entries, expected %d", filename, len(buf), n)
+	}
+
+	filt := make([]bool, n)
+	for i := range filt {
+		filt[i] = buf[i] != 0
+	}
+	return filt, nil
+}
+
+func readInts(filename string) ([]int, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var out []int
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		v, err := strconv.Atoi(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", filename, err)
+		}
+		out = append(out, v)
+	}
+	return out, scanner.Err()
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	mpc_core "github.com/hhcho/mpc-core"
+	"github.com/hhcho/sfgwas-private/mpc"
+	"go.dedis.ch/onet/v3/log"
+)
+
+type benchFunction struct {
+	f      func(float64) float64
+	lo, hi float64
+}
+
+var functions = map[string]benchFunction{
+	"sin":     {math.Sin, -math.Pi, math.Pi},
+	"cos":     {math.Cos, -math.Pi, math.Pi},
+	"sigmoid": {func(x float64) float64 { return 1 / (1 + math.Exp(-x)) }, -8, 8},
+	"tanh":    {math.Tanh, -4, 4},
+	"exp":     {math.Exp, -2, 2},
+	"sqrtinv": {func(x float64) float64 { return 1 / math.Sqrt(x) }, 0.25, 4},
+	"step": {func(x float64) float64 {
+		if x >= 0 {
+			return 1
+		}
+		return 0
+	}, -1, 1},
+}
+
+type benchConfig struct {
+	Function string
+	Method   mpc.ApproxMethod
+	Degree   int
+	FracBits int
+}
+
+// Result is one row of the report
+type Result struct {
+	Function      string   `json:"function"`
+	Method        string   `json:"method"`
+	Degree        int      `json:"degree"`
+	FracBits      int      `json:"frac_bits"`
+	NumInputs     int      `json:"num_inputs"`
+	MaxErr        float64  `json:"max_err"`
+	MeanAbsErr    float64  `json:"mean_abs_err"`
+	MSE           float64  `json:"mse"`
+	MeanRelErr    float64  `json:"mean_rel_err"`
+	ApproxMaxErr  float64  `json:"approx_max_err"`  // Cleartext approximation error
+	ApproxMeanErr float64  `json:"approx_mean_err"` // Cleartext approximation error
+	WallTimeSec   float64  `json:"wall_time_sec"`
+	SentBytes     []uint64 `json:"sent_bytes"` // Per party, including party 0
+}
+
+// approxBench measures the accuracy and cost of secure function approximations. All parties
+// run in-process over InProcTransport; every combination of function x method x degree x
+// fracBits is evaluated on the same random inputs and written as one row of a CSV or JSON report
+func approxBench(args []string) error {
+	fs := flag.NewFlagSet("approx-bench", flag.ExitOnError)
+	functionsFlag := fs.String("functions", "sin,sigmoid,tanh", "comma-separated functions ("+strings.Join(functionNames(), ", ")+")")
+	methodsFlag := fs.String("methods", "taylor,chebyshev,remez", "comma-separated methods (taylor, chebyshev, remez, fourier)")
+	degreesFlag := fs.String("degrees", "5,9,13", "comma-separated polynomial degrees (number of harmonics for fourier)")
+	fracBitsFlag := fs.String("frac-bits", "20,30", "comma-separated fixed-point fractional bits")
+	polyEvalFlag := fs.String("poly-eval", "powers", "polynomial evaluation: powers, horner or ps")
+	dataBits := fs.Int("data-bits", 60, "fixed-point data bits")
+	fieldSize := fs.Int("field-size", 256, "MPC field size (256 or 128)")
+	numParties := fs.Int("parties", 2, "number of data-holding parties (party 0 is added)")
+	numInputs := fs.Int("n", 1000, "number of random inputs per configuration")
+	seed := fs.Int64("seed", 1, "seed for the random inputs")
+	out := fs.String("out", "approxbench.csv", "report path; .json writes JSON, anything else CSV")
+	fs.Parse(args)
+
+	configs, err := sweep(*functionsFlag, *methodsFlag, *degreesFlag, *fracBitsFlag)
+	if err != nil {
+		return err
+	}
+
+	polyEval, err := parsePolyEval(*polyEvalFlag)
+	if err != nil {
+		return err
+	}
+
+	var rtype mpc_core.RElem
+	switch *fieldSize {
+	case 256:
+		rtype = mpc_core.LElem256Zero
+	case 128:
+		rtype = mpc_core.LElem128Zero
+	default:
+		return fmt.Errorf("unsupported -field-size %d", *fieldSize)
+	}
+
+	np := *numParties + 1
+	results := make([]Result, len(configs))
+	for i := range results {
+		results[i].SentBytes = make([]uint64, np)
+	}
+
+	var wg sync.WaitGroup
+	for pid := 0; pid < np; pid++ {
+		wg.Add(1)
+		go func(pid int) {
+			defer wg.Done()
+			runBenchParty(pid, np, rtype, *dataBits, *numInputs, *seed, polyEval, configs, results)
+		}(pid)
+	}
+	wg.Wait()
+
+	if err := writeReport(*out, results); err != nil {
+		return err
+	}
+	log.LLvl1(fmt.Sprintf("approx-bench: %d configurations written to %s", len(results), *out))
+	return nil
+}
+
+func runBenchParty(pid, np int, rtype mpc_core.RElem, dataBits, numInputs int, seed int64, polyEval mpc.PolyEvalMethod, configs []benchConfig, results []Result) {
+	tr, err := mpc.NewTransport(mpc.TransportInProc, "", nil, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	networks, err := mpc.InitCommunication(tr, pid, np, 1, mpc.SharedKeyConfig{Exchange: true}, mpc.Timeouts{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	nets := mpc.ParallelNetworks(networks)
+	mpcEnv := mpc.InitParallelMPCEnv(nets, rtype, dataBits, configs[0].FracBits)
+	mpcObj := mpcEnv[0]
+	mpcObj.SetHubPid(1)
+	for _, n := range nets {
+		n.EnableLogging()
+	}
+
+	for i, cfg := range configs {
+		fn := functions[cfg.Function]
+		mpcObj.SetFracBits(cfg.FracBits)
+
+		ap := mpc.NewApproximator(fn.f, fn.lo, fn.hi, cfg.Method, cfg.Degree)
+		ap.PolyEval = polyEval
+
+		// Same inputs for every configuration of a function; party 1 holds them in full
+		rng := rand.New(rand.NewSource(seed))
+		x := make([]float64, numInputs)
+		for j := range x {
+			x[j] = fn.lo + (fn.hi-fn.lo)*rng.Float64()
+		}
+		var xRV mpc_core.RVec
+		if pid == 1 {
+			xRV = mpc_core.FloatToRVec(rtype, x, cfg.FracBits)
+		} else {
+			xRV = mpc_core.InitRVec(rtype.Zero(), numInputs)
+		}
+
+		mpcObj.AssertSync()
+		nets.ResetNetworkLog()
+
+		start := time.Now()
+		yRV := mpcObj.EvaluateApprox(ap, xRV)
+		elapsed := time.Since(start)
+
+		var sent uint64
+		for _, b := range nets[0].SentBytes {
+			sent += b
+		}
+		results[i].SentBytes[pid] = sent
+
+		y := mpcObj.RevealSymVec(yRV).ToFloat(cfg.FracBits)
+
+		if pid == 1 {
+			res := &results[i]
+			res.Function = cfg.Function
+			res.Method = cfg.Method.String()
+			res.Degree = cfg.Degree
+			res.FracBits = cfg.FracBits
+			res.NumInputs = numInputs
+			res.ApproxMaxErr, res.ApproxMeanErr = ap.MaxErr, ap.MeanErr
+			res.MaxErr, res.MeanAbsErr = ap.Error(x, y)
+			res.WallTimeSec = elapsed.Seconds()
+
+			numRel := 0
+			for j := range x {
+				expected := fn.f(x[j])
+				res.MSE += (y[j] - expected) * (y[j] - expected)
+				if math.Abs(expected) > 1e-12 {
+					res.MeanRelErr += math.Abs((y[j] - expected) / expected)
+					numRel++
+				}
+			}
+			res.MSE /= float64(numInputs)
+			if numRel > 0 {
+				res.MeanRelErr /= float64(numRel)
+			}
+
+			log.LLvl1(fmt.Sprintf("%s %s degree %d fracBits %d: max err %.3e, MAE %.3e, %.3fs",
+				res.Function, res.Method, res.Degree, res.FracBits, res.MaxErr, res.MeanAbsErr, res.WallTimeSec))
+		}
+	}
+
+	mpcObj.AssertSync()
+	for _, n := range nets {
+		n.CloseAll()
+	}
+}
+
+func sweep(functionsFlag, methodsFlag, degreesFlag, fracBitsFlag string) ([]benchConfig, error) {
+	var methods []mpc.ApproxMethod
+	for _, s := range splitList(methodsFlag) {
+		m, err := mpc.ParseApproxMethod(s)
+		if err != nil {
+			return nil, err
+		}
+		methods = append(methods, m)
+	}
+
+	degrees, err := parseInts(degreesFlag)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -degrees: %w", err)
+	}
+	fracBits, err := parseInts(fracBitsFlag)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -frac-bits: %w", err)
+	}
+
+	var configs []benchConfig
+	for _, name := range splitList(functionsFlag) {
+		if _, ok := functions[name]; !ok {
+			return nil, fmt.Errorf("unknown function %q (expected one of %s)", name, strings.Join(functionNames(), ", "))
+		}
+		for _, m := range methods {
+			for _, d := range degrees {
+				for _, fb := range fracBits {
+					configs = append(configs, benchConfig{Function: name, Method: m, Degree: d, FracBits: fb})
+				}
+			}
+		}
+	}
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("empty sweep")
+	}
+	return configs, nil
+}
+
+func parsePolyEval(s string) (mpc.PolyEvalMethod, error) {
+	switch s {
+	case "powers":
+		return mpc.PolyEvalPowers, nil
+	case "horner":
+		return mpc.PolyEvalHorner, nil
+	case "ps":
+		return mpc.PolyEvalPatersonStockmeyer, nil
+	}
+	return 0, fmt.Errorf("unknown -poly-eval %q (expected powers, horner or ps)", s)
+}
+
+func writeReport(path string, results []Result) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if filepath.Ext(path) == ".json" {
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		return enc.Encode(results)
+	}
+
+	w := csv.NewWriter(f)
+	header := []string{"function", "method", "degree", "frac_bits", "num_inputs", "max_err", "mean_abs_err", "mse",
+		"mean_rel_err", "approx_max_err", "approx_mean_err", "wall_time_sec"}
+	for p := range results[0].SentBytes {
+		header = append(header, fmt.Sprintf("sent_bytes_party%d", p))
+	}
+	if err := w.Write(header); err != nil {
+		return err
+	}
+
+	g := func(v float64) string { return strconv.FormatFloat(v, 'g', 6, 64) }
+	for _, r := range results {
+		row := []string{r.Function, r.Method, strconv.Itoa(r.Degree), strconv.Itoa(r.FracBits), strconv.Itoa(r.NumInputs),
+			g(r.MaxErr), g(r.MeanAbsErr), g(r.MSE), g(r.MeanRelErr), g(r.ApproxMaxErr), g(r.ApproxMeanErr), g(r.WallTimeSec)}
+		for _, b := range r.SentBytes {
+			row = append(row, strconv.FormatUint(b, 10))
+		}
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func functionNames() []string {
+	names := make([]string, 0, len(functions))
+	for name := range functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func parseInts(s string) ([]int, error) {
+	var out []int
+	for _, p := range splitList(s) {
+		v, err := strconv.Atoi(p)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
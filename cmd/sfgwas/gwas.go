@@ -0,0 +1,112 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/hhcho/sfgwas-private/gwas"
+	"github.com/hhcho/sfgwas-private/mpc"
+)
+
+// Phases of the protocol (gwas.ProtocolInfo.Phase1, Phase2 and Phase3)
+const (
+	phaseQC = 1 << iota
+	phasePCA
+	phaseAssoc
+)
+
+var phaseNames = map[string]int{"qc": phaseQC, "pca": phasePCA, "assoc": phaseAssoc}
+
+func parsePhases(s string) (int, error) {
+	phases := 0
+	for _, p := range strings.Split(s, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if phaseNames[p] == 0 {
+			return 0, fmt.Errorf("unknown phase %q (expected qc, pca or assoc)", p)
+		}
+		phases |= phaseNames[p]
+	}
+	if phases == 0 {
+		return 0, fmt.Errorf("no phases selected")
+	}
+	return phases, nil
+}
+
+func runGWAS(args []string) error {
+	fs := flag.NewFlagSet("gwas", flag.ExitOnError)
+	pf := addPartyFlags(fs)
+	phasesFlag := fs.String("phases", "qc,pca,assoc", "comma-separated phases to run; skipped earlier phases are read from the cache")
+	fs.Parse(args)
+
+	phases, err := parsePhases(*phasesFlag)
+	if err != nil {
+		return err
+	}
+	return runProtocolPhases(pf, phases)
+}
+
+// runPhases runs a single phase (the qc, pca and assoc commands)
+func runPhases(name string, args []string, phases int) error {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	pf := addPartyFlags(fs)
+	fs.Parse(args)
+
+	return runProtocolPhases(pf, phases)
+}
+
+func runProtocolPhases(pf partyFlags, phases int) error {
+	ok, err := runParties(pf, func(config *gwas.Config, pid int) bool {
+		if err := runProtocol(config, pid, phases); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return false
+		}
+		return true
+	})
+	if err == nil && !ok {
+		err = fmt.Errorf("protocol aborted")
+	}
+	return err
+}
+
+// runProtocol runs the selected phases up to the last one selected. QC always runs since the
+// later phases need its filters; like PCA, it is read from the cache if it is not selected,
+// after the artifact manifests of the earlier run are checked (gwas.ProtocolInfo.CheckPhaseInputs).
+// Returns the network failure that aborted the parties, if any
+func runProtocol(config *gwas.Config, pid, phases int) (err error) {
+	defer mpc.RecoverAbort(pid, &err)
+
+	if config.LocalNumThreads > 0 {
+		runtime.GOMAXPROCS(config.LocalNumThreads)
+	}
+
+	if phases&phaseQC == 0 && !config.SkipQC {
+		config.UseCachedQC = true
+	}
+	if phases&phasePCA == 0 && phases&phaseAssoc != 0 && !config.SkipPCA {
+		config.UseCachedPCA = true
+	}
+
+	prot := gwas.InitializeGWASProtocol(config, pid, false)
+
+	if phases == phaseQC|phasePCA|phaseAssoc {
+		prot.GWAS()
+	} else {
+		prot.CheckPhaseInputs()
+		prot.Phase1()
+		if phases&(phasePCA|phaseAssoc) != 0 {
+			Qpca := prot.Phase2()
+			if phases&phaseAssoc != 0 {
+				prot.Phase3(Qpca)
+			}
+		}
+	}
+
+	prot.SyncAndTerminate(true)
+	return nil
+}
@@ -0,0 +1,27 @@
+package main
+
+import "testing"
+
+func TestParsePhases(t *testing.T) {
+	tests := []struct {
+		in     string
+		phases int
+		ok     bool
+	}{
+		{"qc,pca,assoc", phaseQC | phasePCA | phaseAssoc, true},
+		{"assoc", phaseAssoc, true},
+		{"pca, assoc", phasePCA | phaseAssoc, true},
+		{"assoc,qc", phaseQC | phaseAssoc, true},
+		{"qc,qc,", phaseQC, true},
+		{"", 0, false},
+		{" , ", 0, false},
+		{"qc,ld", 0, false},
+		{"QC", 0, false},
+	}
+	for _, tt := range tests {
+		phases, err := parsePhases(tt.in)
+		if (err == nil) != tt.ok || phases != tt.phases {
+			t.Errorf("parsePhases(%q) = (%b, %v), want %b (ok %t)", tt.in, phases, err, tt.phases, tt.ok)
+		}
+	}
+}
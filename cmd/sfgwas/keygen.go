@@ -0,0 +1,68 @@
+package main
+
+import (
+	"crypto/rand"
+	"flag"
+	"fmt"
+
+	"github.com/aead/chacha20/chacha"
+	"github.com/hhcho/sfgwas-private/mpc"
+	"go.dedis.ch/onet/v3/log"
+)
+
+// keygen writes the shared PRG keys of every party (shared_key_global.bin and
+// shared_key_<a>_<b>.bin) to one directory. Only meant for toy examples and tests: whoever
+// runs it knows every key. Use shared_key_exchange = true in real settings
+func keygen(args []string) error {
+	fs := flag.NewFlagSet("keygen", flag.ExitOnError)
+	pf := addPartyFlags(fs)
+	out := fs.String("out", "", "output directory (defaults to shared_keys_path of the party's config)")
+	fs.Parse(args)
+
+	pid := 0
+	if *pf.party >= 0 {
+		pid = *pf.party
+	}
+	config, err := loadConfig(*pf.configDir, pid)
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		*out = config.SharedKeysPath
+	}
+	if *out == "" {
+		return fmt.Errorf("set --out or shared_keys_path")
+	}
+
+	np := config.NumMainParties + 1
+	global := randomKey()
+	pairwise := make([][][]byte, np)
+	for a := range pairwise {
+		pairwise[a] = make([][]byte, np)
+		for b := 0; b < a; b++ {
+			pairwise[a][b] = randomKey()
+			pairwise[b][a] = pairwise[a][b]
+		}
+	}
+
+	for p := 0; p < np; p++ {
+		keys := &mpc.SharedKeys{Global: global, Pairwise: make(map[int][]byte)}
+		for other := 0; other < np; other++ {
+			if other != p {
+				keys.Pairwise[other] = pairwise[p][other]
+			}
+		}
+		mpc.SaveSharedKeys(p, keys, *out)
+	}
+
+	log.LLvl1(fmt.Sprintf("keygen: shared keys of %d parties written to %s", np, *out))
+	return nil
+}
+
+func randomKey() []byte {
+	key := make([]byte, chacha.KeySize)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return key
+}
@@ -0,0 +1,151 @@
+// Command sfgwas runs the secure federated GWAS protocol and its tools:
+//
+//	sfgwas gwas --config-dir config/ --party 1 [--phases qc,pca,assoc]  # QC, PCA and association tests
+//	sfgwas qc|pca|assoc --config-dir config/ --party 1                  # one phase, reusing the caches of the earlier ones
+//	sfgwas validate --config-dir config/ --party 1                      # check the inputs before a run
+//	sfgwas keygen --config-dir config/                                  # toy shared PRG keys for shared_keys_path
+//	sfgwas approx-bench -functions sin,sigmoid -methods chebyshev,remez -out approx.csv
+//
+// Every party runs the same command with its own ID (--party, or the PID environment
+// variable); with transport = "inproc" all parties run in this process.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	"github.com/hhcho/sfgwas-private/gwas"
+	"github.com/hhcho/sfgwas-private/mpc"
+)
+
+var commands = map[string]func(args []string) error{
+	"gwas":         runGWAS,
+	"qc":           func(args []string) error { return runPhases("qc", args, phaseQC) },
+	"pca":          func(args []string) error { return runPhases("pca", args, phasePCA) },
+	"assoc":        func(args []string) error { return runPhases("assoc", args, phaseAssoc) },
+	"validate":     validate,
+	"keygen":       keygen,
+	"approx-bench": approxBench,
+}
+
+func main() {
+	if len(os.Args) < 2 || commands[os.Args[1]] == nil {
+		fmt.Fprintf(os.Stderr, "usage: sfgwas <%s> [flags]\n", strings.Join(commandNames(), "|"))
+		os.Exit(2)
+	}
+
+	if err := commands[os.Args[1]](os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "sfgwas %s: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
+
+func commandNames() []string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// partyFlags are the flags shared by the commands that run as one party of the protocol
+type partyFlags struct {
+	configDir *string
+	party     *int
+}
+
+func addPartyFlags(fs *flag.FlagSet) partyFlags {
+	return partyFlags{
+		configDir: fs.String("config-dir", "config/", "directory with configGlobal.toml and configLocal.PartyN.toml"),
+		party:     fs.Int("party", -1, "party ID (defaults to the PID environment variable)"),
+	}
+}
+
+// pid returns the --party flag, or the PID environment variable if it is not set
+func (f partyFlags) pid() (int, error) {
+	if *f.party >= 0 {
+		return *f.party, nil
+	}
+	pid, err := strconv.Atoi(os.Getenv("PID"))
+	if err != nil {
+		return 0, fmt.Errorf("set --party or the PID environment variable")
+	}
+	return pid, nil
+}
+
+// loadConfig reads the global and local config of party pid and creates its cache and
+// output directories
+func loadConfig(configDir string, pid int) (*gwas.Config, error) {
+	config := new(gwas.Config)
+	if _, err := toml.DecodeFile(filepath.Join(configDir, "configGlobal.toml"), config); err != nil {
+		return nil, err
+	}
+	if _, err := toml.DecodeFile(filepath.Join(configDir, fmt.Sprintf("configLocal.Party%d.toml", pid)), config); err != nil {
+		return nil, err
+	}
+	for _, dir := range []string{config.CacheDir, config.OutDir} {
+		if dir == "" {
+			continue
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+	}
+	return config, nil
+}
+
+// runParties loads the config of the selected party and calls run with it, or, with
+// transport = "inproc", calls run for every party in its own goroutine. Returns whether
+// run returned true for all of them
+func runParties(f partyFlags, run func(config *gwas.Config, pid int) bool) (bool, error) {
+	pid, err := f.pid()
+	if err != nil {
+		return false, err
+	}
+	config, err := loadConfig(*f.configDir, pid)
+	if err != nil {
+		return false, err
+	}
+
+	pids := []int{pid}
+	configs := []*gwas.Config{config}
+	if config.Transport == mpc.TransportInProc {
+		pids, configs = nil, nil
+		for p := 0; p <= config.NumMainParties; p++ {
+			c := config
+			if p != pid {
+				if c, err = loadConfig(*f.configDir, p); err != nil {
+					return false, err
+				}
+			}
+			pids = append(pids, p)
+			configs = append(configs, c)
+		}
+	}
+
+	ok := make([]bool, len(pids))
+	var wg sync.WaitGroup
+	for i := range pids {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ok[i] = run(configs[i], pids[i])
+		}(i)
+	}
+	wg.Wait()
+
+	for _, v := range ok {
+		if !v {
+			return false, nil
+		}
+	}
+	return true, nil
+}
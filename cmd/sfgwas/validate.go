@@ -0,0 +1,26 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/hhcho/sfgwas-private/gwas"
+)
+
+// validate checks the inputs of a party against the config before the protocol is started:
+// file dimensions, covariate/phenotype contents, unique SNP IDs, genotype file sizes, and
+// (over the network) that every party holds the same SNPs and shared parameters
+func validate(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	pf := addPartyFlags(fs)
+	fs.Parse(args)
+
+	ok, err := runParties(pf, gwas.ValidateGWASInputs)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("inputs failed validation")
+	}
+	return nil
+}
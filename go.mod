@@ -10,6 +10,7 @@ require (
 	github.com/ldsec/lattigo/v2 v2.3.0
 	github.com/ldsec/unlynx v1.4.3
 	go.dedis.ch/onet/v3 v3.2.10
+	golang.org/x/crypto v0.0.0-20210921155107-089bfa567519
 	gonum.org/v1/gonum v0.9.3
 	gonum.org/v1/plot v0.9.0
 )
@@ -33,7 +34,6 @@ require (
 	go.dedis.ch/kyber/v3 v3.0.13 // indirect
 	go.dedis.ch/protobuf v1.0.11 // indirect
 	go.etcd.io/bbolt v1.3.6 // indirect
-	golang.org/x/crypto v0.0.0-20210921155107-089bfa567519 // indirect
 	golang.org/x/image v0.0.0-20210216034530-4410531fe030 // indirect
 	golang.org/x/sys v0.0.0-20211117180635-dee7805ff2e1 // indirect
 	golang.org/x/text v0.3.5 // indirect
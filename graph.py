@@ -1,28 +1,41 @@
-import numpy as np
-import matplotlib.pyplot as plt
+"""Plot an approxbench report: secure max error vs degree, one panel per function.
 
-# Load data from results.txt
-data = np.loadtxt("results.txt")
+Usage: python graph.py [approxbench.csv] [output.png]
+"""
+import csv
+import sys
+from collections import defaultdict
 
-x = data[:, 0]  # x values
-computed_y = data[:, 1]  # Computed sin(x)
-expected_y = data[:, 2]  # True sin(x)
+import matplotlib.pyplot as plt
 
-# Approximate function
-approximate_y = 2.33 * np.sin((np.pi / 9) * x)
+report = sys.argv[1] if len(sys.argv) > 1 else "approxbench.csv"
+output = sys.argv[2] if len(sys.argv) > 2 else None
 
-# Plot the results
-plt.figure(figsize=(10, 5))
-plt.plot(x, expected_y, label="Expected sin(x)", color="blue", linestyle="dashed")
-plt.plot(x, computed_y, label="Computed (Remez Approximation)", color="red")
-plt.plot(x, approximate_y, label="Approximate Function (2.33 * sin(pi/9 * x))", color="green", linestyle="dotted")
+# (function) -> (method, frac_bits) -> [(degree, max_err, approx_max_err)]
+series = defaultdict(lambda: defaultdict(list))
+with open(report) as f:
+    for row in csv.DictReader(f):
+        key = (row["method"], int(row["frac_bits"]))
+        series[row["function"]][key].append(
+            (int(row["degree"]), float(row["max_err"]), float(row["approx_max_err"])))
 
-# Labels and legend
-plt.xlabel("x (radians)")
-plt.ylabel("sin(x)")
-plt.title("Comparison of Expected sin(x), Remez Approximation, and Approximate Function")
-plt.legend()
-plt.grid(True)
+functions = sorted(series)
+fig, axes = plt.subplots(1, len(functions), figsize=(5 * len(functions), 4), squeeze=False)
+for ax, fn in zip(axes[0], functions):
+    for (method, frac_bits), points in sorted(series[fn].items()):
+        points.sort()
+        degrees = [p[0] for p in points]
+        line, = ax.plot(degrees, [p[1] for p in points], marker="o", label=f"{method}, f={frac_bits}")
+        ax.plot(degrees, [p[2] for p in points], linestyle="dotted", color=line.get_color())
+    ax.set_yscale("log")
+    ax.set_xlabel("degree")
+    ax.set_ylabel("max abs error (dotted: cleartext)")
+    ax.set_title(fn)
+    ax.grid(True)
+    ax.legend(fontsize="small")
 
-# Show the plot
-plt.show()
+fig.tight_layout()
+if output:
+    fig.savefig(output)
+else:
+    plt.show()
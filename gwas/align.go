@@ -0,0 +1,538 @@
+package gwas
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.dedis.ch/onet/v3/log"
+
+	"github.com/hhcho/sfgwas-private/mpc"
+)
+
+// Cross-site SNP alignment (align_snps): before QC the data parties match their variants
+// by chromosome, position and alleles, keep the ones that all of them hold, and recode
+// their genotypes to count the same allele. Variants are compared as keyed hashes under a
+// key that party 0 does not know; party 0 intersects them and learns only the list sizes,
+// the block of each variant and which entries match (including relative allele/strand flips)
+
+var complementBase = map[byte]byte{'A': 'T', 'T': 'A', 'C': 'G', 'G': 'C'}
+
+// reverseComplement returns the allele on the other strand, or false unless it is ACGT only
+func reverseComplement(allele string) (string, bool) {
+	out := make([]byte, len(allele))
+	for i := range allele {
+		c, ok := complementBase[allele[len(allele)-1-i]]
+		if !ok {
+			return "", false
+		}
+		out[i] = c
+	}
+	return string(out), true
+}
+
+// alignKey returns the key of a biallelic variant that does not depend on the strand or the
+// order of the alleles. The alleles of the key are sorted on the strand where they sort first;
+// flip is set if the variant's ALT is the first allele (its genotypes must be recoded to count
+// the second) and strand if the key is on the other strand. Palindromic variants (A/T, C/G)
+// are ambiguous: their strand cannot be told from the alleles
+func alignKey(v VcfVariant) (key string, flip, strand, ambiguous, ok bool) {
+	chrom, ok := parseChromNumber(v.Chrom)
+	if !ok || v.Ref == "" || v.Alt == "" || v.Ref == v.Alt || v.Ref == "." || v.Alt == "." || strings.Contains(v.Alt, ",") {
+		return "", false, false, false, false
+	}
+
+	ref, alt := v.Ref, v.Alt
+	x, y := sortedPair(ref, alt)
+	if rcRef, ok := reverseComplement(ref); ok {
+		if rcAlt, ok := reverseComplement(alt); ok {
+			rx, ry := sortedPair(rcRef, rcAlt)
+			ambiguous = rx == x && ry == y
+			if !ambiguous && (rx < x || (rx == x && ry < y)) {
+				ref, alt, x, y, strand = rcRef, rcAlt, rx, ry, true
+			}
+		}
+	}
+
+	return fmt.Sprintf("%d:%d:%s:%s", chrom, v.Pos, x, y), alt == x, strand, ambiguous, true
+}
+
+func sortedPair(a, b string) (string, string) {
+	if b < a {
+		return b, a
+	}
+	return a, b
+}
+
+// AlignmentResult is the output of the alignment at a data party: per block, which columns
+// of the genotype streams are kept and which of them are recoded (2 - x)
+type AlignmentResult struct {
+	ColFilt   [][]bool
+	Flip      [][]bool
+	Variants  []VcfVariant // Shared variants in order, with ALT the counted allele
+	NumShared int
+}
+
+// LoadAlignVariants returns the variants of every block of this party's genotype input, in
+// the column order of the streams created by InitializeGWASProtocol
+func LoadAlignVariants(config *Config, blockSizes []int) [][]VcfVariant {
+	out := make([][]VcfVariant, len(blockSizes))
+
+	switch config.GenoFileFormat {
+	case "pgen":
+		// The streams keep the variants of snp_ids_file in .pvar order
+		snpIDs := LoadSNPIDFile(config.SnpIdsFile)
+		shift := 0
+		for b, size := range blockSizes {
+			keep := make(map[string]bool, size)
+			for _, id := range snpIDs[shift : shift+size] {
+				keep[id] = true
+			}
+			pvarFile := fmt.Sprintf(config.GenoFilePrefix, b+1) + ".pvar" // 1-based
+			for _, v := range LoadPvarVariants(pvarFile) {
+				if keep[v.ID] {
+					out[b] = append(out[b], v)
+				}
+			}
+			shift += size
+		}
+
+	case "bed":
+		variants := LoadPvarVariants(config.GenoFilePrefix + ".bim")
+		shift := 0
+		for b, size := range blockSizes {
+			out[b] = variants[shift : shift+size]
+			shift += size
+		}
+
+	case "vcf":
+		for b := range blockSizes {
+			vr := NewVcfReader(fmt.Sprintf(config.GenoFilePrefix, b+1), "") // 1-based
+			for vr.NextVariant() {
+				out[b] = append(out[b], vr.Variant())
+			}
+			vr.Close()
+		}
+
+	case "blocks":
+		if config.SnpAllelesFile == "" {
+			panic("snp_alleles_file is required to align \"blocks\" input")
+		}
+		pos := LoadSNPPositionFile(config.SnpPosFile, '\t')
+		alleles := loadAllelesFile(config.SnpAllelesFile)
+		var ids []string
+		if config.SnpIdsFile != "" {
+			ids = LoadSNPIDFile(config.SnpIdsFile)
+		}
+		if len(alleles) != len(pos) || (ids != nil && len(ids) != len(pos)) {
+			panic(fmt.Sprintf("%s has %d lines but %s has %d", config.SnpAllelesFile, len(alleles), config.SnpPosFile, len(pos)))
+		}
+
+		shift := 0
+		for b, size := range blockSizes {
+			for j := shift; j < shift+size; j++ {
+				v := VcfVariant{Chrom: strconv.FormatUint(pos[j]/1e9, 10), Pos: pos[j] % 1e9, Ref: alleles[j][0], Alt: alleles[j][1]}
+				if ids != nil {
+					v.ID = ids[j]
+				} else {
+					v.ID = fmt.Sprintf("%s:%d", v.Chrom, v.Pos)
+				}
+				out[b] = append(out[b], v)
+			}
+			shift += size
+		}
+
+	default:
+		panic(fmt.Sprint("Unsupported geno_file_format:", config.GenoFileFormat))
+	}
+
+	for b, size := range blockSizes {
+		if len(out[b]) != size {
+			panic(fmt.Sprintf("block %d has %d variants with alleles, expected %d", b, len(out[b]), size))
+		}
+	}
+	return out
+}
+
+// Each variant is sent to party 0 as a 128-bit keyed hash of its alignKey and a word holding
+// the block index and the flip/strand bits, masked with bits of the keyed hash so that only
+// their differences between parties are revealed
+const alignWordsPerVariant = 3
+
+type alignEntry struct {
+	block, col   int
+	flip, strand bool
+}
+
+// AlignSnps runs the alignment protocol over net. variants (per block, see LoadAlignVariants)
+// is nil at party 0. Returns the number of shared SNPs at every party and the alignment at
+// the data parties
+func AlignSnps(net *mpc.Network, config *Config, variants [][]VcfVariant) *AlignmentResult {
+	pid, hub := net.GetPid(), config.HubPartyId
+	if pid == 0 {
+		return &AlignmentResult{NumShared: alignAtDealer(net, config)}
+	}
+
+	// Key shared by the data parties
+	keyWords := make([]uint64, sha256.Size/8)
+	if pid == hub {
+		key := make([]byte, sha256.Size)
+		if _, err := rand.Read(key); err != nil {
+			panic(err)
+		}
+		for i := range keyWords {
+			keyWords[i] = binary.LittleEndian.Uint64(key[8*i:])
+		}
+		for p := 1; p <= config.NumMainParties; p++ {
+			if p != hub {
+				net.SendIntVector(keyWords, p)
+			}
+		}
+	} else {
+		keyWords = net.ReceiveIntVector(len(keyWords), hub)
+	}
+	key := make([]byte, sha256.Size)
+	for i, w := range keyWords {
+		binary.LittleEndian.PutUint64(key[8*i:], w)
+	}
+
+	// Keyed hashes of the alignable variants; variants whose key is repeated are dropped
+	var entries []alignEntry
+	var words []uint64
+	var numInvalid, numAmbiguous int
+	seen := make(map[[2]uint64]int)
+	for b := range variants {
+		for j, v := range variants[b] {
+			k, flip, strand, ambiguous, ok := alignKey(v)
+			if !ok {
+				numInvalid++
+				continue
+			}
+			if ambiguous && !config.AlignKeepAmbiguous {
+				numAmbiguous++
+				continue
+			}
+
+			mac := hmac.New(sha256.New, key)
+			mac.Write([]byte(k))
+			sum := mac.Sum(nil)
+			tag := [2]uint64{binary.LittleEndian.Uint64(sum), binary.LittleEndian.Uint64(sum[8:])}
+			meta := uint64(b)<<2 | uint64(sum[16]&3)
+			if flip {
+				meta ^= 2
+			}
+			if strand {
+				meta ^= 1
+			}
+
+			seen[tag]++
+			entries = append(entries, alignEntry{block: b, col: j, flip: flip, strand: strand})
+			words = append(words, tag[0], tag[1], meta)
+		}
+	}
+
+	numDup := 0
+	n := 0
+	for i := range entries {
+		tag := [2]uint64{words[alignWordsPerVariant*i], words[alignWordsPerVariant*i+1]}
+		if seen[tag] > 1 {
+			numDup++
+			continue
+		}
+		entries[n] = entries[i]
+		copy(words[alignWordsPerVariant*n:], words[alignWordsPerVariant*i:alignWordsPerVariant*(i+1)])
+		n++
+	}
+	entries, words = entries[:n], words[:alignWordsPerVariant*n]
+
+	log.LLvl1(time.Now().Format(time.RFC3339), fmt.Sprintf("SNP alignment: %d variants to match; skipped %d not biallelic or on unknown chromosomes, %d palindromic (A/T, C/G), %d duplicated",
+		len(entries), numInvalid, numAmbiguous, numDup))
+
+	net.SendIntVector([]uint64{uint64(len(entries))}, 0)
+	if len(entries) > 0 {
+		net.SendIntVector(words, 0)
+	}
+
+	// Entries shared by all parties, in the same order everywhere
+	header := net.ReceiveIntVector(3, 0)
+	numShared := int(header[0])
+	if numShared == 0 {
+		log.Fatalf("SNP alignment: no variants are shared by all parties")
+	}
+	shared := net.ReceiveIntVector(numShared, 0)
+
+	res := &AlignmentResult{
+		ColFilt:   make([][]bool, len(variants)),
+		Flip:      make([][]bool, len(variants)),
+		Variants:  make([]VcfVariant, numShared),
+		NumShared: numShared,
+	}
+	for b := range variants {
+		res.ColFilt[b] = make([]bool, len(variants[b]))
+		res.Flip[b] = make([]bool, len(variants[b]))
+	}
+	numFlipped := 0
+	for i, idx := range shared {
+		e := entries[idx]
+		res.ColFilt[e.block][e.col] = true
+		res.Flip[e.block][e.col] = e.flip
+
+		v := variants[e.block][e.col]
+		if e.flip {
+			v.Ref, v.Alt = v.Alt, v.Ref
+			numFlipped++
+		}
+		res.Variants[i] = v
+	}
+
+	log.LLvl1(time.Now().Format(time.RFC3339), fmt.Sprintf("SNP alignment: %d of %d variants shared by all parties; %d recoded to count the other allele; relative to the hub, %d on the other strand and %d with REF/ALT swapped",
+		numShared, Sum(blockSizesOf(variants)), numFlipped, header[1], header[2]))
+
+	return res
+}
+
+// alignAtDealer intersects the keyed hashes of the data parties at party 0. The shared
+// variants are kept in the hub's order, dropping any that would be in another order (or
+// block) at some party; each party receives the indices of its shared entries, along with
+// the number of them on the other strand or with swapped alleles relative to the hub
+func alignAtDealer(net *mpc.Network, config *Config) int {
+	np := config.NumMainParties + 1
+	hub := config.HubPartyId
+
+	words := make([][]uint64, np)
+	index := make([]map[[2]uint64]int, np)
+	for p := 1; p < np; p++ {
+		n := int(net.ReceiveIntVector(1, p)[0])
+		if n > 0 {
+			words[p] = net.ReceiveIntVector(alignWordsPerVariant*n, p)
+		}
+		index[p] = make(map[[2]uint64]int, n)
+		for i := 0; i < n; i++ {
+			index[p][[2]uint64{words[p][alignWordsPerVariant*i], words[p][alignWordsPerVariant*i+1]}] = i
+		}
+	}
+
+	shared := make([][]uint64, np)
+	strandFlips, alleleSwaps := make([]uint64, np), make([]uint64, np)
+	last := make([]int, np)
+	for p := range last {
+		last[p] = -1
+	}
+	numOutOfOrder := 0
+	idx := make([]int, np)
+	for i := 0; i < len(words[hub])/alignWordsPerVariant; i++ {
+		tag := [2]uint64{words[hub][alignWordsPerVariant*i], words[hub][alignWordsPerVariant*i+1]}
+		meta := words[hub][alignWordsPerVariant*i+2]
+
+		inAll, inOrder := true, true
+		for p := 1; p < np; p++ {
+			j, ok := index[p][tag]
+			if !ok {
+				inAll = false
+				break
+			}
+			idx[p] = j
+			if j <= last[p] || words[p][alignWordsPerVariant*j+2]>>2 != meta>>2 {
+				inOrder = false
+			}
+		}
+		if !inAll {
+			continue
+		}
+		if !inOrder {
+			numOutOfOrder++
+			continue
+		}
+
+		for p := 1; p < np; p++ {
+			diff := words[p][alignWordsPerVariant*idx[p]+2] ^ meta
+			strandFlips[p] += diff & 1
+			alleleSwaps[p] += (diff >> 1) & 1
+			shared[p] = append(shared[p], uint64(idx[p]))
+			last[p] = idx[p]
+		}
+	}
+
+	numShared := len(shared[hub])
+	for p := 1; p < np; p++ {
+		net.SendIntVector([]uint64{uint64(numShared), strandFlips[p], alleleSwaps[p]}, p)
+		if numShared > 0 {
+			net.SendIntVector(shared[p], p)
+		}
+		log.LLvl1(time.Now().Format(time.RFC3339), fmt.Sprintf("SNP alignment: party %d sent %d variants; %d on the other strand and %d with REF/ALT swapped relative to the hub",
+			p, len(words[p])/alignWordsPerVariant, strandFlips[p], alleleSwaps[p]))
+	}
+	log.LLvl1(time.Now().Format(time.RFC3339), fmt.Sprintf("SNP alignment: %d variants shared by all parties (%d dropped for a different order or block)", numShared, numOutOfOrder))
+
+	if numShared == 0 {
+		log.Fatalf("SNP alignment: no variants are shared by all parties")
+	}
+	return numShared
+}
+
+func blockSizesOf(variants [][]VcfVariant) []int {
+	sizes := make([]int, len(variants))
+	for b := range variants {
+		sizes[b] = len(variants[b])
+	}
+	return sizes
+}
+
+// alignGenoInput aligns the SNPs of all parties (see AlignSnps) and replaces the genotype
+// streams of a data party with streams over the shared SNPs, cached in cache_dir. The SNP
+// info files (and geno_count_file) of the config are replaced with aligned copies and
+// num_snps with the number of shared SNPs at every party
+func alignGenoInput(net *mpc.Network, config *Config, pid int, genofs []*GenoFileStream, blockSizes []int) ([]*GenoFileStream, []int, []uint64) {
+	log.LLvl1(time.Now().Format(time.RFC3339), "Starting SNP alignment")
+
+	if pid == 0 {
+		config.NumSnps = AlignSnps(net, config, nil).NumShared
+		return genofs, blockSizes, nil
+	}
+
+	res := AlignSnps(net, config, LoadAlignVariants(config, blockSizes))
+
+	snpIdsFile := filepath.Join(config.CacheDir, "align_snp_ids.txt")
+	snpPosFile := filepath.Join(config.CacheDir, "align_snp_pos.txt")
+	blockSizeFile := filepath.Join(config.CacheDir, "align_block_sizes.txt")
+	w := newSnpInfoWriter(snpIdsFile, snpPosFile, blockSizeFile)
+	alignedSizes := make([]int, len(blockSizes))
+	shift := 0
+	for b := range blockSizes {
+		alignedSizes[b] = SumBool(res.ColFilt[b])
+		for _, v := range res.Variants[shift : shift+alignedSizes[b]] {
+			w.Add(v.Chrom, v.Pos, v.ID)
+		}
+		w.EndBlock()
+		shift += alignedSizes[b]
+	}
+	w.Close()
+	writeAlleleFile(filepath.Join(config.CacheDir, "align_alleles.txt"), res.Variants)
+
+	if config.UsePrecomputedGenoCount {
+		countFile := filepath.Join(config.CacheDir, "align_gcount.transpose.bin")
+		AlignGenoCountFile(config.GenoCountFile, Sum(blockSizes), concatFilters(res.ColFilt), concatFilters(res.Flip), countFile)
+		config.GenoCountFile = countFile
+	}
+
+	aligned := make([]*GenoFileStream, len(genofs))
+	for b := range genofs {
+		cacheFile := filepath.Join(config.CacheDir, fmt.Sprintf("geno_align%s.%d.bin", dosageSuffix(config), b))
+		aligned[b] = NewAlignedGenoFileStream(genofs[b], res.ColFilt[b], res.Flip[b], cacheFile, false)
+	}
+
+	config.SnpIdsFile, config.SnpPosFile, config.GenoBlockSizeFile = snpIdsFile, snpPosFile, blockSizeFile
+	config.NumSnps = res.NumShared
+
+	return aligned, alignedSizes, LoadSNPPositionFile(snpPosFile, '\t')
+}
+
+func concatFilters(filt [][]bool) []bool {
+	var out []bool
+	for _, f := range filt {
+		out = append(out, f...)
+	}
+	return out
+}
+
+// writeAlleleFile writes "REF\tALT" per variant, ALT being the allele counted by the genotypes
+func writeAlleleFile(filename string, variants []VcfVariant) {
+	file, err := os.Create(filename)
+	if err != nil {
+		panic(err)
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	for _, v := range variants {
+		fmt.Fprintf(writer, "%s\t%s\n", v.Ref, v.Alt)
+	}
+	if err := writer.Flush(); err != nil {
+		panic(err)
+	}
+}
+
+// AlignGenoCountFile writes the counts of a geno_count_file (see ComputeGenoCounts) for the
+// kept SNPs, swapping the reference and alternate counts of the flipped ones
+func AlignGenoCountFile(inputFile string, numSnps int, colFilt, flip []bool, outputFile string) {
+	file, err := os.Open(inputFile)
+	if err != nil {
+		panic(err)
+	}
+	defer file.Close()
+
+	var counts [numGenoCountTypes][]uint32
+	reader := bufio.NewReader(file)
+	for t := range counts {
+		counts[t] = make([]uint32, numSnps)
+		if err := binary.Read(reader, binary.LittleEndian, counts[t]); err != nil {
+			panic(fmt.Sprintf("%s: %v", inputFile, err))
+		}
+	}
+	if _, err := reader.ReadByte(); err != io.EOF {
+		panic(fmt.Sprintf("%s has more than %d SNPs", inputFile, numSnps))
+	}
+
+	var aligned [numGenoCountTypes][]uint32
+	for j := range colFilt {
+		if !colFilt[j] {
+			continue
+		}
+		swap := [numGenoCountTypes]int{gcountHomRef, gcountHet, gcountHomAlt, gcountHapRef, gcountHapAlt, gcountMissing}
+		if flip[j] {
+			swap[gcountHomRef], swap[gcountHomAlt] = gcountHomAlt, gcountHomRef
+			swap[gcountHapRef], swap[gcountHapAlt] = gcountHapAlt, gcountHapRef
+		}
+		for t := range aligned {
+			aligned[t] = append(aligned[t], counts[swap[t]][j])
+		}
+	}
+
+	out, err := os.Create(outputFile)
+	if err != nil {
+		panic(err)
+	}
+	defer out.Close()
+
+	writer := bufio.NewWriter(out)
+	for t := range aligned {
+		if err := binary.Write(writer, binary.LittleEndian, aligned[t]); err != nil {
+			panic(err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		panic(err)
+	}
+}
+
+// loadAllelesFile reads a snp_alleles_file ("REF\tALT" per SNP)
+func loadAllelesFile(filename string) [][2]string {
+	file, err := os.Open(filename)
+	if err != nil {
+		panic(err)
+	}
+	defer file.Close()
+
+	var out [][2]string
+	scanner := bufio.NewScanner(file)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		tok := strings.Fields(scanner.Text())
+		if len(tok) != 2 {
+			panic(fmt.Sprintf("%s:%d: expected REF and ALT", filename, lineno))
+		}
+		out = append(out, [2]string{tok[0], tok[1]})
+	}
+	if err := scanner.Err(); err != nil {
+		panic(err)
+	}
+	return out
+}
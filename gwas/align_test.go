@@ -0,0 +1,45 @@
+package gwas
+
+import "testing"
+
+func TestAlignKey(t *testing.T) {
+	tests := []struct {
+		name                        string
+		v                           VcfVariant
+		key                         string
+		flip, strand, ambiguous, ok bool
+	}{
+		// One SNP as the parties may report it: either allele as ALT, on either strand
+		{"A/G", VcfVariant{"1", 100, "rs1", "A", "G"}, "1:100:A:G", false, false, false, true},
+		{"G/A, alleles swapped", VcfVariant{"chr1", 100, "rs1", "G", "A"}, "1:100:A:G", true, false, false, true},
+		{"T/C, other strand", VcfVariant{"1", 100, "rs1", "T", "C"}, "1:100:A:G", false, true, false, true},
+		{"C/T, other strand, swapped", VcfVariant{"1", 100, "rs1", "C", "T"}, "1:100:A:G", true, true, false, true},
+
+		// Palindromic SNPs are their own reverse complement
+		{"A/T", VcfVariant{"2", 5, ".", "A", "T"}, "2:5:A:T", false, false, true, true},
+		{"T/A", VcfVariant{"2", 5, ".", "T", "A"}, "2:5:A:T", true, false, true, true},
+		{"C/G", VcfVariant{"2", 5, ".", "C", "G"}, "2:5:C:G", false, false, true, true},
+		{"G/C", VcfVariant{"2", 5, ".", "G", "C"}, "2:5:C:G", true, false, true, true},
+
+		// Indels: AT/A on this strand (A < AT) sorts before AT/T on the other
+		{"AT/A", VcfVariant{"3", 7, ".", "AT", "A"}, "3:7:A:AT", true, false, false, true},
+		{"AT/T, other strand", VcfVariant{"3", 7, ".", "AT", "T"}, "3:7:A:AT", true, true, false, true},
+
+		// Alleles other than ACGT keep their strand
+		{"A/N", VcfVariant{"4", 9, ".", "A", "N"}, "4:9:A:N", false, false, false, true},
+		{"chrX", VcfVariant{"chrX", 9, ".", "C", "A"}, "23:9:A:C", true, false, false, true},
+
+		{"multiallelic", VcfVariant{"1", 100, ".", "A", "G,T"}, "", false, false, false, false},
+		{"REF = ALT", VcfVariant{"1", 100, ".", "A", "A"}, "", false, false, false, false},
+		{"missing ALT", VcfVariant{"1", 100, ".", "A", "."}, "", false, false, false, false},
+		{"unknown chromosome", VcfVariant{"chrUn", 100, ".", "A", "G"}, "", false, false, false, false},
+	}
+
+	for _, tt := range tests {
+		key, flip, strand, ambiguous, ok := alignKey(tt.v)
+		if key != tt.key || flip != tt.flip || strand != tt.strand || ambiguous != tt.ambiguous || ok != tt.ok {
+			t.Errorf("%s: alignKey = (%q, flip %t, strand %t, ambiguous %t, ok %t), want (%q, %t, %t, %t, %t)",
+				tt.name, key, flip, strand, ambiguous, ok, tt.key, tt.flip, tt.strand, tt.ambiguous, tt.ok)
+		}
+	}
+}
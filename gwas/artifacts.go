@@ -0,0 +1,266 @@
+package gwas
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.dedis.ch/onet/v3/log"
+)
+
+// Phase artifacts: every phase records the files it leaves for the later phases in a
+// manifest (manifest.<phase>.json in cache_dir) with their SHA-256 and the hash of the
+// config that produced them. A run that reads a phase from the cache (use_cached_qc,
+// use_cached_pca, use_cached_combined_q, or --phases) first checks the manifests at every party.
+//
+// Ciphertexts are only valid under the collective keys of the run that produced them, so the
+// PCA output is handed off as each party's decrypted rows of Qpc (re-encrypted on load)
+
+const artifactVersion = 1 // Bump when the files or their format change
+
+// Phase names used in the manifests
+const (
+	PhaseQC    = "qc"
+	PhasePCA   = "pca"
+	PhaseAssoc = "assoc"
+)
+
+// ArtifactManifest describes the artifacts of one phase at one party
+type ArtifactManifest struct {
+	Version    int               `json:"version"`
+	Phase      string            `json:"phase"`
+	Party      int               `json:"party"`
+	ConfigHash string            `json:"config_hash"`
+	Created    string            `json:"created"`
+	Files      map[string]string `json:"files"` // Path -> SHA-256 of the contents
+}
+
+// artifactConfigHashes hashes, for each phase, this party's inputs and the parameters the
+// outputs of the phase depend on (including those of the earlier phases). They are computed
+// before the config is modified at startup
+func artifactConfigHashes(config *Config) map[string]string {
+	numSnps := config.NumSnps
+	if config.AlignSnps { // Set by the alignment
+		numSnps = 0
+	}
+
+	h := sha256.New()
+	fmt.Fprint(h, artifactVersion, config.NumMainParties, config.HubPartyId, config.NumInds, numSnps, config.GenoNumBlocks,
+		config.GenoFileFormat, config.GenoFilePrefix, config.VcfDosageField, config.GenoDosage, config.GenoBlockSizeFile,
+		config.SnpPosFile, config.GenoCountFile, config.SampleKeepFile, config.SnpIdsFile, config.SnpAllelesFile,
+		config.AlignSnps, config.AlignKeepAmbiguous, config.SkipQC, config.UsePrecomputedGenoCount,
+		config.IndMissUB, config.HetLB, config.HetUB, config.SnpMissUB, config.MafLB, config.HweUB)
+	qc := hex.EncodeToString(h.Sum(nil))
+
+	fmt.Fprint(h, config.CkksParams, config.MpcFieldSize, config.MpcDataBits, config.MpcFracBits, config.SkipPCA,
+		config.NumPCs, config.NumOversample, config.NumPowerIters, config.ItersPerEval, config.SnpDistThres)
+	pca := hex.EncodeToString(h.Sum(nil))
+
+	shared := sharedConfigHash(config)
+	h.Write(shared[:])
+	fmt.Fprint(h, config.PhenoFile, config.CovFile, config.BlocksForAssoc)
+	assoc := hex.EncodeToString(h.Sum(nil))
+
+	return map[string]string{PhaseQC: qc, PhasePCA: pca, PhaseAssoc: assoc}
+}
+
+func (g *ProtocolInfo) manifestPath(phase string) string {
+	return g.CachePath(fmt.Sprintf("manifest.%s.json", phase))
+}
+
+// phaseArtifacts lists the files a phase hands off to the later ones at this party
+func (g *ProtocolInfo) phaseArtifacts(phase string) []string {
+	if g.mpcObj[0].GetPid() == 0 {
+		return nil
+	}
+
+	switch phase {
+	case PhaseQC:
+		if g.config.SkipQC && !g.config.UseCachedQC {
+			return nil
+		} else if g.config.UsePrecomputedGenoCount {
+			return []string{g.CachePath("gkeep.txt")}
+		}
+		return []string{g.CachePath("gkeep_miss.txt"), g.CachePath("ikeep.txt"), g.CachePath("gkeep_maf_hwe.txt")}
+	case PhasePCA:
+		if g.config.SkipPCA && !g.config.UseCachedPCA {
+			return nil
+		}
+		return []string{g.CachePath("Qpc.txt")}
+	case PhaseAssoc:
+		files := []string{g.CachePath("Qcomb.bin")}
+		for t := 0; t < g.NumPhenos(); t++ {
+			files = append(files, g.OutPath("assoc"+g.phenoSuffix(t)+".txt"), g.SumStatsPath(t))
+		}
+		return files
+	}
+	panic(fmt.Sprint("Unknown phase:", phase))
+}
+
+// WritePhaseManifest records the artifacts of a completed phase
+func (g *ProtocolInfo) WritePhaseManifest(phase string) {
+	m := ArtifactManifest{
+		Version:    artifactVersion,
+		Phase:      phase,
+		Party:      g.mpcObj[0].GetPid(),
+		ConfigHash: g.configHash[phase],
+		Created:    time.Now().Format(time.RFC3339),
+		Files:      make(map[string]string),
+	}
+	for _, filename := range g.phaseArtifacts(phase) {
+		sum, err := fileSHA256(filename)
+		if err != nil {
+			panic(err)
+		}
+		m.Files[filename] = sum
+	}
+
+	buf, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	writeFileAtomic(g.manifestPath(phase), buf, 0644)
+
+	log.LLvl1(time.Now().Format(time.RFC3339), fmt.Sprintf("Phase %s: %d artifacts recorded in %s", phase, len(m.Files), g.manifestPath(phase)))
+}
+
+// checkPhaseManifest returns why the artifacts of a phase cannot be used, or nil if they can
+func (g *ProtocolInfo) checkPhaseManifest(phase string) []string {
+	filename := g.manifestPath(phase)
+	buf, err := os.ReadFile(filename)
+	if err != nil {
+		return []string{fmt.Sprintf("no artifacts of phase %s (%v); run it first", phase, err)}
+	}
+
+	var m ArtifactManifest
+	if err := json.Unmarshal(buf, &m); err != nil {
+		return []string{fmt.Sprintf("%s: %v", filename, err)}
+	}
+
+	var problems []string
+	if m.Version != artifactVersion {
+		problems = append(problems, fmt.Sprintf("%s has version %d, expected %d; rerun phase %s", filename, m.Version, artifactVersion, phase))
+	}
+	if m.Phase != phase || m.Party != g.mpcObj[0].GetPid() {
+		problems = append(problems, fmt.Sprintf("%s is for phase %s of party %d", filename, m.Phase, m.Party))
+	}
+	if m.ConfigHash != g.configHash[phase] {
+		problems = append(problems, fmt.Sprintf("%s was produced with a different config or inputs; rerun phase %s", filename, phase))
+	}
+	for _, f := range g.phaseArtifacts(phase) {
+		expected, ok := m.Files[f]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s does not list %s", filename, f))
+			continue
+		}
+		if sum, err := fileSHA256(f); err != nil {
+			problems = append(problems, err.Error())
+		} else if sum != expected {
+			problems = append(problems, fmt.Sprintf("%s was modified after phase %s", f, phase))
+		}
+	}
+	return problems
+}
+
+// CheckPhaseInputs checks the manifests of the phases that will be read from the cache, and
+// stops every party (via the hub) if any of them cannot be used
+func (g *ProtocolInfo) CheckPhaseInputs() {
+	var phases []string
+	if g.config.UseCachedQC {
+		phases = append(phases, PhaseQC)
+	}
+	if g.config.UseCachedPCA {
+		phases = append(phases, PhasePCA)
+	}
+	if g.config.UseCachedCombinedQ {
+		phases = append(phases, PhaseAssoc)
+	}
+	if len(phases) == 0 {
+		return
+	}
+
+	var problems []string
+	for _, phase := range phases {
+		problems = append(problems, g.checkPhaseManifest(phase)...)
+	}
+	for _, msg := range problems {
+		log.LLvl1(time.Now().Format(time.RFC3339), "Cached artifacts:", msg)
+	}
+
+	// Agree on the result so that no party starts alone
+	mpcObj := g.mpcObj[0]
+	pid, hub := mpcObj.GetPid(), mpcObj.GetHubPid()
+	status := make([]uint64, mpcObj.GetNParty())
+	if len(problems) > 0 {
+		status[pid] = 1
+	}
+	if pid == hub {
+		for p := 0; p < mpcObj.GetNParty(); p++ {
+			if p != hub {
+				status[p] = mpcObj.Network.ReceiveIntVector(1, p)[0]
+			}
+		}
+		for p := 0; p < mpcObj.GetNParty(); p++ {
+			if p != hub {
+				mpcObj.Network.SendIntVector(status, p)
+			}
+		}
+	} else {
+		mpcObj.Network.SendIntVector(status[pid:pid+1], hub)
+		status = mpcObj.Network.ReceiveIntVector(len(status), hub)
+	}
+
+	var failed []int
+	for p, s := range status {
+		if s != 0 {
+			failed = append(failed, p)
+		}
+	}
+	if len(failed) > 0 {
+		log.Fatalf("Cached artifacts of %v cannot be used at parties %v (see their logs)", phases, failed)
+	}
+	log.LLvl1(time.Now().Format(time.RFC3339), "Cached artifacts verified:", phases)
+}
+
+func fileSHA256(filename string) (string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeFileAtomic writes data to a temporary file next to filename and renames it, so that
+// readers see either the old or the new contents. The file has permissions perm
+func writeFileAtomic(filename string, data []byte, perm os.FileMode) {
+	tmp, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".tmp*")
+	if err != nil {
+		panic(err)
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		panic(err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		panic(err)
+	}
+	if err := tmp.Sync(); err != nil {
+		panic(err)
+	}
+	if err := tmp.Close(); err != nil {
+		panic(err)
+	}
+	if err := os.Rename(tmp.Name(), filename); err != nil {
+		panic(err)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"go.dedis.ch/onet/v3/log"
 
 	"github.com/hhcho/sfgwas-private/crypto"
+	"github.com/hhcho/sfgwas-private/mpc"
 
 	"gonum.org/v1/gonum/mat"
 )
@@ -19,7 +20,7 @@ import (
 type AssocTest struct {
 	general *ProtocolInfo
 
-	pheno crypto.PlainVector
+	pheno crypto.PlainMatrix // One vector per phenotype
 
 	inputCov crypto.PlainMatrix
 	Qpc      crypto.CipherMatrix
@@ -34,16 +35,16 @@ func (g *ProtocolInfo) InitAssociationTests(Qpc crypto.CipherMatrix) *AssocTest
 	gwasParams := g.gwasParams
 	cps := g.cps
 
-	var phenoEnc crypto.PlainVector
+	var phenoEnc crypto.PlainMatrix
 	var covEnc crypto.PlainMatrix
 
 	if pid > 0 {
-		phenoEnc = crypto.EncodeDense(cps, mat.DenseCopyOf(g.pheno))[0]
+		phenoEnc = crypto.EncodeDense(cps, mat.DenseCopyOf(g.pheno))
 		covEnc = crypto.EncodeDense(cps, mat.DenseCopyOf(g.cov))
 		r, c := g.cov.Dims()
 		log.LLvl1(time.Now().Format(time.RFC3339), "Cov dims:", r, c)
 	} else {
-		phenoEnc = make(crypto.PlainVector, 0)
+		phenoEnc = make(crypto.PlainMatrix, 0)
 		covEnc = make(crypto.PlainMatrix, gwasParams.NumCov())
 		log.LLvl1(time.Now().Format(time.RFC3339), "Cov dims:", 0, gwasParams.NumCov())
 	}
@@ -262,6 +263,27 @@ func (ast *AssocTest) computeCombinedQ(C crypto.PlainMatrix, Qpc crypto.CipherMa
 	return Q
 }
 
+// assocNumCtx returns the number of ciphertexts GenoBlockMult outputs for a block with nsnps
+// QC-passing SNPs: pgen blocks are processed in batches of pgen_batch_size SNPs, each padded
+// to whole ciphertexts
+func (g *ProtocolInfo) assocNumCtx(nsnps int) int {
+	slots := g.cps.GetSlots()
+	if !g.IsPgen() {
+		if nsnps == 0 {
+			return 0
+		}
+		return 1 + (nsnps-1)/slots
+	}
+
+	numCtx := 0
+	for nleft := nsnps; nleft > 0; {
+		bsize := Min(nleft, g.config.PgenBatchSize)
+		numCtx += 1 + (bsize-1)/slots
+		nleft -= bsize
+	}
+	return numCtx
+}
+
 func (ast *AssocTest) GenoBlockMult(b int, mat crypto.CipherMatrix) (matOut crypto.CipherMatrix, dosageSum, dosageSqSum []float64, filtOut []bool) {
 	cryptoParams := ast.general.cps
 
@@ -269,6 +291,7 @@ func (ast *AssocTest) GenoBlockMult(b int, mat crypto.CipherMatrix) (matOut cryp
 
 	slots := cryptoParams.GetSlots()
 	isPgen := ast.general.IsPgen()
+	useDosage := ast.general.config.GenoDosage
 	pgenBatchSize := ast.general.config.PgenBatchSize
 
 	XBlock := ast.general.genoBlocks[b]
@@ -300,18 +323,10 @@ func (ast *AssocTest) GenoBlockMult(b int, mat crypto.CipherMatrix) (matOut cryp
 		return
 	}
 
-	numCtx := 0
-	if isPgen {
-		for nleft := nsnps; nleft > 0; {
-			bsize := Min(nleft, pgenBatchSize)
-			numCtx += 1 + (bsize-1)/slots
-			nleft -= bsize
-		}
-	} else {
-		numCtx = 1 + (nsnps-1)/slots
-	}
+	numCtx := ast.general.assocNumCtx(nsnps)
 
-	multFile := ast.general.CachePath(fmt.Sprintf("assoc_cache_mult.%d.bin", b))
+	// The product carries every phenotype, so a cache from a run with another num_phenos is not reused
+	multFile := ast.general.CachePath(fmt.Sprintf("assoc_cache_mult.%d.%dpheno.bin", b, ast.general.NumPhenos()))
 	dosFile := ast.general.CachePath(fmt.Sprintf("assoc_cache_dos_sum.%d.txt", b))
 	dos2File := ast.general.CachePath(fmt.Sprintf("assoc_cache_dos_sqsum.%d.txt", b))
 	filtFile := ast.general.CachePath(fmt.Sprintf("assoc_cache_filt.%d.txt", b))
@@ -363,6 +378,7 @@ func (ast *AssocTest) GenoBlockMult(b int, mat crypto.CipherMatrix) (matOut cryp
 			}
 
 			var wg sync.WaitGroup
+			panics := mpc.NewWorkerPanics(pid)
 
 			for idx := 0; idx < blockSize; idx++ {
 				if snpFilt[idx] {
@@ -370,21 +386,29 @@ func (ast *AssocTest) GenoBlockMult(b int, mat crypto.CipherMatrix) (matOut cryp
 				}
 
 				if counter == pgenBatchSize || (idx == blockSize-1 && counter > 0) {
-					// Fetch an available thread
+					// Fetch an available thread, unless a batch failed
 					threadId := <-threadPool
+					panics.Raise()
 					wg.Add(1)
 
 					go func(threadId, batchIndex, startIndex, idx, counter, shift, outShift int) {
 						defer wg.Done()
+						defer func() { threadPool <- threadId }() // Return thread to pool
+						defer panics.Recover()
 
 						start := time.Now()
 						log.LLvl1(time.Now().Format(time.RFC3339), fmt.Sprintf("MatMult: block %d/%d, batch %d/%d, thread %d started", b+1, numBlocks, batchIndex+1, nbatch, threadId))
 
 						batchFilt := snpFilt[startIndex : idx+1]
 						gfsTempFile := ast.general.CachePath(fmt.Sprintf("pgen_gfs.%d.tmp", threadId))
-						FilterMatrixFilePgen(pgenFile, numInd, counter, ast.general.config.SampleKeepFile, ast.general.config.SnpIdsFile, shift+startIndex, batchFilt, gfsTempFile)
+						FilterMatrixFilePgen(pgenFile, numInd, counter, ast.general.config.SampleKeepFile, ast.general.config.SnpIdsFile, shift+startIndex, batchFilt, useDosage, gfsTempFile)
 
-						X := NewGenoFileStream(gfsTempFile, uint64(numInd), uint64(counter), true)
+						var X *GenoFileStream
+						if useDosage {
+							X = NewDosageFileStream(gfsTempFile, uint64(numInd), uint64(counter), true)
+						} else {
+							X = NewGenoFileStream(gfsTempFile, uint64(numInd), uint64(counter), true)
+						}
 
 						mult, sum, sqSum := MatMult4Stream(cryptoParams, mat, X, 5, true, nprocsPerBlock)
 
@@ -396,9 +420,6 @@ func (ast *AssocTest) GenoBlockMult(b int, mat crypto.CipherMatrix) (matOut cryp
 						}
 
 						log.LLvl1(time.Now().Format(time.RFC3339), fmt.Sprintf("MatMult: block %d/%d, batch %d/%d, thread %d finished,", b+1, numBlocks, batchIndex, nbatch, threadId), "elapsed time", time.Since(start))
-
-						// Return thread to pool
-						threadPool <- threadId
 					}(threadId, batchIndex, startIndex, idx, counter, int(shift), outShift)
 
 					nctx := 1 + (counter-1)/slots
@@ -410,6 +431,7 @@ func (ast *AssocTest) GenoBlockMult(b int, mat crypto.CipherMatrix) (matOut cryp
 			}
 
 			wg.Wait() // Wait until all batches are finished
+			panics.Raise()
 			close(threadPool)
 
 			matOut = crypto.ConcatCipherMatrix(outMult)
@@ -424,17 +446,69 @@ func (ast *AssocTest) GenoBlockMult(b int, mat crypto.CipherMatrix) (matOut cryp
 
 		log.LLvl1(time.Now().Format(time.RFC3339), "MatMult: block", b+1, "/", numBlocks, "elapsed time", time.Since(start))
 
-		// Save cache
-		crypto.SaveCipherMatrixToFile(cryptoParams, matOut, multFile)
-		SaveFloatVectorToFile(dosFile, dosageSum)
-		SaveFloatVectorToFile(dos2File, dosageSqSum)
-		writeFilterToFile(filtFile, filtOut, true)
+		// Save cache (the filter last, since its presence marks a complete cache)
+		saveAtomic(multFile, func(tmpName string) { crypto.SaveCipherMatrixToFile(cryptoParams, matOut, tmpName) })
+		saveAtomic(dosFile, func(tmpName string) { SaveFloatVectorToFile(tmpName, dosageSum) })
+		saveAtomic(dos2File, func(tmpName string) { SaveFloatVectorToFile(tmpName, dosageSqSum) })
+		saveAtomic(filtFile, func(tmpName string) { writeFilterToFile(tmpName, filtOut, true) })
 	}
 
 	return
 }
 
-func (ast *AssocTest) GetAssociationStats() (crypto.CipherVector, []bool) {
+// checkpointBlock saves the outputs of block b (nil for none) and records the block as complete.
+// The hub tells party 0, which records the same checkpoints
+func (ast *AssocTest) checkpointBlock(b int, sx, sxx, sxy crypto.CipherMatrix, filt []bool) {
+	g := ast.general
+	if g.ckpt == nil {
+		return
+	}
+
+	if sx != nil {
+		out := append(crypto.CipherMatrix{sx[0], sxx[0]}, sxy...)
+		saveAtomic(g.checkpointPath("assoc.%d.bin", b), func(tmpName string) {
+			crypto.SaveCipherMatrixToFile(g.cps, out, tmpName)
+		})
+		saveAtomic(g.checkpointPath("assoc_filt.%d.txt", b), func(tmpName string) {
+			writeFilterToFile(tmpName, filt, true)
+		})
+	}
+	g.saveCheckpoint(PhaseAssoc, b)
+
+	mpcObj := g.mpcObj[0]
+	if mpcObj.GetPid() == mpcObj.GetHubPid() {
+		mpcObj.Network.SendIntVector([]uint64{uint64(b)}, 0)
+	}
+}
+
+// loadBlockCheckpoint returns the outputs of block b saved by checkpointBlock
+func (ast *AssocTest) loadBlockCheckpoint(b int) (sx, sxx, sxy crypto.CipherMatrix, filt []bool) {
+	g := ast.general
+	numBlocks := g.config.GenoNumBlocks
+
+	filename := g.checkpointPath("assoc.%d.bin", b)
+	if !fileExists(filename) { // Empty block
+		log.LLvl1(time.Now().Format(time.RFC3339), "MatMult: block", b+1, "/", numBlocks, "skipped (empty)")
+		return
+	}
+
+	out := crypto.LoadCipherMatrixFromFile(g.cps, filename)
+	filt = readFilterFromFile(g.checkpointPath("assoc_filt.%d.txt", b), len(out[0])*g.cps.GetSlots(), true)
+
+	log.LLvl1(time.Now().Format(time.RFC3339), "MatMult: block", b+1, "/", numBlocks, "restored from checkpoint")
+	return crypto.CipherMatrix{out[0]}, crypto.CipherMatrix{out[1]}, out[2:], filt
+}
+
+// AssocStats holds the encrypted per-SNP outputs of GetAssociationStats
+type AssocStats struct {
+	Stat    crypto.CipherVector // stdinvx * stdinvy * (sxy - sx*sy/n): correlation (linear) or z / sqrt(n) (logistic)
+	Beta    crypto.CipherVector // (sxy - sx*sy/n) / varx, if assoc_beta_se is set
+	SEScale crypto.CipherVector // sqrt(vary / varx), if assoc_beta_se is set; SE = SEScale * sqrt((1 - r^2) / df)
+}
+
+// GetAssociationStats tests all phenotypes in a single pass over the genotypes and
+// returns one AssocStats per phenotype
+func (ast *AssocTest) GetAssociationStats() ([]AssocStats, []bool) {
 	debug := ast.general.config.Debug
 
 	covAllOnes := ast.general.config.CovAllOnes // Flag indicating whether cov includes an all-ones covariate
@@ -458,6 +532,7 @@ func (ast *AssocTest) GetAssociationStats() (crypto.CipherVector, []bool) {
 
 	/* Phenotypes and PCs */
 	y := ast.pheno
+	numPhenos := ast.general.NumPhenos()
 	Qpc := ast.Qpc
 
 	/* Setup covariates */
@@ -481,8 +556,11 @@ func (ast *AssocTest) GetAssociationStats() (crypto.CipherVector, []bool) {
 	}
 
 	if debug && pid > 0 {
-		yf := crypto.DecodeFloatVector(cryptoParams, y)[:nrowsAll[pid]]
-		SaveFloatVectorToFile(ast.general.CachePath("y.txt"), yf)
+		yf := make([][]float64, numPhenos)
+		for t := range y {
+			yf[t] = crypto.DecodeFloatVector(cryptoParams, y[t])[:nrowsAll[pid]]
+		}
+		SaveFloatMatrixToFile(ast.general.CachePath("y.txt"), yf)
 
 		Cf := make([][]float64, len(C))
 		for i := range C {
@@ -491,9 +569,11 @@ func (ast *AssocTest) GetAssociationStats() (crypto.CipherVector, []bool) {
 		SaveFloatMatrixToFile(ast.general.CachePath("C.txt"), Cf)
 	}
 
+	resumeBlock := ast.general.resumeIndex(PhaseAssoc) // If resuming, Qcomb was saved earlier in the session
+
 	cacheFileQ := ast.general.CachePath("Qcomb.bin")
 	var Q crypto.CipherMatrix
-	if ast.general.config.UseCachedCombinedQ {
+	if ast.general.config.UseCachedCombinedQ || resumeBlock >= 0 {
 		if pid > 0 {
 			Q = crypto.LoadCipherMatrixFromFile(cryptoParams, cacheFileQ)
 			log.LLvl1(time.Now().Format(time.RFC3339), "Qcomb loaded from", cacheFileQ)
@@ -501,7 +581,7 @@ func (ast *AssocTest) GetAssociationStats() (crypto.CipherVector, []bool) {
 	} else {
 		Q = ast.computeCombinedQV2(C, Qpc) // nil for pid = 0
 		if pid > 0 {
-			crypto.SaveCipherMatrixToFile(cryptoParams, Q, cacheFileQ)
+			saveAtomic(cacheFileQ, func(tmpName string) { crypto.SaveCipherMatrixToFile(cryptoParams, Q, tmpName) })
 			log.LLvl1(time.Now().Format(time.RFC3339), "Qcomb saved to", cacheFileQ)
 		}
 	}
@@ -512,42 +592,75 @@ func (ast *AssocTest) GetAssociationStats() (crypto.CipherVector, []bool) {
 		}
 	}
 
-	var varx, vary, sx, sy, sxy crypto.CipherVector
+	// For a binary phenotype, the residuals of the logistic null model take the place of
+	// ynew and the summed null weights take the place of syy, which turns the statistic
+	// into the score test z / sqrt(n) (same scale as the linear correlation output)
+	logistic := ast.general.IsLogisticAssoc()
+	var yres crypto.CipherMatrix
+	var wSum crypto.CipherVector
+	if logistic {
+		yres = make(crypto.CipherMatrix, numPhenos)
+		wSum = make(crypto.CipherVector, numPhenos)
+		for t := 0; t < numPhenos; t++ {
+			log.LLvl1(time.Now().Format(time.RFC3339), fmt.Sprintf("Fitting logistic null model (phenotype %d/%d)", t+1, numPhenos))
+			yres[t], wSum[t] = ast.fitNullLogistic(Q, ncov+len(Qpc), t)
+		}
+	}
+
+	// sy, syy and vary hold one ciphertext per phenotype, sxy one row per phenotype
+	var varx, vary, sx, sy crypto.CipherVector
+	var sxy crypto.CipherMatrix
 	var nsnps, numCtx int
 	var outFilter []bool
 
 	if pid == 0 {
+		if ast.general.ckpt != nil { // Record the blocks along with the hub
+			for b := resumeBlock + 1; b < numBlocks; b++ {
+				if done := int(mpcObj.Network.ReceiveIntVector(1, mpcObj.GetHubPid())[0]); done != b {
+					panic(fmt.Sprintf("checkpoint: hub completed block %d, expected %d", done, b))
+				}
+				ast.general.saveCheckpoint(PhaseAssoc, b)
+			}
+		}
+
 		numCtx = mpcObj.Network.ReceiveInt(mpcObj.GetHubPid())
 		nsnps = mpcObj.Network.ReceiveInt(mpcObj.GetHubPid())
 
 		varx = crypto.CZeros(cryptoParams, numCtx)
-		vary = crypto.CZeros(cryptoParams, 1)
+		vary = crypto.CZeros(cryptoParams, numPhenos)
 
 	} else { // pid > 0
 
-		// Project covariates out of y: ynew = (I - Q*Q')*y
-		ymat := make(crypto.PlainMatrix, 1)
-		ymat[0] = y
-
-		mmplainfn := func(cp *crypto.CryptoParams, a crypto.CipherVector,
-			B crypto.PlainMatrix, j int) crypto.CipherVector {
-			return crypto.CPMult(cp, a, B[j])
-		}
+		var ynew crypto.CipherMatrix
+		if logistic {
+			// Null residuals are already orthogonal to the columns of Q
+			ynew = yres
+		} else {
+			// Project covariates out of all phenotypes at once: ynew = (I - Q*Q')*y
+			mmplainfn := func(cp *crypto.CryptoParams, a crypto.CipherVector,
+				B crypto.PlainMatrix, j int) crypto.CipherVector {
+				return crypto.CPMult(cp, a, B[j])
+			}
 
-		ynew := DCMatMulAAtBPlain(cryptoParams, mpcObj, Q, ymat, nrowsAll, 1, mmplainfn) // Level -2
-		ynew[0] = crypto.CMultConstRescale(cryptoParams, ynew[0], nrowsTotalInv, true)
+			ynew = DCMatMulAAtBPlain(cryptoParams, mpcObj, Q, y, nrowsAll, numPhenos, mmplainfn) // Level -2
+			for t := range ynew {
+				ynew[t] = crypto.CMultConstRescale(cryptoParams, ynew[t], nrowsTotalInv, true)
+			}
 
-		if debug {
-			for party := 1; party <= ast.general.config.NumMainParties; party++ {
-				SaveMatrixToFile(cryptoParams, mpcObj, ynew, nrowsAll[party], party, ast.general.CachePath("QQy.txt"))
+			if debug {
+				for party := 1; party <= ast.general.config.NumMainParties; party++ {
+					SaveMatrixToFile(cryptoParams, mpcObj, ynew, nrowsAll[party], party, ast.general.CachePath("QQy.txt"))
+				}
 			}
-		}
 
-		ynew[0] = mpcObj.Network.BootstrapVecAll(cryptoParams, ynew[0])
-		ynew[0] = crypto.CMultConst(cryptoParams, ynew[0], -1.0, true)
-		ynew[0] = crypto.CPAdd(cryptoParams, ynew[0], y)
+			for t := range ynew {
+				ynew[t] = mpcObj.Network.BootstrapVecAll(cryptoParams, ynew[t])
+				ynew[t] = crypto.CMultConst(cryptoParams, ynew[t], -1.0, true)
+				ynew[t] = crypto.CPAdd(cryptoParams, ynew[t], y[t])
+			}
 
-		log.LLvl1(time.Now().Format(time.RFC3339), "ynew computed")
+			log.LLvl1(time.Now().Format(time.RFC3339), "ynew computed")
+		}
 
 		if debug {
 			for party := 1; party <= ast.general.config.NumMainParties; party++ {
@@ -596,15 +709,15 @@ func (ast *AssocTest) GetAssociationStats() (crypto.CipherVector, []bool) {
 		// In parallel:
 		// (1) Compute B (=Q'*X, row-based encoding)
 		// (2) Compute sx (=omu'*X)
-		// (3) Compute sxy (=ynew'*X)
+		// (3) Compute sxy (=ynew'*X) for all phenotypes
 		// Note: if covAllOnes = true, then sx = sy = 0. Skip all calculations involving sx and sy.
 
-		concat := make(crypto.CipherMatrix, len(Q)+2) // remove all ones
+		concat := make(crypto.CipherMatrix, len(Q)+1+numPhenos) // remove all ones
 		for i := 0; i < len(Q); i++ {
 			concat[i] = Q[i]
 		}
 		concat[len(Q)] = omu
-		concat[len(Q)+1] = ynew[0]
+		copy(concat[len(Q)+1:], ynew)
 
 		filtOut := make([][]bool, numBlocks)
 
@@ -615,11 +728,19 @@ func (ast *AssocTest) GetAssociationStats() (crypto.CipherVector, []bool) {
 		sxyBlocks := make([]crypto.CipherMatrix, numBlocks)
 
 		for b := 0; b < numBlocks; b++ {
+			if b <= resumeBlock {
+				if ast.general.IsBlockForAssocTest(b) {
+					sxBlocks[b], sxxBlocks[b], sxyBlocks[b], filtOut[b] = ast.loadBlockCheckpoint(b)
+				}
+				continue
+			}
+
 			if !ast.general.IsBlockForAssocTest(b) {
 				log.LLvl1(time.Now().Format(time.RFC3339), "MatMult: block", b+1, "/", numBlocks, "skipped")
 			} else {
 				concatOut, dosageSum, dosageSqSum, filt := ast.GenoBlockMult(b, concat)
 				if concatOut == nil {
+					ast.checkpointBlock(b, nil, nil, nil, nil)
 					continue
 				}
 
@@ -640,7 +761,7 @@ func (ast *AssocTest) GetAssociationStats() (crypto.CipherVector, []bool) {
 					sxBlocks[b] = crypto.CipherMatrix{concatOut[len(Q)]}
 				}
 
-				sxyBlocks[b] = crypto.CipherMatrix{concatOut[len(Q)+1]}
+				sxyBlocks[b] = concatOut[len(Q)+1:]
 
 				log.LLvl1(time.Now().Format(time.RFC3339), "block", b+1, "/", numBlocks, "computed B, sx, sxy")
 
@@ -676,12 +797,14 @@ func (ast *AssocTest) GetAssociationStats() (crypto.CipherVector, []bool) {
 
 				filtOut[b] = filt
 			}
+
+			ast.checkpointBlock(b, sxBlocks[b], sxxBlocks[b], sxyBlocks[b], filtOut[b])
 		}
 
 		log.LLvl1(time.Now().Format(time.RFC3339), "All blocks processed")
 
 		sx = crypto.ConcatCipherMatrix(sxBlocks)[0]
-		sxy = crypto.ConcatCipherMatrix(sxyBlocks)[0]
+		sxy = crypto.ConcatCipherMatrix(sxyBlocks)
 		sxx := crypto.ConcatCipherMatrix(sxxBlocks)[0]
 		sxBlocks, sxxBlocks, sxyBlocks = nil, nil, nil
 
@@ -710,18 +833,23 @@ func (ast *AssocTest) GetAssociationStats() (crypto.CipherVector, []bool) {
 
 		// Compute sy and syy
 		if covAllOnes {
-			sy = crypto.CZeros(cryptoParams, 1)
+			sy = crypto.CZeros(cryptoParams, numPhenos)
 			log.LLvl1(time.Now().Format(time.RFC3339), "sy set to zero")
 		} else {
-			syloc := crypto.InnerSumAll(cryptoParams, ynew[0])
-			sy = crypto.CipherVector{mpcObj.Network.AggregateCText(cryptoParams, syloc)}
+			sy = make(crypto.CipherVector, numPhenos)
+			for t := range ynew {
+				syloc := crypto.InnerSumAll(cryptoParams, ynew[t])
+				sy[t] = mpcObj.Network.AggregateCText(cryptoParams, syloc)
+			}
 			sy = mpcObj.Network.CollectiveBootstrapVec(cryptoParams, sy, -1)
 		}
 
-		ynewsq := crypto.CMult(cryptoParams, ynew[0], ynew[0])
-		syyloc := crypto.InnerSumAll(cryptoParams, ynewsq)
-
-		syy := crypto.CipherVector{mpcObj.Network.AggregateCText(cryptoParams, syyloc)}
+		syy := make(crypto.CipherVector, numPhenos)
+		for t := range ynew {
+			ynewsq := crypto.CMult(cryptoParams, ynew[t], ynew[t])
+			syyloc := crypto.InnerSumAll(cryptoParams, ynewsq)
+			syy[t] = mpcObj.Network.AggregateCText(cryptoParams, syyloc)
+		}
 		syy = mpcObj.Network.CollectiveBootstrapVec(cryptoParams, syy, -1)
 
 		log.LLvl1(time.Now().Format(time.RFC3339), "Computed sy/syy")
@@ -746,15 +874,19 @@ func (ast *AssocTest) GetAssociationStats() (crypto.CipherVector, []bool) {
 			vary = syy
 		}
 
+		if logistic {
+			vary = wSum // score variance uses sum of p(1-p) in place of syy
+		}
+
 		if debug {
 			writeFilterToFile(ast.general.CachePath("xfilt.bin"), outFilter, true)
 			SaveMatrixToFile(cryptoParams, mpcObj, crypto.CipherMatrix{sx}, len(sx)*slots, -1, ast.general.CachePath("sx.txt"))     // sx / sqrt(n)
 			SaveMatrixToFile(cryptoParams, mpcObj, crypto.CipherMatrix{sxx}, len(sx)*slots, -1, ast.general.CachePath("sxx.txt"))   // sxx
-			SaveMatrixToFile(cryptoParams, mpcObj, crypto.CipherMatrix{sy}, 1, -1, ast.general.CachePath("sy.txt"))                 // sy / sqrt(n)
-			SaveMatrixToFile(cryptoParams, mpcObj, crypto.CipherMatrix{syy}, 1, -1, ast.general.CachePath("syy.txt"))               // syy
-			SaveMatrixToFile(cryptoParams, mpcObj, crypto.CipherMatrix{sxy}, len(sx)*slots, -1, ast.general.CachePath("sxy.txt"))   // sxy
+			SaveMatrixToFile(cryptoParams, mpcObj, perPhenoMatrix(sy), 1, -1, ast.general.CachePath("sy.txt"))                      // sy / sqrt(n)
+			SaveMatrixToFile(cryptoParams, mpcObj, perPhenoMatrix(syy), 1, -1, ast.general.CachePath("syy.txt"))                    // syy
+			SaveMatrixToFile(cryptoParams, mpcObj, sxy, len(sx)*slots, -1, ast.general.CachePath("sxy.txt"))                        // sxy
 			SaveMatrixToFile(cryptoParams, mpcObj, crypto.CipherMatrix{varx}, len(sx)*slots, -1, ast.general.CachePath("varx.txt")) // sxx - (sx*sx/n)
-			SaveMatrixToFile(cryptoParams, mpcObj, crypto.CipherMatrix{vary}, 1, -1, ast.general.CachePath("vary.txt"))             // syy - (sy*sy/n)
+			SaveMatrixToFile(cryptoParams, mpcObj, perPhenoMatrix(vary), 1, -1, ast.general.CachePath("vary.txt"))                  // syy - (sy*sy/n)
 		}
 	}
 
@@ -765,26 +897,54 @@ func (ast *AssocTest) GetAssociationStats() (crypto.CipherVector, []bool) {
 	log.LLvl1(time.Now().Format(time.RFC3339), "Computed stdev")
 
 	if pid > 0 {
-		var stats crypto.CipherVector
-		if !covAllOnes {
-			stats = crypto.CMultScalar(cryptoParams, sx, sy[0]) // sx * sy / n
-			stats = crypto.CSub(cryptoParams, sxy, stats)       // sxy - (sx * sy / n)
-		} else {
-			stats = sxy
+		betaSE := ast.general.config.AssocBetaSE
+		if betaSE && logistic {
+			log.LLvl1(time.Now().Format(time.RFC3339), "Warning: assoc_beta_se is only supported for the linear test, skipping beta/SE")
+			betaSE = false
+		}
+
+		res := make([]AssocStats, numPhenos)
+		for t := range res {
+			var stats crypto.CipherVector
+			if !covAllOnes {
+				stats = crypto.CMultScalar(cryptoParams, sx, sy[t]) // sx * sy / n
+				stats = crypto.CSub(cryptoParams, sxy[t], stats)    // sxy - (sx * sy / n)
+			} else {
+				stats = sxy[t]
+			}
+
+			if betaSE {
+				res[t].Beta = crypto.CMult(cryptoParams, stats, stdinvx)       // stdinvx * (sxy - (sx * sy) / n)
+				res[t].Beta = crypto.CMult(cryptoParams, res[t].Beta, stdinvx) // (sxy - (sx * sy) / n) / varx
+
+				sdy := crypto.CMultScalar(cryptoParams, crypto.CipherVector{vary[t]}, stdinvy[t])[0] // sqrt(vary)
+				res[t].SEScale = crypto.CMultScalar(cryptoParams, stdinvx, sdy)
+			}
+
+			stats = crypto.CMult(cryptoParams, stats, stdinvx)          // stdinvx * (sxy - (sx * sy) / n)
+			stats = crypto.CMultScalar(cryptoParams, stats, stdinvy[t]) // stdinvx * stdinvy * (sxy - (sx * sy) / n)
+			res[t].Stat = stats
 		}
-		stats = crypto.CMult(cryptoParams, stats, stdinvx)       // stdinvx * (sxy - (sx * sy) / n)
-		stats = crypto.CMultScalar(cryptoParams, stats, stdinvy) // stdinvx * stdinvy * (sxy - (sx * sy) / n)
 
 		log.LLvl1(time.Now().Format(time.RFC3339), "All done!")
 
-		return stats, outFilter
+		return res, outFilter
 	}
 
 	return nil, nil // party 0
 }
 
-// Returns stdinvx and stdinvy
-func (ast *AssocTest) computeStdInv(varx, vary crypto.CipherVector, nsnps int, filter []bool) (crypto.CipherVector, *ckks.Ciphertext) {
+// perPhenoMatrix puts each per-phenotype ciphertext (value in the first slot) in its own column
+func perPhenoMatrix(cv crypto.CipherVector) crypto.CipherMatrix {
+	out := make(crypto.CipherMatrix, len(cv))
+	for t := range cv {
+		out[t] = crypto.CipherVector{cv[t]}
+	}
+	return out
+}
+
+// Returns stdinvx and stdinvy (one ciphertext per phenotype, vary holds one ciphertext per phenotype)
+func (ast *AssocTest) computeStdInv(varx, vary crypto.CipherVector, nsnps int, filter []bool) (crypto.CipherVector, []*ckks.Ciphertext) {
 	debug := ast.general.config.Debug
 
 	cryptoParams := ast.general.cps
@@ -797,7 +957,10 @@ func (ast *AssocTest) computeStdInv(varx, vary crypto.CipherVector, nsnps int, f
 
 	// Convert to SS
 	varxSS := mpcObj.CVecToSS(cryptoParams, mpcObj.GetRType(), varx, -1, len(varx), slots*len(varx))
-	varySS := mpcObj.CiphertextToSS(cryptoParams, mpcObj.GetRType(), vary[0], -1, 1)
+	varySS := mpc_core.InitRVec(rtype.Zero(), len(vary))
+	for t := range vary {
+		varySS[t] = mpcObj.CiphertextToSS(cryptoParams, mpcObj.GetRType(), vary[t], -1, 1)[0]
+	}
 
 	if debug && pid > 0 {
 		log.LLvl1(time.Now().Format(time.RFC3339), "varxSS", mpcObj.RevealSymVec(varxSS[:5]).ToFloat(mpcObj.GetFracBits()))
@@ -805,7 +968,7 @@ func (ast *AssocTest) computeStdInv(varx, vary crypto.CipherVector, nsnps int, f
 	}
 
 	// Concatenate
-	varSS := mpc_core.InitRVec(rtype.Zero(), nsnps+1)
+	varSS := mpc_core.InitRVec(rtype.Zero(), nsnps+len(vary))
 	if pid > 0 {
 		dst := 0
 		for src := range varxSS {
@@ -816,7 +979,7 @@ func (ast *AssocTest) computeStdInv(varx, vary crypto.CipherVector, nsnps int, f
 		}
 	}
 
-	varSS[len(varSS)-1] = varySS[0]
+	copy(varSS[nsnps:], varySS)
 
 	// Compute Sqrt Inverse
 	stdinvSS := mpcPar.SqrtInv(varSS, useBoolean)
@@ -825,7 +988,7 @@ func (ast *AssocTest) computeStdInv(varx, vary crypto.CipherVector, nsnps int, f
 		log.LLvl1(time.Now().Format(time.RFC3339), "varxSS", mpcObj.RevealSymVec(varxSS[:5]).ToFloat(mpcObj.GetFracBits()))
 		log.LLvl1(time.Now().Format(time.RFC3339), "varSS", mpcObj.RevealSymVec(varSS[:5]).ToFloat(mpcObj.GetFracBits()))
 		log.LLvl1(time.Now().Format(time.RFC3339), "stdinvxSS", mpcObj.RevealSymVec(stdinvSS[:5]).ToFloat(mpcObj.GetFracBits()))
-		log.LLvl1(time.Now().Format(time.RFC3339), "stdinvySS", mpcObj.RevealSymVec(stdinvSS[(len(stdinvSS)-1):]).ToFloat(mpcObj.GetFracBits()))
+		log.LLvl1(time.Now().Format(time.RFC3339), "stdinvySS", mpcObj.RevealSymVec(stdinvSS[nsnps:]).ToFloat(mpcObj.GetFracBits()))
 	}
 
 	// Convert back to HE
@@ -841,12 +1004,15 @@ func (ast *AssocTest) computeStdInv(varx, vary crypto.CipherVector, nsnps int, f
 	}
 
 	stdinvx := mpcObj.SSToCVec(cryptoParams, stdinvxSS)
-	stdinvy := mpcObj.SStoCiphertext(cryptoParams, mpc_core.RVec{stdinvSS[len(stdinvSS)-1]})
-	stdinvy = crypto.Rebalance(cryptoParams, stdinvy)
+	stdinvy := make([]*ckks.Ciphertext, len(vary))
+	for t := range stdinvy {
+		stdinvy[t] = mpcObj.SStoCiphertext(cryptoParams, mpc_core.RVec{stdinvSS[nsnps+t]})
+		stdinvy[t] = crypto.Rebalance(cryptoParams, stdinvy[t])
+	}
 
 	if debug && pid > 0 {
-		SaveMatrixToFile(cryptoParams, mpcObj, crypto.CipherMatrix{stdinvx}, nsnps, -1, ast.general.CachePath("stdinvx.txt"))                  // 1 / sqrt(sxx - (sx*sx/n))
-		SaveMatrixToFile(cryptoParams, mpcObj, crypto.CipherMatrix{crypto.CipherVector{stdinvy}}, 1, -1, ast.general.CachePath("stdinvy.txt")) // 1 / sqrt(syy - (sy*sy/n))
+		SaveMatrixToFile(cryptoParams, mpcObj, crypto.CipherMatrix{stdinvx}, nsnps, -1, ast.general.CachePath("stdinvx.txt")) // 1 / sqrt(sxx - (sx*sx/n))
+		SaveMatrixToFile(cryptoParams, mpcObj, perPhenoMatrix(stdinvy), 1, -1, ast.general.CachePath("stdinvy.txt"))          // 1 / sqrt(syy - (sy*sy/n))
 	}
 
 	return stdinvx, stdinvy
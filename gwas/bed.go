@@ -0,0 +1,241 @@
+package gwas
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.dedis.ch/onet/v3/log"
+)
+
+// bedGenotypes maps the 2-bit .bed codes to A1 allele counts (A1 is the fifth .bim column,
+// usually ALT): 00 = homozygous A1, 01 = missing, 10 = heterozygous, 11 = homozygous A2
+var bedGenotypes = [4]int8{2, -1, 1, 0}
+
+// BedReader decodes the variants of a SNP-major PLINK 1 .bed file sequentially
+type BedReader struct {
+	filename    string
+	file        *os.File
+	reader      *bufio.Reader
+	numSamples  int
+	numVariants int
+	next        int
+	buf         []byte
+}
+
+// BimVariant is one line of a .bim file
+type BimVariant struct {
+	Chrom string
+	ID    string
+	Pos   uint64
+}
+
+// NewBedReader opens a .bed file with numSamples samples (lines of the .fam) and
+// numVariants variants (lines of the .bim)
+func NewBedReader(filename string, numSamples, numVariants int) *BedReader {
+	file, err := os.Open(filename)
+	if err != nil {
+		panic(err)
+	}
+
+	magic := make([]byte, 3)
+	if _, err := io.ReadFull(file, magic); err != nil {
+		panic(fmt.Sprintf("%s: failed to read header: %v", filename, err))
+	}
+	if magic[0] != 0x6c || magic[1] != 0x1b {
+		panic(fmt.Sprintf("%s: not a .bed file", filename))
+	}
+	if magic[2] != 0x01 {
+		panic(fmt.Sprintf("%s: only SNP-major .bed files are supported", filename))
+	}
+
+	br := &BedReader{
+		filename:    filename,
+		file:        file,
+		numSamples:  numSamples,
+		numVariants: numVariants,
+		buf:         make([]byte, (numSamples+3)/4),
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		panic(err)
+	}
+	if expected := 3 + int64(numVariants)*int64(len(br.buf)); info.Size() != expected {
+		panic(fmt.Sprintf("%s has %d bytes, expected %d for %d samples and %d variants", filename, info.Size(), expected, numSamples, numVariants))
+	}
+
+	br.Seek(0)
+	return br
+}
+
+func (br *BedReader) NumSamples() int {
+	return br.numSamples
+}
+
+func (br *BedReader) NumVariants() int {
+	return br.numVariants
+}
+
+// Seek positions the reader at variant v
+func (br *BedReader) Seek(v int) {
+	if _, err := br.file.Seek(3+int64(v)*int64(len(br.buf)), io.SeekStart); err != nil {
+		panic(err)
+	}
+	br.reader = bufio.NewReader(br.file)
+	br.next = v
+}
+
+// Next decodes the next variant into geno (A1 allele counts, missing is -1). Returns
+// false after the last variant
+func (br *BedReader) Next(geno []int8) bool {
+	if br.next >= br.numVariants {
+		return false
+	}
+	if _, err := io.ReadFull(br.reader, br.buf); err != nil {
+		panic(fmt.Sprintf("%s: failed to read variant %d: %v", br.filename, br.next, err))
+	}
+	br.next++
+
+	for i := 0; i < br.numSamples; i++ {
+		geno[i] = bedGenotypes[(br.buf[i/4]>>uint(2*(i%4)))&3]
+	}
+	return true
+}
+
+func (br *BedReader) Close() {
+	br.file.Close()
+}
+
+// LoadBimFile reads the chromosome, ID and position of every variant in a .bim file
+func LoadBimFile(filename string) []BimVariant {
+	file, err := os.Open(filename)
+	if err != nil {
+		panic(err)
+	}
+	defer file.Close()
+
+	var out []BimVariant
+	scanner := bufio.NewScanner(file)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		tok := strings.Fields(scanner.Text())
+		if len(tok) == 0 {
+			continue
+		}
+		if len(tok) < 4 {
+			panic(fmt.Sprintf("%s:%d: expected at least 4 columns", filename, lineno))
+		}
+
+		pos, err := strconv.ParseUint(tok[3], 10, 64)
+		if err != nil {
+			panic(fmt.Sprintf("%s:%d: %v", filename, lineno, err))
+		}
+		out = append(out, BimVariant{Chrom: tok[0], ID: tok[1], Pos: pos})
+	}
+	if err := scanner.Err(); err != nil {
+		panic(err)
+	}
+	return out
+}
+
+// BimChromBlocks splits the variants into blocks of consecutive variants on the same chromosome
+func BimChromBlocks(variants []BimVariant) []int {
+	var sizes []int
+	for i := range variants {
+		if i == 0 || variants[i].Chrom != variants[i-1].Chrom {
+			sizes = append(sizes, 0)
+		}
+		sizes[len(sizes)-1]++
+	}
+	return sizes
+}
+
+// BimPositions encodes the positions of the variants as LoadSNPPositionFile does
+// (chrom * 1e9 + pos)
+func BimPositions(variants []BimVariant) []uint64 {
+	out := make([]uint64, len(variants))
+	for i, v := range variants {
+		out[i] = ChromNumber(v.Chrom)*1e9 + v.Pos
+	}
+	return out
+}
+
+// ChromNumber returns the PLINK number of a chromosome code, with or without a "chr"
+// prefix: 1-22, X = 23, Y = 24, XY = 25, MT = 26
+func ChromNumber(chrom string) uint64 {
+	num, ok := parseChromNumber(chrom)
+	if !ok {
+		panic(fmt.Sprintf("unrecognized chromosome code: %s", chrom))
+	}
+	return num
+}
+
+func parseChromNumber(chrom string) (uint64, bool) {
+	code := strings.TrimPrefix(chrom, "chr")
+	switch strings.ToUpper(code) {
+	case "X":
+		return 23, true
+	case "Y":
+		return 24, true
+	case "XY":
+		return 25, true
+	case "M", "MT":
+		return 26, true
+	}
+
+	num, err := strconv.ParseUint(code, 10, 64)
+	return num, err == nil && num > 0
+}
+
+// BedToGenoFile decodes variants start to start+count-1 of the kept samples of a .bed
+// file into a sample-major int8 matrix file read by NewGenoFileStream
+func BedToGenoFile(bedFile string, numSamples, numVariants int, sampleFilt []bool, start, count int, outputFile string) {
+	br := NewBedReader(bedFile, numSamples, numVariants)
+	defer br.Close()
+
+	if len(sampleFilt) != numSamples {
+		panic(fmt.Sprintf("%s has %d samples but the sample filter has %d", bedFile, numSamples, len(sampleFilt)))
+	}
+
+	br.Seek(start)
+	WriteGenoFileByVariant(outputFile, sampleFilt, count, func(c int, geno []int8) {
+		if !br.Next(geno) {
+			panic(fmt.Sprintf("%s: variant index %d out of range", bedFile, start+c))
+		}
+	})
+}
+
+// newBedGenoFileStreams returns one stream per chromosome of <bedPrefix>.bed/.bim/.fam for
+// the samples in keepFile (plink2 --keep format, empty for all), with the genotypes decoded
+// into cacheDir on first use, along with the block sizes and SNP positions from the .bim
+func newBedGenoFileStreams(bedPrefix, keepFile, cacheDir string, numBlocks int) ([]*GenoFileStream, []int, []uint64) {
+	variants := LoadBimFile(bedPrefix + ".bim")
+	blockSizes := BimChromBlocks(variants)
+	if len(blockSizes) != numBlocks {
+		log.Fatalf("%s.bim has %d chromosomes but geno_num_blocks is %d", bedPrefix, len(blockSizes), numBlocks)
+	}
+
+	sampleFilt := PgenSampleFilter(bedPrefix+".fam", keepFile)
+	numSamples, numRows := len(sampleFilt), uint64(SumBool(sampleFilt))
+
+	genofs := make([]*GenoFileStream, numBlocks)
+	shift := 0
+	for i := range genofs {
+		start, count := shift, blockSizes[i]
+		cacheFile := filepath.Join(cacheDir, fmt.Sprintf("geno_bed.%d.bin", i))
+
+		log.LLvl1(time.Now().Format(time.RFC3339), "Opening bed block:", bedPrefix, "chrom", variants[start].Chrom, numRows, count)
+		genofs[i] = newCachedGenoFileStream(cacheFile, numRows, uint64(count), false, false, func() {
+			BedToGenoFile(bedPrefix+".bed", numSamples, len(variants), sampleFilt, start, count, cacheFile)
+		})
+
+		shift += count
+	}
+
+	return genofs, blockSizes, BimPositions(variants)
+}
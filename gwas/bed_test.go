@@ -0,0 +1,62 @@
+package gwas
+
+import (
+	"reflect"
+	"testing"
+)
+
+// testdata/toy.bed is encoded by hand following the PLINK 1 .bed specification: magic
+// 6c 1b 01, then per variant 2 bytes for the 5 samples of toy.fam, 4 samples per byte from
+// the low bits, with codes 00 = homozygous A1, 01 = missing, 10 = heterozygous,
+// 11 = homozygous A2 (bytes 78 02, ff 03, e1 00)
+var toyBedGeno = [][]int8{
+	{2, 1, 0, -1, 1},
+	{0, 0, 0, 0, 0},
+	{-1, 2, 1, 0, 2},
+}
+
+func TestBedReader(t *testing.T) {
+	br := NewBedReader("testdata/toy.bed", 5, 3)
+	defer br.Close()
+
+	geno := make([]int8, br.NumSamples())
+	for v, want := range toyBedGeno {
+		if !br.Next(geno) {
+			t.Fatalf("no variant %d", v)
+		}
+		if !reflect.DeepEqual(geno, want) {
+			t.Errorf("variant %d: genotypes %v, want %v", v, geno, want)
+		}
+	}
+	if br.Next(geno) {
+		t.Error("Next returned a variant after the last one")
+	}
+
+	br.Seek(2)
+	if br.Next(geno); !reflect.DeepEqual(geno, toyBedGeno[2]) {
+		t.Errorf("after Seek(2): genotypes %v, want %v", geno, toyBedGeno[2])
+	}
+}
+
+func TestBedReaderSizeMismatch(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("NewBedReader accepted a .bed file that does not match the sample count")
+		}
+	}()
+	NewBedReader("testdata/toy.bed", 9, 3)
+}
+
+func TestBimFile(t *testing.T) {
+	variants := LoadBimFile("testdata/toy.bim")
+	want := []BimVariant{{"1", "rs1", 10583}, {"1", "rs2", 10611}, {"chrX", "rs3", 2699520}}
+	if !reflect.DeepEqual(variants, want) {
+		t.Fatalf("LoadBimFile = %v, want %v", variants, want)
+	}
+	if sizes := BimChromBlocks(variants); !reflect.DeepEqual(sizes, []int{2, 1}) {
+		t.Errorf("BimChromBlocks = %v, want [2 1]", sizes)
+	}
+	if pos := BimPositions(variants); !reflect.DeepEqual(pos, []uint64{1e9 + 10583, 1e9 + 10611, 23e9 + 2699520}) {
+		t.Errorf("BimPositions = %v", pos)
+	}
+}
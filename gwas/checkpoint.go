@@ -0,0 +1,312 @@
+package gwas
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/hhcho/sfgwas-private/crypto"
+	"github.com/hhcho/sfgwas-private/mpc"
+	"github.com/ldsec/lattigo/v2/ckks"
+	"go.dedis.ch/onet/v3/log"
+)
+
+// Checkpoints (checkpoint = true): every party records in cache_dir each power iteration of
+// PCA and each block of the association tests it completes. At startup the hub collects the
+// progress of all parties, and they resume after the last step that every party recorded.
+//
+// Ciphertexts are only valid under the collective keys that encrypted them, so a session keeps
+// its keys in cache_dir and a resumed run loads them instead of running CollectiveInit. Each
+// step also records the shared PRG states, which are restored on resume and reseeded with a
+// nonce from the hub so that the interrupted step does not reuse them
+
+const checkpointVersion = 1 // Bump when the files or their format change
+
+const (
+	checkpointFile     = "checkpoint.json"
+	checkpointKeysFile = "ckpt_keys.bin"
+	checkpointPrefix   = "ckpt_" // Files of the current session, removed at a fresh start
+	checkpointNonceLen = 4       // In uint64s
+)
+
+type checkpointEntry struct {
+	Seq   int              `json:"seq"`   // 1 for the first step of the session
+	Phase string           `json:"phase"` // PhasePCA or PhaseAssoc
+	Index int              `json:"index"` // Completed power iteration or block
+	PRG   []map[int][]byte `json:"prg"`   // Shared PRG states of each thread
+}
+
+type checkpointState struct {
+	Version    int               `json:"version"`
+	Session    uint64            `json:"session"` // Chosen by the hub at a fresh start
+	ConfigHash string            `json:"config_hash"`
+	Entries    []checkpointEntry `json:"entries"`
+}
+
+type checkpointer struct {
+	dir    string
+	state  checkpointState
+	resume *checkpointEntry // Step to resume after, nil at a fresh start
+}
+
+func (c *checkpointer) path(filename string) string {
+	return filepath.Join(c.dir, filename)
+}
+
+// load reads the checkpoints of this party and returns why they cannot be resumed, if so
+func (c *checkpointer) load(configHash string, numThreads int) string {
+	buf, err := os.ReadFile(c.path(checkpointFile))
+	if os.IsNotExist(err) {
+		return "none found"
+	} else if err != nil {
+		return err.Error()
+	}
+	if err := json.Unmarshal(buf, &c.state); err != nil {
+		return fmt.Sprintf("%s: %v", checkpointFile, err)
+	}
+
+	if c.state.Version != checkpointVersion {
+		return fmt.Sprintf("%s has version %d, expected %d", checkpointFile, c.state.Version, checkpointVersion)
+	} else if c.state.ConfigHash != configHash {
+		return "the config or inputs changed since the last run"
+	} else if !fileExists(c.path(checkpointKeysFile)) {
+		return fmt.Sprintf("%s not found", checkpointKeysFile)
+	}
+	for i, e := range c.state.Entries {
+		if e.Seq != i+1 || len(e.PRG) != numThreads {
+			return fmt.Sprintf("%s is corrupt at step %d", checkpointFile, i+1)
+		}
+	}
+	return ""
+}
+
+func (c *checkpointer) write() {
+	buf, err := json.MarshalIndent(c.state, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	writeFileAtomic(c.path(checkpointFile), buf, 0600) // Holds the shared PRG states
+}
+
+// reset starts a new session, removing the files of the previous one together with the block
+// caches of GenoBlockMult, which hold ciphertexts under its keys
+func (c *checkpointer) reset(session uint64, configHash string) {
+	for _, pattern := range []string{checkpointPrefix + "*", "assoc_cache_*"} {
+		files, err := filepath.Glob(c.path(pattern))
+		if err != nil {
+			panic(err)
+		}
+		for _, f := range files {
+			if err := os.Remove(f); err != nil {
+				panic(err)
+			}
+		}
+	}
+
+	c.state = checkpointState{
+		Version:    checkpointVersion,
+		Session:    session,
+		ConfigHash: configHash,
+	}
+	c.resume = nil
+	c.write()
+}
+
+// agreeOnCheckpoint decides with the other parties (via the hub) whether to resume the
+// checkpointed session and from which step, and restores or reseeds the shared PRGs accordingly.
+// Steps before the resumed one are read from the cache
+func agreeOnCheckpoint(config *Config, networks mpc.ParallelNetworks, configHash string) *checkpointer {
+	net := networks[0]
+	pid, hub := net.GetPid(), config.HubPartyId
+	c := &checkpointer{dir: config.CacheDir}
+
+	// Session and number of recorded steps at this party; session 0 if it cannot resume
+	local := []uint64{0, 0}
+	if problem := c.load(configHash, len(networks)); problem != "" {
+		log.LLvl1(time.Now().Format(time.RFC3339), "Checkpoint:", problem)
+	} else {
+		local[0], local[1] = c.state.Session, uint64(len(c.state.Entries))
+	}
+
+	// Session, steps to keep (0 for a fresh start) and a nonce for the shared PRGs
+	decision := make([]uint64, 2+checkpointNonceLen)
+	if pid == hub {
+		resume, steps := local[0] != 0, local[1]
+		for p := 0; p < net.GetNParty(); p++ {
+			if p == hub {
+				continue
+			}
+			other := net.ReceiveIntVector(len(local), p)
+			if other[0] != local[0] {
+				resume = false
+			}
+			if other[1] < steps {
+				steps = other[1]
+			}
+		}
+
+		buf := make([]byte, 8*len(decision))
+		if _, err := rand.Read(buf); err != nil {
+			panic(err)
+		}
+		for i := range decision {
+			decision[i] = binary.LittleEndian.Uint64(buf[8*i:])
+		}
+		if resume && steps > 0 {
+			decision[0], decision[1] = local[0], steps
+		} else {
+			decision[0] |= 1 // Nonzero session
+			decision[1] = 0
+		}
+
+		for p := 0; p < net.GetNParty(); p++ {
+			if p != hub {
+				net.SendIntVector(decision, p)
+			}
+		}
+	} else {
+		net.SendIntVector(local, hub)
+		decision = net.ReceiveIntVector(len(decision), hub)
+	}
+
+	nonce := make([]byte, 8*checkpointNonceLen)
+	for i := 0; i < checkpointNonceLen; i++ {
+		binary.LittleEndian.PutUint64(nonce[8*i:], decision[2+i])
+	}
+
+	if decision[1] == 0 {
+		c.reset(decision[0], configHash)
+		log.LLvl1(time.Now().Format(time.RFC3339), fmt.Sprintf("Checkpoint: new session %016x", decision[0]))
+	} else {
+		c.state.Entries = c.state.Entries[:decision[1]]
+		c.resume = &c.state.Entries[len(c.state.Entries)-1]
+		for thread := range networks {
+			networks[thread].Rand.ImportSharedPRGs(c.resume.PRG[thread])
+		}
+		c.write()
+
+		if !config.SkipQC {
+			config.UseCachedQC = true
+		}
+		if c.resume.Phase == PhaseAssoc && !config.SkipPCA {
+			config.UseCachedPCA = true
+		}
+		log.LLvl1(time.Now().Format(time.RFC3339), fmt.Sprintf("Checkpoint: resuming session %016x after %s step %d",
+			decision[0], c.resume.Phase, c.resume.Index+1))
+	}
+
+	for thread := range networks {
+		networks[thread].Rand.ReseedSharedPRGs(nonce)
+	}
+	return c
+}
+
+// saveKeys keeps the collective keys of a new session, readable by the owner only since they
+// include this party's secret key share
+func (c *checkpointer) saveKeys(cps *crypto.CryptoParams) {
+	buf, err := cps.MarshalBinary()
+	if err != nil {
+		panic(err)
+	}
+	writeFileAtomic(c.path(checkpointKeysFile), buf, 0600)
+}
+
+// loadKeys restores the collective keys of the resumed session
+func (c *checkpointer) loadKeys(params *ckks.Parameters, prec uint, pid int) *crypto.CryptoParams {
+	buf, err := os.ReadFile(c.path(checkpointKeysFile))
+	if err != nil {
+		panic(err)
+	}
+	saved := new(crypto.CryptoParams)
+	if err := saved.UnmarshalBinary(buf); err != nil {
+		panic(err)
+	}
+
+	cps := crypto.NewCryptoParams(params, saved.Sk, saved.AggregateSk, saved.Pk, saved.Rlk, prec, runtime.GOMAXPROCS(0))
+	if pid > 0 {
+		cps.RotKs = saved.RotKs
+		cps.SetEvaluators(cps.Params, cps.Rlk, cps.RotKs)
+	}
+	log.LLvl1(time.Now().Format(time.RFC3339), "Checkpoint: collective keys loaded from", c.path(checkpointKeysFile))
+	return cps
+}
+
+// saveCheckpoint records that step index of phase is complete at this party. The files of the
+// step must have been written before
+func (g *ProtocolInfo) saveCheckpoint(phase string, index int) {
+	c := g.ckpt
+	if c == nil {
+		return
+	}
+
+	e := checkpointEntry{
+		Seq:   len(c.state.Entries) + 1,
+		Phase: phase,
+		Index: index,
+		PRG:   make([]map[int][]byte, len(g.mpcObj)),
+	}
+	for thread := range g.mpcObj {
+		e.PRG[thread] = g.mpcObj[thread].Network.Rand.ExportSharedPRGs()
+	}
+	c.state.Entries = append(c.state.Entries, e)
+	c.write()
+}
+
+// resumeIndex returns the last completed step of phase if the run resumes within it, or -1
+func (g *ProtocolInfo) resumeIndex(phase string) int {
+	if g.ckpt == nil || g.ckpt.resume == nil || g.ckpt.resume.Phase != phase {
+		return -1
+	}
+	return g.ckpt.resume.Index
+}
+
+// clearCheckpoints ends the session once the association tests are complete, removing its
+// files and the collective keys
+func (g *ProtocolInfo) clearCheckpoints() {
+	c := g.ckpt
+	if c == nil {
+		return
+	}
+	files, err := filepath.Glob(c.path(checkpointPrefix + "*"))
+	if err != nil {
+		panic(err)
+	}
+	files = append(files, c.path(checkpointFile), c.path(checkpointKeysFile))
+	for _, f := range files {
+		if err := os.Remove(f); err != nil && !os.IsNotExist(err) {
+			panic(err)
+		}
+	}
+	g.ckpt = nil
+	log.LLvl1(time.Now().Format(time.RFC3339), "Checkpoint: session complete, checkpoints removed")
+}
+
+// checkpointPath returns the name of a file that belongs to the checkpointed session
+func (g *ProtocolInfo) checkpointPath(format string, index int) string {
+	return g.CachePath(checkpointPrefix + fmt.Sprintf(format, index))
+}
+
+// saveAtomic calls save with a temporary file name next to filename, then renames the file,
+// so that an interrupted write never leaves a partial file under filename
+func saveAtomic(filename string, save func(tmpName string)) {
+	tmpName := filename + ".tmp"
+	save(tmpName)
+
+	file, err := os.Open(tmpName)
+	if err != nil {
+		panic(err)
+	}
+	if err := file.Sync(); err != nil {
+		panic(err)
+	}
+	file.Close()
+
+	if err := os.Rename(tmpName, filename); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,176 @@
+package gwas
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/hhcho/sfgwas-private/mpc"
+)
+
+func TestClearCheckpoints(t *testing.T) {
+	c := &checkpointer{dir: t.TempDir()}
+	writeFileAtomic(c.path(checkpointKeysFile), []byte("keys"), 0600)
+	writeFileAtomic(c.path(checkpointFile), []byte("{}"), 0600)
+	writeFileAtomic(c.path(checkpointPrefix+"pca_qloc.3.bin"), []byte("q"), 0600)
+	writeFileAtomic(c.path("manifest.qc.json"), []byte("{}"), 0644)
+
+	info, err := os.Stat(c.path(checkpointKeysFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("%s has permissions %o, want 600", checkpointKeysFile, perm)
+	}
+
+	g := &ProtocolInfo{ckpt: c}
+	g.clearCheckpoints()
+	if g.ckpt != nil {
+		t.Error("the session is still set")
+	}
+
+	files, err := filepath.Glob(filepath.Join(c.dir, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || filepath.Base(files[0]) != "manifest.qc.json" {
+		t.Errorf("files left in the cache: %v, want only manifest.qc.json", files)
+	}
+}
+
+// advanceSharedPRGs draws from every shared PRG, as a protocol step would
+func advanceSharedPRGs(rand *mpc.Random, np int) {
+	buf := make([]byte, 32)
+	for id := -1; id < np; id++ {
+		rand.SwitchPRG(id)
+		rand.RandRead(buf)
+	}
+	rand.RestorePRG()
+}
+
+// Parties that recorded different steps, or sessions that cannot be resumed, end up on the
+// same decision, with their shared PRGs in step
+func TestAgreeOnCheckpoint(t *testing.T) {
+	const np, hash = 3, "hash"
+	tests := []struct {
+		name     string
+		steps    [np]int    // Steps recorded at each party: 3 power iterations, then assoc blocks
+		sessions [np]uint64 // Session recorded at each party
+		hashes   [np]string // Config hash recorded at each party
+		resume   int        // Step all parties resume after, 0 for a fresh start
+	}{
+		{"resume in PCA", [np]int{3, 5, 4}, [np]uint64{7, 7, 7}, [np]string{hash, hash, hash}, 3},
+		{"resume in assoc", [np]int{5, 4, 5}, [np]uint64{7, 7, 7}, [np]string{hash, hash, hash}, 4},
+		{"no steps at one party", [np]int{5, 0, 5}, [np]uint64{7, 7, 7}, [np]string{hash, hash, hash}, 0},
+		{"session mismatch", [np]int{5, 5, 5}, [np]uint64{7, 7, 8}, [np]string{hash, hash, hash}, 0},
+		{"config changed", [np]int{5, 5, 5}, [np]uint64{7, 7, 7}, [np]string{hash, hash, "other"}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr, err := mpc.NewTransport(mpc.TransportInProc, "", nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ckpts := make([]*checkpointer, np)
+			configs := make([]*Config, np)
+			nets := make([]mpc.ParallelNetworks, np)
+			var wg sync.WaitGroup
+			for pid := 0; pid < np; pid++ {
+				configs[pid] = &Config{HubPartyId: 1, CacheDir: t.TempDir()}
+				wg.Add(1)
+				go func(pid int) {
+					defer wg.Done()
+					networks, err := mpc.InitCommunication(tr, pid, np, 1, mpc.SharedKeyConfig{Exchange: true}, mpc.Timeouts{})
+					if err != nil {
+						t.Errorf("party %d: %v", pid, err)
+						return
+					}
+					nets[pid] = networks
+
+					// Every party runs 5 steps and records the first tt.steps[pid]
+					c := &checkpointer{dir: configs[pid].CacheDir}
+					c.state = checkpointState{Version: checkpointVersion, Session: tt.sessions[pid], ConfigHash: tt.hashes[pid]}
+					for step := 1; step <= 5; step++ {
+						advanceSharedPRGs(networks[0].Rand, np)
+						phase, index := PhasePCA, step-1
+						if step > 3 {
+							phase, index = PhaseAssoc, step-4
+						}
+						if step <= tt.steps[pid] {
+							c.state.Entries = append(c.state.Entries, checkpointEntry{Seq: step, Phase: phase, Index: index,
+								PRG: []map[int][]byte{networks[0].Rand.ExportSharedPRGs()}})
+						}
+					}
+					c.write()
+					writeFileAtomic(c.path(checkpointKeysFile), []byte("keys"), 0600)
+					writeFileAtomic(c.path(checkpointPrefix+"pca_qloc.1.bin"), []byte("q"), 0600)
+
+					ckpts[pid] = agreeOnCheckpoint(configs[pid], networks, hash)
+				}(pid)
+			}
+			wg.Wait()
+			if t.Failed() {
+				return
+			}
+			defer func() {
+				for _, networks := range nets {
+					networks[0].CloseAll()
+				}
+			}()
+
+			for pid, c := range ckpts {
+				if c.state.Session != ckpts[0].state.Session {
+					t.Errorf("party %d: session %016x, party 0 has %016x", pid, c.state.Session, ckpts[0].state.Session)
+				}
+				_, statErr := os.Stat(c.path(checkpointPrefix + "pca_qloc.1.bin"))
+				if tt.resume == 0 {
+					if c.resume != nil || len(c.state.Entries) != 0 || c.state.Session == 0 || c.state.Session == 7 {
+						t.Errorf("party %d: resume %v after %d steps of session %016x, want a fresh start", pid, c.resume, len(c.state.Entries), c.state.Session)
+					}
+					if !os.IsNotExist(statErr) {
+						t.Errorf("party %d: the files of the previous session were not removed", pid)
+					}
+					continue
+				}
+
+				if c.resume == nil || c.resume.Seq != tt.resume || len(c.state.Entries) != tt.resume || c.state.Session != 7 {
+					t.Errorf("party %d: resume %v after %d steps of session %016x, want step %d of session 7", pid, c.resume, len(c.state.Entries), c.state.Session, tt.resume)
+				}
+				if statErr != nil {
+					t.Errorf("party %d: %v", pid, statErr)
+				}
+				if problem := (&checkpointer{dir: c.dir}).load(hash, 1); problem != "" {
+					t.Errorf("party %d: the truncated checkpoints cannot be loaded: %s", pid, problem)
+				}
+				if !configs[pid].UseCachedQC || configs[pid].UseCachedPCA != (tt.resume > 3) {
+					t.Errorf("party %d: use_cached_qc %t, use_cached_pca %t after resuming at step %d", pid, configs[pid].UseCachedQC, configs[pid].UseCachedPCA, tt.resume)
+				}
+			}
+
+			// Restored (or not) and reseeded alike at every party
+			draw := func(pid, id int) []byte {
+				buf := make([]byte, 32)
+				rand := nets[pid][0].Rand
+				rand.SwitchPRG(id)
+				rand.RandRead(buf)
+				rand.RestorePRG()
+				return buf
+			}
+			global := draw(0, mpc.GlobalPRG)
+			for a := 0; a < np; a++ {
+				if a > 0 && !bytes.Equal(draw(a, mpc.GlobalPRG), global) {
+					t.Errorf("the global PRG differs between parties 0 and %d", a)
+				}
+				for b := a + 1; b < np; b++ {
+					if !bytes.Equal(draw(a, b), draw(b, a)) {
+						t.Errorf("the PRG shared by parties %d and %d differs between them", a, b)
+					}
+				}
+			}
+		})
+	}
+}
@@ -297,6 +297,31 @@ type GenoFileStream struct {
 	filtNumCol uint64
 
 	replaceMissing bool
+	dosage         bool // Entries are uint16 fixed-point dosages (see EncodeDosage) instead of int8 hard calls
+
+	prepare func() // Creates filename before it is first opened (e.g. decodes a .pgen)
+}
+
+// Fixed-point dosages in dosage matrix files: DosageScale is one allele (as in .pgen dosage tracks)
+const (
+	DosageScale   = 16384
+	DosageMissing = math.MaxUint16
+)
+
+// EncodeDosage converts an allele dosage in [0, 2] (NaN if missing) to fixed point
+func EncodeDosage(d float64) uint16 {
+	if math.IsNaN(d) {
+		return DosageMissing
+	}
+	return uint16(math.Round(math.Max(0, math.Min(2, d)) * DosageScale))
+}
+
+// DosageHardCall rounds a fixed-point dosage to the nearest allele count (-1 if missing)
+func DosageHardCall(v uint16) int8 {
+	if v == DosageMissing {
+		return -1
+	}
+	return int8((uint32(v) + DosageScale/2) / DosageScale)
 }
 
 func NewGenoFileStream(filename string, numRow, numCol uint64, replaceMissing bool) *GenoFileStream {
@@ -324,11 +349,218 @@ func NewGenoFileStream(filename string, numRow, numCol uint64, replaceMissing bo
 	}
 }
 
+// NewDosageFileStream returns a stream over a sample-major matrix file of fixed-point
+// dosages (two bytes per entry, little-endian). NextRow returns hard calls
+func NewDosageFileStream(filename string, numRow, numCol uint64, replaceMissing bool) *GenoFileStream {
+
+	log.LLvl1(time.Now().Format(time.RFC3339), "NewDosageFileStream:", filename, numRow, numCol, replaceMissing)
+
+	if _, err := os.Stat(filename); err != nil {
+		panic(err)
+	}
+
+	return &GenoFileStream{
+		filename:       filename,
+		buf:            make([]byte, 2*numCol),
+		numRows:        numRow,
+		numCols:        numCol,
+		replaceMissing: replaceMissing,
+		dosage:         true,
+	}
+}
+
+// NewPgenGenoFileStream returns a stream over the variants of <pgenPrefix>.pgen/.psam/.pvar
+// with the given IDs, for the samples in keepFile (plink2 --keep format, empty for all).
+// The genotypes (dosages if useDosage is set) are decoded into cacheFile the first time
+// the stream is read
+func NewPgenGenoFileStream(pgenPrefix, keepFile string, variantIDs []string, numRow uint64, useDosage bool, cacheFile string, replaceMissing bool) *GenoFileStream {
+
+	log.LLvl1(time.Now().Format(time.RFC3339), "NewPgenGenoFileStream:", pgenPrefix, numRow, len(variantIDs), useDosage, replaceMissing)
+
+	return newCachedGenoFileStream(cacheFile, numRow, uint64(len(variantIDs)), useDosage, replaceMissing, func() {
+		PgenFilesetToGenoFile(pgenPrefix, keepFile, variantIDs, int(numRow), len(variantIDs), useDosage, cacheFile)
+	})
+}
+
+// NewVcfGenoFileStream returns a stream over the numCol variants of a VCF/BCF file for the
+// samples in keepFile (plink2 --keep format, empty for all), with allele counts taken from
+// field (see NewVcfReader). The genotypes (dosages if useDosage is set) are decoded into
+// cacheFile the first time the stream is read
+func NewVcfGenoFileStream(vcfFile, field, keepFile string, numRow, numCol uint64, useDosage bool, cacheFile string, replaceMissing bool) *GenoFileStream {
+
+	log.LLvl1(time.Now().Format(time.RFC3339), "NewVcfGenoFileStream:", vcfFile, field, numRow, numCol, useDosage, replaceMissing)
+
+	return newCachedGenoFileStream(cacheFile, numRow, numCol, useDosage, replaceMissing, func() {
+		sampleFilt := VcfSampleFilter(vcfFile, keepFile)
+		if uint64(SumBool(sampleFilt)) != numRow {
+			panic(fmt.Sprintf("%s: selected %d samples, expected %d", vcfFile, SumBool(sampleFilt), numRow))
+		}
+		if useDosage {
+			VcfToDosageFile(vcfFile, field, sampleFilt, int(numCol), cacheFile)
+		} else {
+			VcfToGenoFile(vcfFile, field, sampleFilt, int(numCol), cacheFile)
+		}
+	})
+}
+
+// newCachedGenoFileStream returns a stream over cacheFile (a hard call or dosage matrix
+// file), which is created with create (unless it exists) the first time the stream is read
+func newCachedGenoFileStream(cacheFile string, numRow, numCol uint64, dosage, replaceMissing bool, create func()) *GenoFileStream {
+	entrySize := uint64(1)
+	if dosage {
+		entrySize = 2
+	}
+	return &GenoFileStream{
+		filename:       cacheFile,
+		buf:            make([]byte, entrySize*numCol),
+		numRows:        numRow,
+		numCols:        numCol,
+		replaceMissing: replaceMissing,
+		dosage:         dosage,
+		prepare: func() {
+			if fileExists(cacheFile) {
+				log.LLvl1(time.Now().Format(time.RFC3339), "Cache file found:", cacheFile)
+				return
+			}
+			log.LLvl1(time.Now().Format(time.RFC3339), "Decoding genotypes into", cacheFile)
+			create()
+		},
+	}
+}
+
+// NewAlignedGenoFileStream returns a stream over the columns of src kept by colFilt, with the
+// allele counts of the columns with flip set recoded (2 - x) to count the other allele. The
+// aligned matrix is written to cacheFile the first time the stream is read
+func NewAlignedGenoFileStream(src *GenoFileStream, colFilt, flip []bool, cacheFile string, replaceMissing bool) *GenoFileStream {
+	numCols := SumBool(colFilt)
+
+	log.LLvl1(time.Now().Format(time.RFC3339), "NewAlignedGenoFileStream:", cacheFile, src.NumRows(), src.NumCols(), "->", numCols)
+
+	return newCachedGenoFileStream(cacheFile, src.NumRows(), uint64(numCols), src.IsDosage(), replaceMissing, func() {
+		keptFlip := make([]bool, 0, numCols)
+		for j := range colFilt {
+			if colFilt[j] {
+				keptFlip = append(keptFlip, flip[j])
+			}
+		}
+		src.UpdateColFilt(colFilt)
+		writeAlignedGenoFile(src, keptFlip, cacheFile)
+	})
+}
+
+func writeAlignedGenoFile(src *GenoFileStream, flip []bool, outputFile string) {
+	out, err := os.Create(outputFile)
+	if err != nil {
+		panic(err)
+	}
+	defer out.Close()
+
+	writer := bufio.NewWriter(out)
+	if src.IsDosage() {
+		buf := make([]byte, 2*len(flip))
+		for row := src.NextRowDosage(); row != nil; row = src.NextRowDosage() {
+			for j, v := range row {
+				if flip[j] && v != DosageMissing {
+					v = 2*DosageScale - v
+				}
+				binary.LittleEndian.PutUint16(buf[2*j:], v)
+			}
+			if _, err := writer.Write(buf); err != nil {
+				panic(err)
+			}
+		}
+	} else {
+		buf := make([]byte, len(flip))
+		for row := src.NextRow(); row != nil; row = src.NextRow() {
+			for j, x := range row {
+				if flip[j] && x >= 0 {
+					x = 2 - x
+				}
+				buf[j] = byte(x)
+			}
+			if _, err := writer.Write(buf); err != nil {
+				panic(err)
+			}
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		panic(err)
+	}
+}
+
+const genoWriteChunkSize = 4096 // Variants buffered by WriteGenoFileByVariant before writing
+
+// WriteGenoFileByVariant writes a sample-major int8 matrix file (the input of NewGenoFileStream)
+// from a variant-major source: next(c, geno) fills geno (one entry per sample of the source)
+// with variant c, c = 0..ncols-1 in order; samples with sampleFilt false are dropped
+func WriteGenoFileByVariant(outputFile string, sampleFilt []bool, ncols int, next func(c int, geno []int8)) {
+	geno := make([]int8, len(sampleFilt))
+	writeMatrixFileByVariant(outputFile, sampleFilt, ncols, 1, func(c int) { next(c, geno) }, func(i int, dst []byte) {
+		dst[0] = byte(geno[i])
+	})
+}
+
+// WriteDosageFileByVariant writes a sample-major dosage matrix file (the input of
+// NewDosageFileStream) as WriteGenoFileByVariant does, with next filling dosages (NaN if missing)
+func WriteDosageFileByVariant(outputFile string, sampleFilt []bool, ncols int, next func(c int, dosage []float64)) {
+	dosage := make([]float64, len(sampleFilt))
+	writeMatrixFileByVariant(outputFile, sampleFilt, ncols, 2, func(c int) { next(c, dosage) }, func(i int, dst []byte) {
+		binary.LittleEndian.PutUint16(dst, EncodeDosage(dosage[i]))
+	})
+}
+
+// writeMatrixFileByVariant calls next for each variant, then encode for each kept sample i
+// to write its entry of entrySize bytes
+func writeMatrixFileByVariant(outputFile string, sampleFilt []bool, ncols, entrySize int, next func(c int), encode func(i int, dst []byte)) {
+	nrows := SumBool(sampleFilt)
+	rowSize := int64(ncols) * int64(entrySize)
+
+	out, err := os.Create(outputFile)
+	if err != nil {
+		panic(err)
+	}
+	defer out.Close()
+	if err := out.Truncate(int64(nrows) * rowSize); err != nil {
+		panic(err)
+	}
+
+	chunk := make([][]byte, nrows) // chunk[row][(col - chunkStart) * entrySize]
+	for r := range chunk {
+		chunk[r] = make([]byte, Min(genoWriteChunkSize, ncols)*entrySize)
+	}
+
+	chunkStart := 0
+	for c := 0; c < ncols; c++ {
+		next(c)
+
+		r, off := 0, (c-chunkStart)*entrySize
+		for i, keep := range sampleFilt {
+			if keep {
+				encode(i, chunk[r][off:off+entrySize])
+				r++
+			}
+		}
+
+		if c-chunkStart+1 == genoWriteChunkSize || c == ncols-1 {
+			for r := range chunk {
+				if _, err := out.WriteAt(chunk[r][:off+entrySize], int64(r)*rowSize+int64(chunkStart*entrySize)); err != nil {
+					panic(err)
+				}
+			}
+			chunkStart = c + 1
+		}
+	}
+}
+
 func (gfs *GenoFileStream) readRow() []int8 {
 	if gfs.CheckEOF() {
 		return nil
 	}
 
+	if gfs.reader == nil { // Not opened yet
+		gfs.Reset()
+	}
+
 	_, err := io.ReadFull(gfs.reader, gfs.buf)
 	if err != nil {
 		panic(err)
@@ -338,13 +570,17 @@ func (gfs *GenoFileStream) readRow() []int8 {
 	if gfs.filtCols != nil {
 		intBuf = make([]int8, gfs.filtNumCol)
 	} else {
-		intBuf = make([]int8, len(gfs.buf))
+		intBuf = make([]int8, gfs.numCols)
 	}
 
 	idx := 0
-	for i := range gfs.buf {
+	for i := 0; i < int(gfs.numCols); i++ {
 		if gfs.filtCols == nil || gfs.filtCols[i] {
-			intBuf[idx] = int8(gfs.buf[i])
+			if gfs.dosage {
+				intBuf[idx] = DosageHardCall(binary.LittleEndian.Uint16(gfs.buf[2*i:]))
+			} else {
+				intBuf[idx] = int8(gfs.buf[i])
+			}
 
 			if gfs.replaceMissing && intBuf[idx] < 0 { // replace missing with zero
 				intBuf[idx] = 0
@@ -362,7 +598,7 @@ func (gfs *GenoFileStream) readRow() []int8 {
 func (gfs *GenoFileStream) Reset() {
 	var err error
 	if gfs.file == nil {
-		gfs.file, err = os.Open(gfs.filename)
+		gfs.file, err = os.Open(gfs.Filename())
 	} else {
 		_, err = gfs.file.Seek(0, io.SeekStart)
 	}
@@ -416,13 +652,70 @@ func (gfs *GenoFileStream) NextRow() []int8 {
 		return nil
 	}
 
+	gfs.skipFilteredRows()
+
+	return gfs.readRow()
+}
+
+// NextRowDosage returns the next row as fixed-point dosages (see EncodeDosage); hard calls
+// are converted for int8 files. Missing entries are DosageMissing, or zero if replaceMissing is set
+func (gfs *GenoFileStream) NextRowDosage() []uint16 {
+	if gfs.CheckEOF() {
+		return nil
+	}
+
+	gfs.skipFilteredRows()
+
+	if gfs.CheckEOF() {
+		return nil
+	}
+
+	if gfs.reader == nil { // Not opened yet
+		gfs.Reset()
+	}
+
+	_, err := io.ReadFull(gfs.reader, gfs.buf)
+	if err != nil {
+		panic(err)
+	}
+
+	dosBuf := make([]uint16, gfs.NumColsToKeep())
+
+	idx := 0
+	for i := 0; i < int(gfs.numCols); i++ {
+		if gfs.filtCols == nil || gfs.filtCols[i] {
+			if gfs.dosage {
+				dosBuf[idx] = binary.LittleEndian.Uint16(gfs.buf[2*i:])
+			} else if int8(gfs.buf[i]) < 0 {
+				dosBuf[idx] = DosageMissing
+			} else {
+				dosBuf[idx] = uint16(gfs.buf[i]) * DosageScale
+			}
+
+			if gfs.replaceMissing && dosBuf[idx] == DosageMissing {
+				dosBuf[idx] = 0
+			}
+
+			idx++
+		}
+	}
+
+	gfs.lineCount++
+
+	return dosBuf
+}
+
+func (gfs *GenoFileStream) skipFilteredRows() {
 	if gfs.filtRows != nil {
 		for gfs.lineCount < uint64(len(gfs.filtRows)) && !gfs.filtRows[gfs.lineCount] {
 			gfs.readRow()
 		}
 	}
+}
 
-	return gfs.readRow()
+// IsDosage returns whether the stream reads fixed-point dosages rather than hard calls
+func (gfs *GenoFileStream) IsDosage() bool {
+	return gfs.dosage
 }
 
 func (gfs *GenoFileStream) UpdateRowFilt(a []bool) int {
@@ -492,3 +785,12 @@ func (gfs *GenoFileStream) RowFilt() []bool {
 func (gfs *GenoFileStream) LineCount() uint64 {
 	return gfs.lineCount
 }
+
+// Filename returns the underlying sample-major matrix file, creating it first if needed
+func (gfs *GenoFileStream) Filename() string {
+	if gfs.prepare != nil {
+		gfs.prepare()
+		gfs.prepare = nil
+	}
+	return gfs.filename
+}
@@ -1,10 +1,8 @@
 package gwas
 
 import (
-	"bufio"
 	"os"
 	"path/filepath"
-	"strconv"
 	"time"
 
 	"go.dedis.ch/onet/v3/log"
@@ -34,7 +32,9 @@ type ProtocolInfo struct {
 
 	gwasParams *GWASParams
 
-	config *Config
+	config     *Config
+	configHash map[string]string // Per phase, see artifactConfigHashes
+	ckpt       *checkpointer     // nil unless checkpointing
 }
 
 type Config struct {
@@ -48,6 +48,7 @@ type Config struct {
 	NumInds    []int `toml:"num_inds"`
 	NumSnps    int   `toml:"num_snps"`
 	NumCovs    int   `toml:"num_covs"`
+	NumPhenos  int   `toml:"num_phenos"` // Columns of pheno_file, each tested separately (default 1)
 	CovAllOnes bool  `toml:"cov_all_ones"`
 
 	ItersPerEval  int `toml:"iter_per_eigenval"`
@@ -62,6 +63,12 @@ type Config struct {
 	UseCachedCombinedQ bool `toml:"use_cached_combined_q"`
 	SkipPowerIter      bool `toml:"skip_power_iter"`
 	PCARestartIter     int  `toml:"restart_pca_from_iter"`
+	Checkpoint         bool `toml:"checkpoint"` // Record each power iteration and assoc block, and resume after a crash
+
+	AssocTest        string `toml:"assoc_test"`         // 'linear' (default) or 'logistic' (binary 0/1 phenotype)
+	LogisticNumIters int    `toml:"logistic_num_iters"` // Newton iterations for the logistic null model
+	SumStatsGzip     bool   `toml:"sumstats_gzip"`      // Gzip the annotated summary statistics (assoc.glm.*)
+	AssocBetaSE      bool   `toml:"assoc_beta_se"`      // Also decrypt effect sizes and standard errors (linear test only)
 
 	IndMissUB    float64 `toml:"imiss_ub"`
 	HetLB        float64 `toml:"het_lb"`
@@ -71,13 +78,30 @@ type Config struct {
 	HweUB        float64 `toml:"hwe_ub"`
 	SnpDistThres int     `toml:"snp_dist_thres"`
 
+	Transport string `toml:"transport"` // 'tcp' (default) or 'inproc'
 	BindingIP string `toml:"binding_ipaddr"`
 	Servers   map[string]mpc.Server
 
-	SharedKeysPath string `toml:"shared_keys_path"`
+	NetConnectTimeout int  `toml:"net_connect_timeout"` // Seconds to wait for each peer at startup (0: no limit)
+	NetSendTimeout    int  `toml:"net_send_timeout"`    // Seconds allowed for sending one message (0: no limit)
+	NetReceiveTimeout int  `toml:"net_receive_timeout"` // Seconds to wait for one message (0: no limit)
+	NetChecksum       bool `toml:"net_checksum"`        // Add a CRC-32C of the payload to every message sent
+
+	UseTLS      bool   `toml:"use_tls"`
+	TLSCertFile string `toml:"tls_cert_file"` // PEM certificate with this party's name (e.g. "party1") as a DNS SAN
+	TLSKeyFile  string `toml:"tls_key_file"`
+	TLSCAFile   string `toml:"tls_ca_file"` // CA that signed every party certificate
+
+	SharedKeysPath    string `toml:"shared_keys_path"`
+	SharedKeyExchange bool   `toml:"shared_key_exchange"` // Derive PRG keys with X25519 at startup
+	PersistSharedKeys bool   `toml:"persist_shared_keys"` // Save exchanged keys to shared_keys_path
 
-	GenoFileFormat string `toml:"geno_file_format"`        // 'blocks' or 'pgen'
+	GenoFileFormat string `toml:"geno_file_format"`        // 'blocks', 'pgen', 'bed' or 'vcf'
 	GenoFilePrefix string `toml:"geno_binary_file_prefix"` // If 'pgen' expects a '%d' placeholder for chrom, e.g. "ukb_imp_chr%d_v3" (.pgen/.psam/.pvar)
+	// If 'bed' a single fileset split into blocks by chromosome, e.g. "ukb_cal" (.bed/.bim/.fam)
+	// If 'vcf' a file name with a '%d' placeholder for chrom, e.g. "chr%d.dose.vcf.gz" (VCF, bgzipped VCF or BCF)
+	VcfDosageField string `toml:"vcf_dosage_field"` // 'GT' (hard calls, default), 'DS' or 'GP' (dosages, rounded unless geno_dosage is set)
+	GenoDosage     bool   `toml:"geno_dosage"`      // Use dosages instead of hard calls ('pgen', 'vcf', or 'blocks' with dosage matrix files)
 
 	GenoNumBlocks     int    `toml:"geno_num_blocks"`
 	GenoBlockSizeFile string `toml:"geno_block_size_file"`
@@ -90,6 +114,10 @@ type Config struct {
 	GenoCountFile           string `toml:"geno_count_file"`
 	SampleKeepFile          string `toml:"sample_keep_file"`
 	SnpIdsFile              string `toml:"snp_ids_file"`
+	SnpAllelesFile          string `toml:"snp_alleles_file"` // REF and ALT of each SNP (tab-separated), to align 'blocks' input
+
+	AlignSnps          bool `toml:"align_snps"`           // Keep the SNPs shared by all parties, matched by chrom:pos:alleles
+	AlignKeepAmbiguous bool `toml:"align_keep_ambiguous"` // Keep A/T and C/G SNPs (matched on the forward strand only)
 
 	OutDir   string `toml:"output_dir"`
 	CacheDir string `toml:"cache_dir"`
@@ -120,10 +148,41 @@ func (prot *ProtocolInfo) IsBlockForAssocTest(blockId int) bool {
 	return false
 }
 
+func numPhenos(config *Config) int {
+	if config.NumPhenos <= 0 {
+		return 1
+	}
+	return config.NumPhenos
+}
+
+func (prot *ProtocolInfo) NumPhenos() int {
+	return numPhenos(prot.config)
+}
+
+// phenoSuffix distinguishes the output files of each phenotype when there is more than one
+func (prot *ProtocolInfo) phenoSuffix(pheno int) string {
+	if prot.NumPhenos() == 1 {
+		return ""
+	}
+	return fmt.Sprintf(".pheno%d", pheno+1)
+}
+
+func (prot *ProtocolInfo) IsLogisticAssoc() bool {
+	if prot.config.AssocTest == "logistic" {
+		return true
+	} else if prot.config.AssocTest == "linear" || prot.config.AssocTest == "" {
+		return false
+	} else {
+		panic(fmt.Sprint("Unsupported assoc_test:", prot.config.AssocTest))
+	}
+}
+
 func (prot *ProtocolInfo) IsPgen() bool {
-	if prot.config.GenoFileFormat == "pgen" {
+	if prot.config.AlignSnps { // Aligned input is read from the cached matrix files
+		return false
+	} else if prot.config.GenoFileFormat == "pgen" {
 		return true
-	} else if prot.config.GenoFileFormat == "blocks" {
+	} else if prot.config.GenoFileFormat == "blocks" || prot.config.GenoFileFormat == "bed" || prot.config.GenoFileFormat == "vcf" {
 		return false
 	} else {
 		panic(fmt.Sprint("Unsupported geno_file_format:", prot.config.GenoFileFormat))
@@ -151,6 +210,8 @@ func (prot *ProtocolInfo) GetGenoBlocks() []*GenoFileStream {
 }
 
 func InitializeGWASProtocol(config *Config, pid int, mpcOnly bool) (gwasProt *ProtocolInfo) {
+	configHash := artifactConfigHashes(config)
+
 	var chosen int
 	if !mpcOnly {
 		switch config.CkksParams {
@@ -170,7 +231,12 @@ func InitializeGWASProtocol(config *Config, pid int, mpcOnly bool) (gwasProt *Pr
 	}
 
 	prec := uint(config.MpcFieldSize)
-	networks := mpc.ParallelNetworks(mpc.InitCommunication(config.BindingIP, config.Servers, pid, config.NumMainParties+1, config.MpcNumThreads, config.SharedKeysPath))
+	networks := initNetworks(config, pid, config.MpcNumThreads)
+
+	var ckpt *checkpointer
+	if config.Checkpoint && !mpcOnly {
+		ckpt = agreeOnCheckpoint(config, networks, configHash[PhaseAssoc])
+	}
 
 	var params *ckks.Parameters
 	if !mpcOnly {
@@ -200,8 +266,13 @@ func InitializeGWASProtocol(config *Config, pid int, mpcOnly bool) (gwasProt *Pr
 	}
 
 	var cps *crypto.CryptoParams
-	if !mpcOnly {
+	if ckpt != nil && ckpt.resume != nil {
+		cps = ckpt.loadKeys(params, prec, pid)
+	} else if !mpcOnly {
 		cps = networks.CollectiveInit(params, prec)
+		if ckpt != nil {
+			ckpt.saveKeys(cps)
+		}
 	}
 
 	var pheno, cov *mat.Dense
@@ -210,59 +281,112 @@ func InitializeGWASProtocol(config *Config, pid int, mpcOnly bool) (gwasProt *Pr
 	var genoBlockSizes []int
 
 	isPgen := config.GenoFileFormat == "pgen"
+	isBed := config.GenoFileFormat == "bed"
+	isVcf := config.GenoFileFormat == "vcf"
 
 	genofs = make([]*GenoFileStream, config.GenoNumBlocks)
 	genoBlockSizes = make([]int, config.GenoNumBlocks)
 
-	if pid > 0 {
-		// Read geno block size file
-		file, err := os.Open(config.GenoBlockSizeFile)
-
-		if err != nil {
-			log.Fatalf("failed to open:", config.GenoBlockSizeFile)
+	if pid > 0 && isBed {
+		if config.GenoDosage {
+			log.Fatalf("geno_dosage is not supported for .bed input (hard calls only)")
 		}
-		scanner := bufio.NewScanner(file)
-		scanner.Split(bufio.ScanLines)
 
-		for i := 0; i < config.GenoNumBlocks; i++ {
-			if !scanner.Scan() {
-				log.Fatalf("not enough lines in", config.GenoBlockSizeFile)
-			}
+		// One .bed fileset; blocks and positions come from the chromosomes in the .bim
+		genofs, genoBlockSizes, pos = newBedGenoFileStreams(config.GenoFilePrefix, config.SampleKeepFile, config.CacheDir, config.GenoNumBlocks)
 
-			genoBlockSizes[i], err = strconv.Atoi(scanner.Text())
-			if err != nil {
-				log.Fatalf("parse error:", config.GenoBlockSizeFile)
+		totalSize := 0
+		for _, v := range genoBlockSizes {
+			totalSize += v
+		}
+		if totalSize != config.NumSnps && !config.AlignSnps {
+			log.Fatalf("%s.bim has %d SNPs but num_snps is %d", config.GenoFilePrefix, totalSize, config.NumSnps)
+		}
+		if n := genofs[0].NumRows(); n != uint64(config.NumInds[pid]) {
+			log.Fatalf("%s.fam has %d samples to keep but num_inds is %d", config.GenoFilePrefix, n, config.NumInds[pid])
+		}
+		if config.SnpPosFile != "" {
+			// snp_position_file overrides the positions in the .bim
+			pos = LoadSNPPositionFile(config.SnpPosFile, '\t')
+			if len(pos) != totalSize {
+				log.Fatalf("%s has %d positions but %s.bim has %d SNPs", config.SnpPosFile, len(pos), config.GenoFilePrefix, totalSize)
 			}
 		}
-
-		if scanner.Scan() {
-			log.Fatalf("too many lines in", config.GenoBlockSizeFile)
+	} else if pid > 0 {
+		if isVcf {
+			prepareVcfSidecarFiles(config)
 		}
 
-		file.Close()
+		genoBlockSizes = LoadBlockSizeFile(config.GenoBlockSizeFile)
+		if len(genoBlockSizes) != config.GenoNumBlocks {
+			log.Fatalf("%s has %d lines but geno_num_blocks is %d", config.GenoBlockSizeFile, len(genoBlockSizes), config.GenoNumBlocks)
+		}
 
 		totalSize := 0
 		for _, v := range genoBlockSizes {
 			totalSize += v
 		}
-		if totalSize != config.NumSnps {
+		if totalSize != config.NumSnps && !config.AlignSnps {
 			log.Fatalf("Sum of block sizes does not match number of snps")
 		}
 
-		if !isPgen {
+		if isVcf {
+			// One VCF/BCF per chromosome (block); the streams decode it on first use
+			for i := range genofs {
+				vcfFile := fmt.Sprintf(config.GenoFilePrefix, i+1) // 1-based
+				cacheFile := filepath.Join(config.CacheDir, fmt.Sprintf("geno_vcf%s.%d.bin", dosageSuffix(config), i))
+				genofs[i] = NewVcfGenoFileStream(vcfFile, config.VcfDosageField, config.SampleKeepFile, uint64(config.NumInds[pid]), uint64(genoBlockSizes[i]), config.GenoDosage, cacheFile, false)
+			}
+		} else if !isPgen {
 			// Create file streams for geno block files
 			for i := range genofs {
 				filename := fmt.Sprintf("%s.%d.bin", config.GenoFilePrefix, i)
 				log.LLvl1(time.Now().Format(time.RFC3339), "Opening geno file:", filename)
-				genofs[i] = NewGenoFileStream(filename, uint64(config.NumInds[pid]), uint64(genoBlockSizes[i]), false)
+				if config.GenoDosage {
+					genofs[i] = NewDosageFileStream(filename, uint64(config.NumInds[pid]), uint64(genoBlockSizes[i]), false)
+				} else {
+					genofs[i] = NewGenoFileStream(filename, uint64(config.NumInds[pid]), uint64(genoBlockSizes[i]), false)
+				}
+			}
+		} else {
+			// One .pgen fileset per chromosome (block); the streams decode it on first use
+			snpIDs := LoadSNPIDFile(config.SnpIdsFile)
+			if len(snpIDs) != totalSize {
+				log.Fatalf("%s has %d IDs but the block sizes add up to %d", config.SnpIdsFile, len(snpIDs), totalSize)
+			}
+
+			shift := 0
+			for i := range genofs {
+				pgenPrefix := fmt.Sprintf(config.GenoFilePrefix, i+1) // 1-based
+				cacheFile := filepath.Join(config.CacheDir, fmt.Sprintf("geno_pgen%s.%d.bin", dosageSuffix(config), i))
+				genofs[i] = NewPgenGenoFileStream(pgenPrefix, config.SampleKeepFile, snpIDs[shift:shift+genoBlockSizes[i]], uint64(config.NumInds[pid]), config.GenoDosage, cacheFile, false)
+				shift += genoBlockSizes[i]
 			}
 		}
 
+		pos = LoadSNPPositionFile(config.SnpPosFile, '\t')
+	}
+
+	if pid > 0 {
 		tab := '\t'
 		pheno = LoadMatrixFromFile(config.PhenoFile, tab)
+		if r, c := pheno.Dims(); c != numPhenos(config) {
+			log.Fatalf("%s has %d columns but num_phenos is %d", config.PhenoFile, c, numPhenos(config))
+		} else if r != config.NumInds[pid] {
+			log.Fatalf("%s has %d rows but num_inds is %d", config.PhenoFile, r, config.NumInds[pid])
+		}
 		cov = LoadMatrixFromFile(config.CovFile, tab)
-		pos = LoadSNPPositionFile(config.SnpPosFile, tab)
-		log.LLvl1(time.Now().Format(time.RFC3339), "First few SNP positions:", pos[:5])
+		if r, c := cov.Dims(); c != config.NumCovs {
+			log.Fatalf("%s has %d columns but num_covs is %d", config.CovFile, c, config.NumCovs)
+		} else if r != config.NumInds[pid] {
+			log.Fatalf("%s has %d rows but num_inds is %d", config.CovFile, r, config.NumInds[pid])
+		}
+		log.LLvl1(time.Now().Format(time.RFC3339), "First few SNP positions:", pos[:Min(len(pos), 5)])
+	}
+
+	if config.AlignSnps {
+		// Restrict every party to the SNPs shared by all, recoded to the hub's alleles
+		genofs, genoBlockSizes, pos = alignGenoInput(networks[0], config, pid, genofs, genoBlockSizes)
 	}
 
 	gwasParams := InitGWASParams(config.NumInds, config.NumSnps, config.NumCovs, config.NumPCs, config.SnpDistThres)
@@ -279,6 +403,8 @@ func InitializeGWASProtocol(config *Config, pid int, mpcOnly bool) (gwasProt *Pr
 
 		gwasParams: gwasParams,
 		config:     config,
+		configHash: configHash,
+		ckpt:       ckpt,
 	}
 }
 
@@ -310,6 +436,10 @@ func (g *ProtocolInfo) Phase1() {
 
 	log.LLvl1(time.Now().Format(time.RFC3339), "Finished QC")
 
+	if !g.config.UseCachedQC {
+		g.WritePhaseManifest(PhaseQC)
+	}
+
 	net.PrintNetworkLog()
 }
 
@@ -354,6 +484,10 @@ func (g *ProtocolInfo) Phase2() crypto.CipherMatrix {
 
 	log.LLvl1(time.Now().Format(time.RFC3339), "Finished PCA")
 
+	if !g.config.UseCachedPCA {
+		g.WritePhaseManifest(PhasePCA)
+	}
+
 	net.PrintNetworkLog()
 
 	return Qpca
@@ -372,29 +506,51 @@ func (g *ProtocolInfo) Phase3(Qpca crypto.CipherMatrix) {
 
 	net.PrintNetworkLog()
 
-	// Collective decrypt and save to file
-	if g.mpcObj[0].GetPid() > 0 {
-		assocDec := g.mpcObj[0].Network.CollectiveDecryptVec(g.cps, assoc, -1)
-		out := crypto.DecodeFloatVector(g.cps, assocDec)
-
-		outFinal := make([]float64, SumBool(outFilter))
-		index := 0
-		for i := range outFilter {
-			if outFilter[i] {
-				outFinal[index] = out[i]
-				index++
+	// Collective decrypt and save to file, one set of outputs per phenotype
+	for t, assocT := range assoc {
+		assocPath := g.OutPath("assoc" + g.phenoSuffix(t) + ".txt")
+		if g.mpcObj[0].GetPid() > 0 {
+			outFinal := g.decryptAssocOutput(assocT.Stat, outFilter)
+			SaveFloatVectorToFile(assocPath, outFinal)
+
+			var beta, seScale []float64
+			if assocT.Beta != nil {
+				beta = g.decryptAssocOutput(assocT.Beta, outFilter)
+				seScale = g.decryptAssocOutput(assocT.SEScale, outFilter)
 			}
+
+			g.WriteAssocSumStats(t, outFinal, beta, seScale, outFilter, len(Qpca))
+			log.LLvl1(time.Now().Format(time.RFC3339), fmt.Sprintf("Summary statistics saved to: %s", g.SumStatsPath(t)))
 		}
+		log.LLvl1(time.Now().Format(time.RFC3339), fmt.Sprintf("Output collectively decrypted and saved to: %s", assocPath))
+	}
 
-		SaveFloatVectorToFile(g.OutPath("assoc.txt"), outFinal)
+	g.WritePhaseManifest(PhaseAssoc)
+	g.clearCheckpoints()
+}
+
+// decryptAssocOutput collectively decrypts a per-SNP output and keeps the entries in outFilter
+func (g *ProtocolInfo) decryptAssocOutput(cv crypto.CipherVector, outFilter []bool) []float64 {
+	dec := g.mpcObj[0].Network.CollectiveDecryptVec(g.cps, cv, -1)
+	out := crypto.DecodeFloatVector(g.cps, dec)
+
+	outFinal := make([]float64, SumBool(outFilter))
+	index := 0
+	for i := range outFilter {
+		if outFilter[i] {
+			outFinal[index] = out[i]
+			index++
+		}
 	}
-	log.LLvl1(time.Now().Format(time.RFC3339), fmt.Sprintf("Output collectively decrypted and saved to: %s", g.OutPath("assoc.txt")))
+	return outFinal
 }
 
 func (g *ProtocolInfo) GWAS() {
 
 	log.LLvl1(time.Now().Format(time.RFC3339), "Starting GWAS protocol")
 
+	g.CheckPhaseInputs()
+
 	g.Phase1()
 	Qpca := g.Phase2()
 	g.Phase3(Qpca)
@@ -526,6 +682,75 @@ func (g *ProtocolInfo) SyncAndTerminate(closeChannelFlag bool) {
 
 }
 
+// initNetworks connects this party to every peer over the configured transport, with
+// one network per thread
+func initNetworks(config *Config, pid, numThreads int) mpc.ParallelNetworks {
+	var tlsConf *mpc.TLSConfig
+	if config.UseTLS {
+		tlsConf = &mpc.TLSConfig{
+			CertFile: config.TLSCertFile,
+			KeyFile:  config.TLSKeyFile,
+			CAFile:   config.TLSCAFile,
+		}
+	}
+	transport, err := mpc.NewTransport(config.Transport, config.BindingIP, config.Servers, tlsConf)
+	if err != nil {
+		log.Fatalf("Party %d cannot set up the %q transport: %v", pid, config.Transport, err)
+	}
+	keyConf := mpc.SharedKeyConfig{
+		Path:     config.SharedKeysPath,
+		Exchange: config.SharedKeyExchange,
+		Persist:  config.PersistSharedKeys,
+	}
+	timeouts := mpc.Timeouts{
+		Connect: time.Duration(config.NetConnectTimeout) * time.Second,
+		Send:    time.Duration(config.NetSendTimeout) * time.Second,
+		Receive: time.Duration(config.NetReceiveTimeout) * time.Second,
+	}
+	networks, err := mpc.InitCommunication(transport, pid, config.NumMainParties+1, numThreads, keyConf, timeouts)
+	if err != nil {
+		log.Fatalf("Party %d cannot connect to the other parties: %v", pid, err)
+	}
+	if config.NetChecksum {
+		for _, n := range networks {
+			n.EnableChecksums()
+		}
+	}
+	return networks
+}
+
+// dosageSuffix distinguishes the cached dosage matrices from the hard call ones
+func dosageSuffix(config *Config) string {
+	if config.GenoDosage {
+		return "_dosage"
+	}
+	return ""
+}
+
+// prepareVcfSidecarFiles writes the SNP IDs, positions and block sizes of the VCF input,
+// unless the configured files exist; unset paths default to the cache directory
+func prepareVcfSidecarFiles(config *Config) {
+	if config.SnpIdsFile == "" {
+		config.SnpIdsFile = filepath.Join(config.CacheDir, "vcf_snp_ids.txt")
+	}
+	if config.SnpPosFile == "" {
+		config.SnpPosFile = filepath.Join(config.CacheDir, "vcf_snp_pos.txt")
+	}
+	if config.GenoBlockSizeFile == "" {
+		config.GenoBlockSizeFile = filepath.Join(config.CacheDir, "vcf_block_sizes.txt")
+	}
+
+	if fileExists(config.SnpIdsFile) && fileExists(config.SnpPosFile) && fileExists(config.GenoBlockSizeFile) {
+		return
+	}
+
+	vcfFiles := make([]string, config.GenoNumBlocks)
+	for i := range vcfFiles {
+		vcfFiles[i] = fmt.Sprintf(config.GenoFilePrefix, i+1)
+	}
+	WriteVcfSidecarFiles(vcfFiles, config.SnpIdsFile, config.SnpPosFile, config.GenoBlockSizeFile)
+}
+
 func (g *ProtocolInfo) OutPath(filename string) string {
 	return filepath.Join(g.config.OutDir, filename)
 }
@@ -556,11 +781,11 @@ func (g *ProtocolInfo) GeneratePCAInput(numSnpsPCA int, snpFiltPCA []bool, isPge
 				m := g.genoBlockSizes[chr]
 				numSnpsPCAPerBlock[chr] = SumBool(snpFiltPCA[shift : shift+m])
 
-				FilterMatrixFilePgen(pgenPrefix, numIndsPCA, numSnpsPCAPerBlock[chr], g.config.SampleKeepFile, g.config.SnpIdsFile, shift, snpFiltPCA[shift:shift+m], outFile)
+				FilterMatrixFilePgen(pgenPrefix, numIndsPCA, numSnpsPCAPerBlock[chr], g.config.SampleKeepFile, g.config.SnpIdsFile, shift, snpFiltPCA[shift:shift+m], false, outFile) // PCA uses hard calls
 
 				shift += m
 			}
-		} else { // blocks format
+		} else { // blocks, bed or vcf format
 			indFilt := g.genoBlocks[0].RowFilt()
 			if indFilt == nil {
 				indFilt = OnesBool(int(g.genoBlocks[0].NumRows()))
@@ -568,12 +793,16 @@ func (g *ProtocolInfo) GeneratePCAInput(numSnpsPCA int, snpFiltPCA []bool, isPge
 
 			shift := 0
 			for i := range g.genoBlocks { // TODO parallelize
-				genoFile := fmt.Sprintf("%s.%d.bin", g.config.GenoFilePrefix, i)
+				genoFile := g.genoBlocks[i].Filename()
 				outFile := g.CachePath(fmt.Sprintf("geno_pca.%d.bin", i))
 
 				n, m := int(g.genoBlocks[i].NumRows()), int(g.genoBlocks[i].NumCols())
 
-				FilterMatrixFile(genoFile, n, m, indFilt, snpFiltPCA[shift:shift+m], outFile)
+				if g.genoBlocks[i].IsDosage() { // PCA uses hard calls
+					FilterDosageMatrixFile(genoFile, n, m, indFilt, snpFiltPCA[shift:shift+m], outFile)
+				} else {
+					FilterMatrixFile(genoFile, n, m, indFilt, snpFiltPCA[shift:shift+m], outFile)
+				}
 
 				numSnpsPCAPerBlock[i] = SumBool(snpFiltPCA[shift : shift+m])
 
@@ -687,7 +916,7 @@ func (g *ProtocolInfo) PopulationStratification() crypto.CipherMatrix {
 
 }
 
-func (g *ProtocolInfo) ComputeAssocStatistics(Qpca crypto.CipherMatrix) (crypto.CipherVector, []bool) {
+func (g *ProtocolInfo) ComputeAssocStatistics(Qpca crypto.CipherMatrix) ([]AssocStats, []bool) {
 	assocTest := g.InitAssociationTests(Qpca)
 	return assocTest.GetAssociationStats()
 }
@@ -0,0 +1,92 @@
+package gwas
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// InitializeGWASProtocol on .bed input: every data party gets its genotype blocks from the
+// .bim, its phenotypes and covariates, and positions from the .bim unless snp_position_file
+// is set (party 2)
+func TestInitializeBed(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	phenoFile := write("pheno.txt", "1.5\n0.2\n-0.7\n2.1\n0.9\n")
+	covFile := write("cov.txt", "1\t40\n0\t52\n1\t61\n0\t35\n1\t47\n")
+	posFile := write("pos.txt", "1\t100\n1\t200\n23\t300\n")
+
+	prots := make([]*ProtocolInfo, 3)
+	var wg sync.WaitGroup
+	for pid := range prots {
+		config := &Config{
+			NumMainParties:    2,
+			HubPartyId:        1,
+			NumInds:           []int{0, 5, 5},
+			NumSnps:           3,
+			NumCovs:           2,
+			Transport:         "inproc",
+			SharedKeyExchange: true,
+			GenoFileFormat:    "bed",
+			GenoFilePrefix:    "testdata/toy",
+			GenoNumBlocks:     2,
+			PhenoFile:         phenoFile,
+			CovFile:           covFile,
+			CacheDir:          filepath.Join(dir, fmt.Sprintf("cache%d", pid)),
+			MpcFieldSize:      128,
+			MpcDataBits:       60,
+			MpcFracBits:       30,
+			MpcNumThreads:     1,
+		}
+		if pid == 2 {
+			config.SnpPosFile = posFile
+		}
+		if err := os.MkdirAll(config.CacheDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		wg.Add(1)
+		go func(pid int) {
+			defer wg.Done()
+			prots[pid] = InitializeGWASProtocol(config, pid, true)
+		}(pid)
+	}
+	wg.Wait()
+
+	for pid := 1; pid < len(prots); pid++ {
+		g := prots[pid]
+		if !reflect.DeepEqual(g.genoBlockSizes, []int{2, 1}) {
+			t.Errorf("party %d: block sizes %v, want [2 1]", pid, g.genoBlockSizes)
+		}
+		if g.pheno == nil || g.cov == nil {
+			t.Fatalf("party %d: phenotypes or covariates not loaded", pid)
+		}
+		if r, c := g.pheno.Dims(); r != 5 || c != 1 || g.pheno.At(3, 0) != 2.1 {
+			t.Errorf("party %d: phenotypes %d x %d, want 5 x 1", pid, r, c)
+		}
+		if r, c := g.cov.Dims(); r != 5 || c != 2 || g.cov.At(2, 1) != 61 {
+			t.Errorf("party %d: covariates %d x %d, want 5 x 2", pid, r, c)
+		}
+	}
+	if want := []uint64{1e9 + 10583, 1e9 + 10611, 23e9 + 2699520}; !reflect.DeepEqual(prots[1].pos, want) {
+		t.Errorf("party 1: positions %v, want those of the .bim %v", prots[1].pos, want)
+	}
+	if want := []uint64{1e9 + 100, 1e9 + 200, 23e9 + 300}; !reflect.DeepEqual(prots[2].pos, want) {
+		t.Errorf("party 2: positions %v, want those of snp_position_file %v", prots[2].pos, want)
+	}
+
+	for _, g := range prots {
+		for _, n := range g.mpcObj.GetNetworks() {
+			n.CloseAll()
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package gwas
+
+import (
+	"fmt"
+	"time"
+
+	mpc_core "github.com/hhcho/mpc-core"
+	"github.com/ldsec/lattigo/v2/ckks"
+	"go.dedis.ch/onet/v3/log"
+	"gonum.org/v1/gonum/mat"
+
+	"github.com/hhcho/sfgwas-private/crypto"
+)
+
+const defaultLogisticNumIters = 10
+
+// fitNullLogistic fits the logistic null model of a phenotype (column of pheno_file) on the columns of Q
+// (covariates and PCs, with Q'Q = nI) in secret shares. Since the Hessian of the
+// log-likelihood is bounded by Q'Q/4, each Newton step uses that fixed bound instead of
+// inverting a new matrix: beta += (4/n) * Q'(y - sigmoid(Q*beta)).
+// Returns this party's null residuals y - p (encrypted) and the sum of the null weights
+// p(1-p) over all samples (encrypted in the first slot). Called by all parties.
+func (ast *AssocTest) fitNullLogistic(Q crypto.CipherMatrix, ncol, pheno int) (crypto.CipherVector, *ckks.Ciphertext) {
+	cryptoParams := ast.general.cps
+	mpcPar := ast.general.mpcObj
+	mpcObj := mpcPar[0]
+	pid := mpcObj.GetPid()
+	rtype := mpcObj.GetRType()
+	dataBits := mpcObj.GetDataBits()
+	fracBits := mpcObj.GetFracBits()
+	slots := cryptoParams.GetSlots()
+
+	numIters := ast.general.config.LogisticNumIters
+	if numIters <= 0 {
+		numIters = defaultLogisticNumIters
+	}
+
+	/* Offsets of each party's samples in the joint sample vector */
+	nrowsAll := ast.general.gwasParams.FiltNumInds()
+	offset := make([]int, len(nrowsAll)+1)
+	for p := 1; p < len(nrowsAll); p++ {
+		offset[p+1] = offset[p] + nrowsAll[p]
+	}
+	nrowsTotal := offset[len(nrowsAll)]
+
+	/* Secret share Q and y; y is held in full by its owner and zero elsewhere */
+	QSS := mpc_core.InitRMat(rtype.Zero(), ncol, nrowsTotal)
+	ySS := mpc_core.InitRVec(rtype.Zero(), nrowsTotal)
+	for p := 1; p < len(nrowsAll); p++ {
+		numCtx := 1 + (nrowsAll[p]-1)/slots
+		Qp := mpcObj.CMatToSS(cryptoParams, rtype, Q, p, ncol, numCtx, nrowsAll[p])
+		for c := range QSS {
+			copy(QSS[c][offset[p]:offset[p+1]], Qp[c])
+		}
+
+		if pid == p {
+			yf := mat.Col(nil, pheno, ast.general.pheno)
+			for i := range yf {
+				if yf[i] != 0 && yf[i] != 1 {
+					panic(fmt.Sprintf("logistic association test requires a 0/1 phenotype, found %f (sample %d)", yf[i], i))
+				}
+			}
+			copy(ySS[offset[p]:offset[p+1]], mpc_core.FloatToRVec(rtype, yf, fracBits))
+		}
+	}
+	log.LLvl1(time.Now().Format(time.RFC3339), fmt.Sprintf("Logistic null model (phenotype %d): Q and y converted to secret shares", pheno+1))
+
+	QtSS := QSS.Transpose()
+	stepScale := rtype.FromFloat64(4.0/float64(nrowsTotal), fracBits)
+
+	beta := mpc_core.InitRMat(rtype.Zero(), ncol, 1)
+	var prob, resid mpc_core.RVec
+	for it := 0; ; it++ {
+		eta := mpcObj.SSMultMat(QtSS, beta) // Q * beta
+		eta = mpcObj.TruncMat(eta, dataBits, fracBits)
+		prob = mpcPar.SSSigmoidVec(eta.Transpose()[0])
+
+		resid = ySS.Copy()
+		resid.Sub(prob)
+
+		if it == numIters {
+			break
+		}
+
+		grad := mpcObj.SSMultMat(QSS, mpc_core.RMat{resid}.Transpose()) // Q' * (y - p)
+		grad = mpcObj.TruncMat(grad, dataBits, fracBits)
+		for c := range grad {
+			grad[c][0] = grad[c][0].Mul(stepScale)
+		}
+		grad = mpcObj.TruncMat(grad, dataBits, fracBits)
+		beta.Add(grad)
+
+		log.LLvl1(time.Now().Format(time.RFC3339), fmt.Sprintf("Logistic null model: iteration %d/%d done", it+1, numIters))
+		if ast.general.config.Debug {
+			log.LLvl1(time.Now().Format(time.RFC3339), "beta", mpcObj.RevealSymMat(beta).Transpose()[0].ToFloat(fracBits))
+		}
+	}
+
+	/* Null weights p(1-p), summed over samples */
+	weights := mpcPar.SSSquareElemVec(prob)
+	weights = mpcObj.TruncVec(weights, dataBits, fracBits)
+	weights.MulScalar(rtype.One().Neg())
+	weights.Add(prob)
+	wSum := rtype.Zero()
+	for i := range weights {
+		wSum = wSum.Add(weights[i])
+	}
+
+	var res crypto.CipherVector
+	for p := 1; p < len(nrowsAll); p++ {
+		cv := mpcObj.SSToCVec(cryptoParams, resid[offset[p]:offset[p+1]])
+		if pid == p {
+			res = cv
+		}
+	}
+	wSumCt := mpcObj.SStoCiphertext(cryptoParams, mpc_core.RVec{wSum})
+
+	log.LLvl1(time.Now().Format(time.RFC3339), "Logistic null model fitted")
+
+	return res, wSumCt
+}
@@ -592,6 +592,107 @@ func (b BlockI8) Dims() (int, int) {
 	return b.r, b.c
 }
 
+// BlockU16 holds fixed-point dosages (see EncodeDosage) with missing entries set to zero
+type BlockU16 struct {
+	data [][]uint16
+	r    int
+	c    int
+}
+
+func NewBlockU16(r, c int) BlockU16 {
+	return BlockU16{
+		data: make([][]uint16, r),
+		r:    r,
+		c:    c,
+	}
+}
+
+func (b BlockU16) At(i, j int) float64 {
+	return float64(b.data[i][j]) / DosageScale
+}
+
+func (b BlockU16) Dims() (int, int) {
+	return b.r, b.c
+}
+
+// readBlockRow reads the next nr rows of gfs (ncol columns) and splits them into blocks
+// of slots columns, using BlockU16 for dosage streams and BlockI8 otherwise. Missing entries
+// are set to zero if replaceMissing is set. If sum and sqSum are not nil, the column sums
+// of the entries and their squares are accumulated
+func readBlockRow(gfs *GenoFileStream, nr, ncol, slots int, replaceMissing bool, sum, sqSum []float64) BlockVector {
+	m_ct := (ncol-1)/slots + 1
+	blockVec := make(BlockVector, m_ct)
+
+	if gfs.IsDosage() {
+		BSlice := make([]BlockU16, m_ct)
+		for ri := 0; ri < nr; ri++ {
+
+			// Read one row from file
+			row := gfs.NextRowDosage()
+
+			for rj := range row {
+				if replaceMissing && row[rj] == DosageMissing {
+					row[rj] = 0
+				}
+
+				if sum != nil {
+					x := float64(row[rj]) / DosageScale
+					sqSum[rj] += x * x
+					sum[rj] += x
+				}
+			}
+
+			// Add slice to each block matrix
+			for bj := range BSlice {
+				j1 := bj * slots
+				j2 := Min((bj+1)*slots, ncol)
+				if ri == 0 {
+					BSlice[bj] = NewBlockU16(nr, j2-j1)
+				}
+				BSlice[bj].data[ri] = row[j1:j2]
+			}
+		}
+
+		for bj := range blockVec {
+			blockVec[bj] = Block(BSlice[bj])
+		}
+		return blockVec
+	}
+
+	BSlice := make([]BlockI8, m_ct)
+	for ri := 0; ri < nr; ri++ {
+
+		// Read one row from file
+		row := gfs.NextRow()
+
+		for rj := range row {
+			if replaceMissing && row[rj] < 0 {
+				row[rj] = 0
+			}
+
+			if sum != nil {
+				sqSum[rj] += float64(row[rj] * row[rj])
+				sum[rj] += float64(row[rj])
+			}
+		}
+
+		// Add slice to each block matrix
+		for bj := range BSlice {
+			j1 := bj * slots
+			j2 := Min((bj+1)*slots, ncol)
+			if ri == 0 {
+				BSlice[bj] = NewBlockI8(nr, j2-j1)
+			}
+			BSlice[bj].data[ri] = row[j1:j2]
+		}
+	}
+
+	for bj := range blockVec {
+		blockVec[bj] = Block(BSlice[bj])
+	}
+	return blockVec
+}
+
 type BlockF64 mat.Dense
 
 func (b BlockF64) At(i, j int) float64 {
@@ -932,29 +1033,8 @@ func MatMult4StreamPreprocess(cryptoParams *crypto.CryptoParams, gfs *GenoFileSt
 
 		log.LLvl1(time.Now().Format(time.RFC3339), "Block row", bi+1, "/", numBlockRows, "gathering submatrix")
 
-		BSlice := make([]BlockI8, m_ct)
 		nr := Min((bi+1)*slots, int(gfs.NumRows())) - bi*slots
-		for ri := 0; ri < nr; ri++ {
-
-			// Read one row from file
-			row := gfs.NextRow()
-
-			// Add slice to each block matrix
-			for bj := range BSlice {
-				j1 := bj * slots
-				j2 := Min((bj+1)*slots, int(gfs.NumCols()))
-				nc := j2 - j1
-				if ri == 0 {
-					BSlice[bj] = NewBlockI8(nr, nc)
-				}
-				BSlice[bj].data[ri] = row[j1:j2]
-			}
-		}
-
-		blockVec := make(BlockVector, m_ct)
-		for bj := range blockVec {
-			blockVec[bj] = Block(BSlice[bj])
-		}
+		blockVec := readBlockRow(gfs, nr, int(gfs.NumCols()), slots, false, nil, nil)
 
 		log.LLvl1(time.Now().Format(time.RFC3339), "Block row", bi+1, "/", numBlockRows, "finding active diagonals")
 
@@ -1281,41 +1361,9 @@ func MatMult4Stream(cryptoParams *crypto.CryptoParams, A crypto.CipherMatrix, gf
 
 		log.LLvl1(time.Now().Format(time.RFC3339), "Block row", bi+1, "/", numBlockRows, "gathering submatrix")
 
-		BSlice := make([]BlockI8, m_ct)
+		// Replace missing with zeros
 		nr := Min((bi+1)*slots, int(nrow)) - bi*slots
-		for ri := 0; ri < nr; ri++ {
-
-			// Read one row from file
-			row := gfs.NextRow()
-
-			// Replace missing with zeros
-			for rj := range row {
-				if row[rj] < 0 {
-					row[rj] = 0
-				}
-
-				if computeSquaredSum {
-					sqSum[rj] += float64(row[rj] * row[rj])
-					sum[rj] += float64(row[rj])
-				}
-			}
-
-			// Add slice to each block matrix
-			for bj := range BSlice {
-				j1 := bj * slots
-				j2 := Min((bj+1)*slots, int(ncol))
-				nc := j2 - j1
-				if ri == 0 {
-					BSlice[bj] = NewBlockI8(nr, nc)
-				}
-				BSlice[bj].data[ri] = row[j1:j2]
-			}
-		}
-
-		blockVec := make(BlockVector, m_ct)
-		for bj := range blockVec {
-			blockVec[bj] = Block(BSlice[bj])
-		}
+		blockVec := readBlockRow(gfs, nr, int(ncol), slots, true, sum, sqSum)
 
 		log.LLvl1(time.Now().Format(time.RFC3339), "Block row", bi+1, "/", numBlockRows, "finding active diagonals")
 
@@ -3,6 +3,7 @@ package gwas
 import (
 	"fmt"
 	"math"
+	"os"
 	"time"
 
 	"go.dedis.ch/onet/v3/log"
@@ -243,7 +244,12 @@ func (pca *PCA) DistributedPCA() crypto.CipherMatrix {
 
 	if !skipPowerIter {
 
-		if restartIter <= 0 {
+		ckptIter := pca.general.resumeIndex(PhasePCA)
+		if ckptIter >= 0 {
+			restartIter = ckptIter
+		}
+
+		if restartIter <= 0 && ckptIter < 0 {
 
 			if pid > 0 {
 				// Normalize to reduce value range of Q
@@ -311,7 +317,12 @@ func (pca *PCA) DistributedPCA() crypto.CipherMatrix {
 		} else { // Load in cached Q
 			log.LLvl1(time.Now().Format(time.RFC3339), "Restarting power iteration from iter ", restartIter+1, "/", nPowerIter)
 
-			if pid > 0 {
+			if pid > 0 && ckptIter >= 0 {
+				cacheFile := pca.general.checkpointPath("pca_qloc.%d.bin", ckptIter)
+				Qloc = crypto.LoadCipherMatrixFromFile(cryptoParams, cacheFile)
+
+				log.LLvl1(time.Now().Format(time.RFC3339), "Checkpoint loaded. Number of rows:", kp, cacheFile)
+			} else if pid > 0 {
 				// TODO cache ciphertexts instead
 				cacheFile := pca.general.CachePath(fmt.Sprintf("QmulB_%d.txt", restartIter))
 				mat := LoadMatrixFromFileFloat(cacheFile, ',')
@@ -331,7 +342,7 @@ func (pca *PCA) DistributedPCA() crypto.CipherMatrix {
 		}
 
 		itStart := 0
-		if restartIter > 0 {
+		if restartIter > 0 || ckptIter >= 0 {
 			itStart = restartIter + 1
 		}
 
@@ -368,6 +379,23 @@ func (pca *PCA) DistributedPCA() crypto.CipherMatrix {
 			} else {
 				Q = NetDQRenc(cryptoParams, mpcObj, Qloc, nRowsAll)
 			}
+
+			if pca.general.ckpt != nil {
+				if pid > 0 {
+					cacheFile := pca.general.checkpointPath("pca_qloc.%d.bin", it)
+					saveAtomic(cacheFile, func(tmpName string) {
+						crypto.SaveCipherMatrixToFile(cryptoParams, Qloc, tmpName)
+					})
+				}
+				pca.general.saveCheckpoint(PhasePCA, it)
+				// Parties are at most one iteration apart, so agreeOnCheckpoint never resumes
+				// before it-1 once this party has recorded it
+				if pid > 0 && it >= 2 {
+					if err := os.Remove(pca.general.checkpointPath("pca_qloc.%d.bin", it-2)); err != nil && !os.IsNotExist(err) {
+						panic(err)
+					}
+				}
+			}
 		}
 		log.LLvl1(time.Now().Format(time.RFC3339), "Power iteration complete")
 
@@ -0,0 +1,691 @@
+package gwas
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Storage modes of the .pgen header (third byte)
+const (
+	pgenModeFixedHardcall = 0x02 // Fixed-width, 2-bit hardcalls only
+	pgenModeFixedDosage   = 0x03 // Fixed-width, 2-bit hardcalls followed by 16-bit dosages
+	pgenModeVariable      = 0x10 // Variable-width records with per-variant record types
+)
+
+const (
+	pgenBlockSize     = 1 << 16 // Variants per block of the variable-width index
+	pgenDifflistGroup = 64      // Difflist entries per group (first sample ID stored in full)
+	pgenDosageMissing = 65535
+	pgenDosageUnit    = 16384 // Dosage of one ALT allele
+)
+
+// PgenReader decodes the variants of a PLINK 2 .pgen file sequentially. Genotypes are
+// ALT allele counts (0, 1, 2; missing is -1). Supports the fixed-width hardcall/dosage
+// modes and the variable-width mode (2-bit, 1-bit, difflist and LD-compressed hardcall
+// tracks and unphased dosage tracks; phase tracks are skipped)
+type PgenReader struct {
+	filename    string
+	file        *os.File
+	reader      *bufio.Reader
+	mode        byte
+	numVariants int
+	numSamples  int
+
+	// Variable-width mode
+	vrtypes      []byte
+	vrecLens     []uint32
+	recordOffset int64 // Offset of the first variant record
+	sampleIDSize int   // Bytes per sample ID in difflists
+
+	next   int     // Index of the next variant
+	rec    []byte  // Current variant record
+	geno   []uint8 // 2-bit codes of the current variant (0, 1, 2 ALT alleles; 3 missing)
+	ldBase []uint8 // Codes of the last variant that is not LD-compressed
+}
+
+func NewPgenReader(filename string) *PgenReader {
+	file, err := os.Open(filename)
+	if err != nil {
+		panic(err)
+	}
+
+	pr := &PgenReader{filename: filename, file: file}
+	pr.readHeader()
+	pr.Reset()
+	return pr
+}
+
+func (pr *PgenReader) readHeader() {
+	header := make([]byte, 11)
+	if _, err := io.ReadFull(pr.file, header); err != nil {
+		panic(fmt.Sprintf("%s: failed to read header: %v", pr.filename, err))
+	}
+	if header[0] != 0x6c || header[1] != 0x1b {
+		panic(fmt.Sprintf("%s: not a .pgen file", pr.filename))
+	}
+
+	pr.mode = header[2]
+	pr.numVariants = int(binary.LittleEndian.Uint32(header[3:]))
+	pr.numSamples = int(binary.LittleEndian.Uint32(header[7:]))
+	pr.sampleIDSize = bytesToRepresent(uint32(pr.numSamples))
+
+	switch pr.mode {
+	case pgenModeFixedHardcall, pgenModeFixedDosage:
+		pr.recordOffset = 11
+		return
+	case pgenModeVariable:
+	default:
+		panic(fmt.Sprintf("%s: unsupported .pgen storage mode 0x%02x", pr.filename, pr.mode))
+	}
+
+	reader := bufio.NewReader(pr.file)
+	ctrl, err := reader.ReadByte()
+	if err != nil {
+		panic(err)
+	}
+
+	// Bits 0-3: vrtype width and vrec_len byte count, bits 4-5: ALT allele count
+	// byte count, bits 6-7: nonref flag storage (3 = explicit per-variant flags)
+	var vrtypeBits, vrecLenSize int
+	switch v := int(ctrl & 15); {
+	case v < 4:
+		vrtypeBits, vrecLenSize = 4, v+1
+	case v < 8:
+		vrtypeBits, vrecLenSize = 8, v-3
+	default:
+		panic(fmt.Sprintf("%s: unsupported .pgen header control byte 0x%02x", pr.filename, ctrl))
+	}
+	alleleCtSize := int((ctrl >> 4) & 3)
+	explicitNonref := (ctrl >> 6) == 3
+
+	numBlocks := (pr.numVariants + pgenBlockSize - 1) / pgenBlockSize
+	blockOffsets := make([]byte, 8*numBlocks)
+	if _, err := io.ReadFull(reader, blockOffsets); err != nil {
+		panic(err)
+	}
+	if numBlocks > 0 {
+		pr.recordOffset = int64(binary.LittleEndian.Uint64(blockOffsets))
+	}
+
+	pr.vrtypes = make([]byte, pr.numVariants)
+	pr.vrecLens = make([]uint32, pr.numVariants)
+	for b := 0; b < numBlocks; b++ {
+		start := b * pgenBlockSize
+		n := Min(pgenBlockSize, pr.numVariants-start)
+
+		if vrtypeBits == 4 {
+			buf := make([]byte, (n+1)/2)
+			if _, err := io.ReadFull(reader, buf); err != nil {
+				panic(err)
+			}
+			for i := 0; i < n; i++ {
+				pr.vrtypes[start+i] = (buf[i/2] >> (4 * uint(i%2))) & 15
+			}
+		} else if _, err := io.ReadFull(reader, pr.vrtypes[start:start+n]); err != nil {
+			panic(err)
+		}
+
+		buf := make([]byte, n*vrecLenSize)
+		if _, err := io.ReadFull(reader, buf); err != nil {
+			panic(err)
+		}
+		for i := 0; i < n; i++ {
+			pr.vrecLens[start+i] = readUintLE(buf[i*vrecLenSize:], vrecLenSize)
+		}
+
+		skip := n * alleleCtSize
+		if explicitNonref {
+			skip += (n + 7) / 8
+		}
+		if _, err := reader.Discard(skip); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func (pr *PgenReader) NumVariants() int {
+	return pr.numVariants
+}
+
+func (pr *PgenReader) NumSamples() int {
+	return pr.numSamples
+}
+
+// Reset rewinds to the first variant
+func (pr *PgenReader) Reset() {
+	if _, err := pr.file.Seek(pr.recordOffset, io.SeekStart); err != nil {
+		panic(err)
+	}
+	pr.reader = bufio.NewReader(pr.file)
+	pr.next = 0
+	pr.geno = make([]uint8, pr.numSamples)
+	pr.ldBase = nil
+}
+
+func (pr *PgenReader) Close() {
+	pr.file.Close()
+}
+
+// Next decodes the hardcalls of the next variant into geno (one entry per sample;
+// missing is -1). Returns false after the last variant
+func (pr *PgenReader) Next(geno []int8) bool {
+	if !pr.readVariant() {
+		return false
+	}
+	for i, g := range pr.geno {
+		if g == 3 {
+			geno[i] = -1
+		} else {
+			geno[i] = int8(g)
+		}
+	}
+	return true
+}
+
+// NextDosage decodes the ALT allele dosages of the next variant into dosage (missing
+// is NaN), falling back to hardcalls for samples without a dosage entry. Returns false
+// after the last variant
+func (pr *PgenReader) NextDosage(dosage []float64) bool {
+	if !pr.readVariant() {
+		return false
+	}
+	for i, g := range pr.geno {
+		if g == 3 {
+			dosage[i] = math.NaN()
+		} else {
+			dosage[i] = float64(g)
+		}
+	}
+
+	switch pr.mode {
+	case pgenModeFixedHardcall:
+	case pgenModeFixedDosage:
+		pr.parseDosageAll(pr.rec[(pr.numSamples+3)/4:], dosage)
+	case pgenModeVariable:
+		vrtype := pr.vrtypes[pr.next-1]
+		dosageType := (vrtype >> 5) & 3
+		if dosageType == 0 {
+			break
+		}
+		if vrtype&8 != 0 {
+			panic(fmt.Sprintf("%s: dosages of multiallelic variant %d are not supported", pr.filename, pr.next-1))
+		}
+
+		pos := pr.mainTrackLen()
+		if vrtype&16 != 0 {
+			pos += pr.phaseTrackLen(pr.rec[pos:])
+		}
+		pr.parseDosage(pr.rec[pos:], dosageType, dosage)
+	}
+	return true
+}
+
+// readVariant reads the next record and decodes its hardcall track into pr.geno
+func (pr *PgenReader) readVariant() bool {
+	if pr.next >= pr.numVariants {
+		return false
+	}
+
+	var recLen int
+	switch pr.mode {
+	case pgenModeFixedHardcall:
+		recLen = (pr.numSamples + 3) / 4
+	case pgenModeFixedDosage:
+		recLen = (pr.numSamples+3)/4 + 2*pr.numSamples
+	default:
+		recLen = int(pr.vrecLens[pr.next])
+	}
+	if cap(pr.rec) < recLen {
+		pr.rec = make([]byte, recLen)
+	}
+	pr.rec = pr.rec[:recLen]
+	if _, err := io.ReadFull(pr.reader, pr.rec); err != nil {
+		panic(fmt.Sprintf("%s: failed to read variant %d: %v", pr.filename, pr.next, err))
+	}
+
+	vrtype := byte(0)
+	if pr.mode == pgenModeVariable {
+		vrtype = pr.vrtypes[pr.next]
+	}
+	pr.next++
+
+	rec := pr.rec
+	switch vrtype & 7 {
+	case 0: // 2-bit genotypes
+		unpackGenotypes(rec, pr.geno)
+	case 1: // Two common genotypes in a 1-bit array, others in a difflist
+		base, delta := rec[0]/4, rec[0]&3
+		for i := range pr.geno {
+			pr.geno[i] = base + delta*((rec[1+i/8]>>uint(i%8))&1)
+		}
+		pr.applyDifflist(rec[1+(pr.numSamples+7)/8:])
+	case 2, 3: // Difflist against the last non-LD-compressed variant (3: then ALT/REF swapped)
+		if pr.ldBase == nil {
+			panic(fmt.Sprintf("%s: LD-compressed variant %d without a reference variant", pr.filename, pr.next-1))
+		}
+		copy(pr.geno, pr.ldBase)
+		pr.applyDifflist(rec)
+		if vrtype&7 == 3 {
+			for i, g := range pr.geno {
+				if g != 3 {
+					pr.geno[i] = 2 - g
+				}
+			}
+		}
+	default: // Difflist against a constant genotype
+		for i := range pr.geno {
+			pr.geno[i] = vrtype & 3
+		}
+		pr.applyDifflist(rec)
+	}
+
+	if t := vrtype & 7; t != 2 && t != 3 {
+		if pr.ldBase == nil {
+			pr.ldBase = make([]uint8, pr.numSamples)
+		}
+		copy(pr.ldBase, pr.geno)
+	}
+	return true
+}
+
+// mainTrackLen returns the byte length of the hardcall track of the current record
+func (pr *PgenReader) mainTrackLen() int {
+	vrtype := pr.vrtypes[pr.next-1]
+	switch vrtype & 7 {
+	case 0:
+		return (pr.numSamples + 3) / 4
+	case 1:
+		return 1 + (pr.numSamples+7)/8 + pr.difflistLen(pr.rec[1+(pr.numSamples+7)/8:], true)
+	default:
+		return pr.difflistLen(pr.rec, true)
+	}
+}
+
+// phaseTrackLen returns the byte length of a biallelic phase track: a flag bit followed by
+// one bit per heterozygous sample, and if the flag is set, one phase bit per phased sample
+func (pr *PgenReader) phaseTrackLen(track []byte) int {
+	hetCt := 0
+	for _, g := range pr.geno {
+		if g == 1 {
+			hetCt++
+		}
+	}
+	n := (hetCt + 8) / 8
+	if track[0]&1 == 0 {
+		return n
+	}
+	phasedCt := 0
+	for i := 1; i <= hetCt; i++ {
+		phasedCt += int((track[i/8] >> uint(i%8)) & 1)
+	}
+	return n + (phasedCt+7)/8
+}
+
+func (pr *PgenReader) parseDosage(track []byte, dosageType byte, dosage []float64) {
+	switch dosageType {
+	case 1: // Sample list followed by a dosage per listed sample
+		var samples []uint32
+		pos := pr.parseDifflist(track, false, func(s uint32, _ uint8) { samples = append(samples, s) })
+		for k, s := range samples {
+			dosage[s] = pgenDosage(binary.LittleEndian.Uint16(track[pos+2*k:]))
+		}
+	case 2: // Dosage for every sample
+		pr.parseDosageAll(track, dosage)
+	case 3: // Sample bitarray followed by a dosage per set bit
+		pos := (pr.numSamples + 7) / 8
+		for i := range dosage {
+			if (track[i/8]>>uint(i%8))&1 == 1 {
+				dosage[i] = pgenDosage(binary.LittleEndian.Uint16(track[pos:]))
+				pos += 2
+			}
+		}
+	}
+}
+
+func (pr *PgenReader) parseDosageAll(track []byte, dosage []float64) {
+	for i := range dosage {
+		dosage[i] = pgenDosage(binary.LittleEndian.Uint16(track[2*i:]))
+	}
+}
+
+func pgenDosage(v uint16) float64 {
+	if v == pgenDosageMissing {
+		return math.NaN()
+	}
+	return float64(v) / pgenDosageUnit
+}
+
+func (pr *PgenReader) applyDifflist(buf []byte) {
+	pr.parseDifflist(buf, true, func(s uint32, g uint8) { pr.geno[s] = g })
+}
+
+func (pr *PgenReader) difflistLen(buf []byte, withGenotypes bool) int {
+	return pr.parseDifflist(buf, withGenotypes, func(uint32, uint8) {})
+}
+
+// parseDifflist calls fn for each (sample, genotype) entry of a difflist and returns its
+// byte length. Layout: entry count (varint), first sample ID of each group of 64
+// (fixed width), one byte per group but the last (skip hints), 2-bit genotypes if
+// withGenotypes, then varint sample ID deltas within each group
+func (pr *PgenReader) parseDifflist(buf []byte, withGenotypes bool, fn func(sample uint32, geno uint8)) int {
+	length, pos := readVarint(buf, 0)
+	if length == 0 {
+		return pos
+	}
+
+	groupCt := (int(length) + pgenDifflistGroup - 1) / pgenDifflistGroup
+	groupStarts := buf[pos : pos+groupCt*pr.sampleIDSize]
+	pos += groupCt*(pr.sampleIDSize+1) - 1
+
+	var genotypes []byte
+	if withGenotypes {
+		genotypes = buf[pos : pos+(int(length)+3)/4]
+		pos += len(genotypes)
+	}
+
+	var sample uint32
+	for i := 0; i < int(length); i++ {
+		if i%pgenDifflistGroup == 0 {
+			sample = readUintLE(groupStarts[(i/pgenDifflistGroup)*pr.sampleIDSize:], pr.sampleIDSize)
+		} else {
+			var delta uint32
+			delta, pos = readVarint(buf, pos)
+			sample += delta
+		}
+		if int(sample) >= pr.numSamples {
+			panic(fmt.Sprintf("%s: corrupt difflist in variant %d", pr.filename, pr.next-1))
+		}
+
+		var g uint8
+		if withGenotypes {
+			g = (genotypes[i/4] >> uint(2*(i%4))) & 3
+		}
+		fn(sample, g)
+	}
+	return pos
+}
+
+func unpackGenotypes(buf []byte, geno []uint8) {
+	for i := range geno {
+		geno[i] = (buf[i/4] >> uint(2*(i%4))) & 3
+	}
+}
+
+// readVarint reads a little-endian base-128 integer starting at buf[pos]
+func readVarint(buf []byte, pos int) (uint32, int) {
+	var v uint32
+	for shift := uint(0); ; shift += 7 {
+		b := buf[pos]
+		pos++
+		v |= uint32(b&127) << shift
+		if b < 128 {
+			return v, pos
+		}
+	}
+}
+
+func readUintLE(buf []byte, size int) uint32 {
+	var v uint32
+	for i := 0; i < size; i++ {
+		v |= uint32(buf[i]) << (8 * uint(i))
+	}
+	return v
+}
+
+// bytesToRepresent returns the number of bytes needed to store values up to n
+func bytesToRepresent(n uint32) int {
+	size := 1
+	for n >>= 8; n > 0; n >>= 8 {
+		size++
+	}
+	return size
+}
+
+// LoadPsamSampleIDs returns the "FID\tIID" (or "IID" if the file has no FID column)
+// of every sample in a .psam (or .fam) file
+func LoadPsamSampleIDs(filename string) []string {
+	file, err := os.Open(filename)
+	if err != nil {
+		panic(err)
+	}
+	defer file.Close()
+
+	fidCol, iidCol := 0, 1 // .fam layout if there is no header
+	var ids []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			fidCol, iidCol = -1, -1
+			for c, name := range strings.Fields(line) {
+				switch strings.TrimPrefix(name, "#") {
+				case "FID":
+					fidCol = c
+				case "IID":
+					iidCol = c
+				}
+			}
+			if iidCol < 0 {
+				panic(fmt.Sprintf("%s: no IID column in header", filename))
+			}
+			continue
+		}
+
+		tok := strings.Fields(line)
+		if len(tok) == 0 {
+			continue
+		}
+		if fidCol >= 0 {
+			ids = append(ids, tok[fidCol]+"\t"+tok[iidCol])
+		} else {
+			ids = append(ids, tok[iidCol])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		panic(err)
+	}
+	return ids
+}
+
+// LoadPvarVariantIDs returns the ID column of a .pvar (or .bim) file
+func LoadPvarVariantIDs(filename string) []string {
+	file, err := os.Open(filename)
+	if err != nil {
+		panic(err)
+	}
+	defer file.Close()
+
+	idCol := 1 // .bim layout if there is no header
+	var ids []string
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 1<<16), 1<<26)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "##") {
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			idCol = -1
+			for c, name := range strings.Fields(line) {
+				if name == "ID" {
+					idCol = c
+				}
+			}
+			if idCol < 0 {
+				panic(fmt.Sprintf("%s: no ID column in header", filename))
+			}
+			continue
+		}
+
+		tok := strings.Fields(line)
+		if len(tok) > idCol {
+			ids = append(ids, tok[idCol])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		panic(err)
+	}
+	return ids
+}
+
+// PgenSampleFilter returns which samples of a .psam file are listed in a keep file in
+// the format of plink2 --keep (FID IID or IID per line). An empty keepFile keeps all samples
+func PgenSampleFilter(psamFile, keepFile string) []bool {
+	return KeepFileSampleFilter(LoadPsamSampleIDs(psamFile), keepFile)
+}
+
+// KeepFileSampleFilter returns which of the samples ("FID\tIID" or "IID") are listed in a
+// plink2 --keep file. An empty keepFile keeps all samples
+func KeepFileSampleFilter(samples []string, keepFile string) []bool {
+	if keepFile == "" {
+		return OnesBool(len(samples))
+	}
+
+	file, err := os.Open(keepFile)
+	if err != nil {
+		panic(err)
+	}
+	defer file.Close()
+
+	keepFull := make(map[string]bool) // FID\tIID
+	keepIID := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		tok := strings.Fields(scanner.Text())
+		if len(tok) == 0 || strings.HasPrefix(tok[0], "#") {
+			continue
+		}
+		if len(tok) == 1 {
+			keepIID[tok[0]] = true
+		} else {
+			keepFull[tok[0]+"\t"+tok[1]] = true
+			keepIID[tok[1]] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		panic(err)
+	}
+
+	filt := make([]bool, len(samples))
+	for i, id := range samples {
+		if strings.Contains(id, "\t") && len(keepFull) > 0 {
+			filt[i] = keepFull[id]
+		} else {
+			filt[i] = keepIID[id[strings.LastIndex(id, "\t")+1:]]
+		}
+	}
+	return filt
+}
+
+// PgenVariantIndices returns the (sorted) indices in a .pvar file of the given variant IDs
+func PgenVariantIndices(pvarFile string, ids []string) []int {
+	index := make(map[string]int)
+	for i, id := range LoadPvarVariantIDs(pvarFile) {
+		index[id] = i
+	}
+
+	out := make([]int, len(ids))
+	for i, id := range ids {
+		v, ok := index[id]
+		if !ok {
+			panic(fmt.Sprintf("%s: variant %s not found", pvarFile, id))
+		}
+		out[i] = v
+	}
+	sort.Ints(out)
+	return out
+}
+
+// PgenToGenoFile decodes the given variants (sorted indices) of the kept samples of a .pgen
+// file into a sample-major int8 matrix file read by NewGenoFileStream. If useDosage is
+// set, dosages are rounded to the nearest integer instead of using the hardcalls
+func PgenToGenoFile(pgenFile string, sampleFilt []bool, variants []int, useDosage bool, outputFile string) {
+	pr := NewPgenReader(pgenFile)
+	defer pr.Close()
+
+	if len(sampleFilt) != pr.NumSamples() {
+		panic(fmt.Sprintf("%s has %d samples but the sample filter has %d", pgenFile, pr.NumSamples(), len(sampleFilt)))
+	}
+
+	dosage := make([]float64, pr.NumSamples())
+	WriteGenoFileByVariant(outputFile, sampleFilt, len(variants), func(c int, geno []int8) {
+		v := variants[c]
+		if c > 0 && v <= variants[c-1] {
+			panic("PgenToGenoFile: variant indices must be sorted and unique")
+		}
+		for pr.next <= v {
+			var ok bool
+			if useDosage && pr.next == v {
+				ok = pr.NextDosage(dosage)
+			} else {
+				ok = pr.Next(geno)
+			}
+			if !ok {
+				panic(fmt.Sprintf("%s: variant index %d out of range", pgenFile, v))
+			}
+		}
+
+		if useDosage {
+			for i := range geno {
+				if math.IsNaN(dosage[i]) {
+					geno[i] = -1
+				} else {
+					geno[i] = int8(math.Round(dosage[i]))
+				}
+			}
+		}
+	})
+}
+
+// PgenToDosageFile decodes the dosages of the given variants (sorted indices) of the kept
+// samples of a .pgen file into a sample-major dosage matrix file read by NewDosageFileStream
+func PgenToDosageFile(pgenFile string, sampleFilt []bool, variants []int, outputFile string) {
+	pr := NewPgenReader(pgenFile)
+	defer pr.Close()
+
+	if len(sampleFilt) != pr.NumSamples() {
+		panic(fmt.Sprintf("%s has %d samples but the sample filter has %d", pgenFile, pr.NumSamples(), len(sampleFilt)))
+	}
+
+	geno := make([]int8, pr.NumSamples())
+	WriteDosageFileByVariant(outputFile, sampleFilt, len(variants), func(c int, dosage []float64) {
+		v := variants[c]
+		if c > 0 && v <= variants[c-1] {
+			panic("PgenToDosageFile: variant indices must be sorted and unique")
+		}
+		for pr.next < v { // Skip without decoding dosages
+			if !pr.Next(geno) {
+				panic(fmt.Sprintf("%s: variant index %d out of range", pgenFile, v))
+			}
+		}
+		if !pr.NextDosage(dosage) {
+			panic(fmt.Sprintf("%s: variant index %d out of range", pgenFile, v))
+		}
+	})
+}
+
+// PgenFilesetToGenoFile extracts the variants with the given IDs and the samples in keepFile
+// (plink2 --keep format, empty for all) from <pgenPrefix>.pgen/.psam/.pvar into a
+// sample-major int8 matrix file (dosage matrix file if useDosage is set), checking the
+// expected dimensions
+func PgenFilesetToGenoFile(pgenPrefix, keepFile string, variantIDs []string, nrows, ncols int, useDosage bool, outputFile string) {
+	sampleFilt := PgenSampleFilter(pgenPrefix+".psam", keepFile)
+	variants := PgenVariantIndices(pgenPrefix+".pvar", variantIDs)
+
+	if SumBool(sampleFilt) != nrows || len(variants) != ncols {
+		panic(fmt.Sprintf("%s: selected %d samples and %d variants, expected %d and %d",
+			pgenPrefix, SumBool(sampleFilt), len(variants), nrows, ncols))
+	}
+
+	if useDosage {
+		PgenToDosageFile(pgenPrefix+".pgen", sampleFilt, variants, outputFile)
+	} else {
+		PgenToGenoFile(pgenPrefix+".pgen", sampleFilt, variants, false, outputFile)
+	}
+}
@@ -0,0 +1,157 @@
+package gwas
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"testing"
+)
+
+// Variable-width .pgen files written by PLINK 2, and the genotype counts plink2 --geno-counts
+// reported for them (scripts/computeGenoCounts.py of earlier releases: one row of uint32
+// per count type, over all variants of chromosomes 1 to 22)
+const (
+	examplePgenTemplate = "../example_data/party1/geno/chr%d"
+	exampleKeepFile     = "../example_data/party1/sample_keep.txt"
+	exampleGenoCounts   = "../example_data/party1/all.gcount.transpose.bin"
+	exampleNumVariants  = 100000
+)
+
+// loadPlinkGenoCounts returns row t (gcountHomRef, ...) of the example geno counts
+func loadPlinkGenoCounts(t *testing.T) [numGenoCountTypes][]uint32 {
+	buf, err := os.ReadFile(exampleGenoCounts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buf) != 4*numGenoCountTypes*exampleNumVariants {
+		t.Fatalf("%s has %d bytes, expected %d", exampleGenoCounts, len(buf), 4*numGenoCountTypes*exampleNumVariants)
+	}
+	var counts [numGenoCountTypes][]uint32
+	for c := range counts {
+		counts[c] = make([]uint32, exampleNumVariants)
+		for j := range counts[c] {
+			counts[c][j] = binary.LittleEndian.Uint32(buf[4*(c*exampleNumVariants+j):])
+		}
+	}
+	return counts
+}
+
+func TestPgenReaderMatchesPlink(t *testing.T) {
+	counts := loadPlinkGenoCounts(t)
+
+	// Variants of chromosome c start at offset[c-1]
+	offset := []int{0}
+	for chr := 1; chr <= 22; chr++ {
+		pr := NewPgenReader(fmt.Sprintf(examplePgenTemplate, chr) + ".pgen")
+		offset = append(offset, offset[chr-1]+pr.NumVariants())
+		pr.Close()
+	}
+	if offset[22] != exampleNumVariants {
+		t.Fatalf("the example .pgen files have %d variants, the PLINK counts %d", offset[22], exampleNumVariants)
+	}
+
+	for _, chr := range []int{1, 11, 22} {
+		prefix := fmt.Sprintf(examplePgenTemplate, chr)
+		sampleFilt := PgenSampleFilter(prefix+".psam", exampleKeepFile)
+		pr := NewPgenReader(prefix + ".pgen")
+
+		geno := make([]int8, pr.NumSamples())
+		dosage := make([]float64, pr.NumSamples())
+		for v := 0; pr.Next(geno); v++ {
+			var c [numGenoCountTypes]uint32
+			for i, x := range geno {
+				if !sampleFilt[i] {
+					continue
+				}
+				switch x {
+				case 0:
+					c[gcountHomRef]++
+				case 1:
+					c[gcountHet]++
+				case 2:
+					c[gcountHomAlt]++
+				default:
+					c[gcountMissing]++
+				}
+			}
+			for k := range c {
+				if want := counts[k][offset[chr-1]+v]; c[k] != want {
+					t.Fatalf("chr%d variant %d: count %d is %d, PLINK reports %d", chr, v, k, c[k], want)
+				}
+			}
+		}
+		if pr.Next(geno) {
+			t.Errorf("chr%d: Next returned a variant after the last one", chr)
+		}
+
+		// Without dosage tracks, dosages are the hardcalls
+		pr.Reset()
+		pr.Next(geno)
+		pr.Reset()
+		pr.NextDosage(dosage)
+		for i := range geno {
+			if dosage[i] != float64(geno[i]) && !(geno[i] == -1 && math.IsNaN(dosage[i])) {
+				t.Fatalf("chr%d: dosage of sample %d is %g, hardcall %d", chr, i, dosage[i], geno[i])
+			}
+		}
+		pr.Close()
+	}
+}
+
+// Fixed-width fixtures, encoded by hand following the .pgen specification (2-bit codes
+// 0/1/2 ALT alleles and 3 missing, 4 samples per byte from the low bits; dosages are
+// uint16 in units of 1/16384, 65535 missing)
+func TestPgenReaderFixedWidth(t *testing.T) {
+	nan := math.NaN()
+	tests := []struct {
+		file   string
+		geno   [][]int8
+		dosage [][]float64
+	}{
+		{
+			"testdata/hardcall.pgen",
+			[][]int8{{0, 1, 2, -1, 2}, {-1, 0, 0, 1, 1}},
+			[][]float64{{0, 1, 2, nan, 2}, {nan, 0, 0, 1, 1}},
+		},
+		{
+			"testdata/dosage.pgen",
+			[][]int8{{0, 1, 2, -1, 1, 0}, {2, 2, 1, 0, -1, 0}},
+			[][]float64{{0, 1.25, 2, nan, 0.5, 0.75}, {2, 1.75, 1, 0, nan, 0.25}},
+		},
+	}
+
+	for _, tt := range tests {
+		pr := NewPgenReader(tt.file)
+		if pr.NumVariants() != len(tt.geno) || pr.NumSamples() != len(tt.geno[0]) {
+			t.Fatalf("%s: %d variants x %d samples, want %d x %d", tt.file, pr.NumVariants(), pr.NumSamples(), len(tt.geno), len(tt.geno[0]))
+		}
+
+		geno := make([]int8, pr.NumSamples())
+		for v := range tt.geno {
+			if !pr.Next(geno) {
+				t.Fatalf("%s: no variant %d", tt.file, v)
+			}
+			for i := range geno {
+				if geno[i] != tt.geno[v][i] {
+					t.Errorf("%s: variant %d sample %d: hardcall %d, want %d", tt.file, v, i, geno[i], tt.geno[v][i])
+				}
+			}
+		}
+
+		pr.Reset()
+		dosage := make([]float64, pr.NumSamples())
+		for v := range tt.dosage {
+			pr.NextDosage(dosage)
+			for i := range dosage {
+				if want := tt.dosage[v][i]; dosage[i] != want && !(math.IsNaN(want) && math.IsNaN(dosage[i])) {
+					t.Errorf("%s: variant %d sample %d: dosage %g, want %g", tt.file, v, i, dosage[i], want)
+				}
+			}
+		}
+		if pr.NextDosage(dosage) {
+			t.Errorf("%s: NextDosage returned a variant after the last one", tt.file)
+		}
+		pr.Close()
+	}
+}
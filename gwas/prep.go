@@ -0,0 +1,232 @@
+package gwas
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.dedis.ch/onet/v3/log"
+)
+
+// Data preparation run by each party before the protocol (see cmd/prep)
+
+// snpInfoWriter writes the snp_ids_file, snp_position_file ("chrom\tpos") and
+// geno_block_size_file (one line per block) read by InitializeGWASProtocol
+type snpInfoWriter struct {
+	files          []*os.File
+	ids, pos, size *bufio.Writer
+	blockSize      int
+}
+
+func newSnpInfoWriter(snpIdsFile, snpPosFile, blockSizeFile string) *snpInfoWriter {
+	w := &snpInfoWriter{}
+	for _, filename := range []string{snpIdsFile, snpPosFile, blockSizeFile} {
+		file, err := os.Create(filename)
+		if err != nil {
+			panic(err)
+		}
+		w.files = append(w.files, file)
+	}
+	w.ids, w.pos, w.size = bufio.NewWriter(w.files[0]), bufio.NewWriter(w.files[1]), bufio.NewWriter(w.files[2])
+	return w
+}
+
+func (w *snpInfoWriter) Add(chrom string, pos uint64, id string) {
+	fmt.Fprintln(w.ids, id)
+	fmt.Fprintf(w.pos, "%d\t%d\n", ChromNumber(chrom), pos)
+	w.blockSize++
+}
+
+func (w *snpInfoWriter) EndBlock() {
+	fmt.Fprintln(w.size, w.blockSize)
+	w.blockSize = 0
+}
+
+func (w *snpInfoWriter) Close() {
+	for _, bw := range []*bufio.Writer{w.ids, w.pos, w.size} {
+		if err := bw.Flush(); err != nil {
+			panic(err)
+		}
+	}
+	for _, file := range w.files {
+		file.Close()
+	}
+}
+
+// LoadPvarVariants returns the chromosome, position, ID and alleles of every variant in a
+// .pvar (or .bim, where ALT is the counted A1 allele) file
+func LoadPvarVariants(filename string) []VcfVariant {
+	file, err := os.Open(filename)
+	if err != nil {
+		panic(err)
+	}
+	defer file.Close()
+
+	chromCol, posCol, idCol, refCol, altCol := 0, 3, 1, 5, 4 // .bim layout if there is no header
+	var out []VcfVariant
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 1<<16), 1<<26)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "##") {
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			chromCol, posCol, idCol, refCol, altCol = -1, -1, -1, -1, -1
+			for c, name := range strings.Fields(line) {
+				switch strings.TrimPrefix(name, "#") {
+				case "CHROM":
+					chromCol = c
+				case "POS":
+					posCol = c
+				case "ID":
+					idCol = c
+				case "REF":
+					refCol = c
+				case "ALT":
+					altCol = c
+				}
+			}
+			if chromCol < 0 || posCol < 0 || idCol < 0 {
+				panic(fmt.Sprintf("%s: header needs CHROM, POS and ID columns", filename))
+			}
+			continue
+		}
+
+		tok := strings.Fields(line)
+		if len(tok) == 0 {
+			continue
+		}
+		if len(tok) <= Max(chromCol, Max(posCol, idCol)) {
+			panic(fmt.Sprintf("%s:%d: too few columns", filename, lineno))
+		}
+		pos, err := strconv.ParseUint(tok[posCol], 10, 64)
+		if err != nil {
+			panic(fmt.Sprintf("%s:%d: %v", filename, lineno, err))
+		}
+		v := VcfVariant{Chrom: tok[chromCol], Pos: pos, ID: tok[idCol]}
+		if refCol >= 0 && altCol >= 0 && len(tok) > Max(refCol, altCol) {
+			v.Ref, v.Alt = tok[refCol], tok[altCol]
+		}
+		out = append(out, v)
+	}
+	if err := scanner.Err(); err != nil {
+		panic(err)
+	}
+	return out
+}
+
+// WriteSnpInfoFiles writes snp_pos.txt, snp_ids.txt and chrom_sizes.txt to outDir for the
+// .pvar files of chromosomes 1 to numChroms (pgenTemplate has a '%d' placeholder for chrom)
+func WriteSnpInfoFiles(pgenTemplate string, numChroms int, outDir string) {
+	w := newSnpInfoWriter(filepath.Join(outDir, "snp_ids.txt"), filepath.Join(outDir, "snp_pos.txt"), filepath.Join(outDir, "chrom_sizes.txt"))
+	defer w.Close()
+
+	for chr := 1; chr <= numChroms; chr++ {
+		for _, v := range LoadPvarVariants(fmt.Sprintf(pgenTemplate, chr) + ".pvar") {
+			w.Add(v.Chrom, v.Pos, v.ID)
+		}
+		w.EndBlock()
+
+		log.LLvl1(time.Now().Format(time.RFC3339), "Processed chromosome", chr)
+	}
+}
+
+// Genotype count columns of plink2 --geno-counts, in the order of geno_count_file
+const (
+	gcountHomRef = iota
+	gcountHet
+	gcountHomAlt
+	gcountHapRef
+	gcountHapAlt
+	gcountMissing
+	numGenoCountTypes
+)
+
+// ComputeGenoCounts counts the genotypes of the samples in keepFile (plink2 --keep format,
+// empty for all) for every variant of chromosomes 1 to numChroms (pgenTemplate has a '%d'
+// placeholder for chrom), as plink2 --geno-counts does for autosomes. Writes
+// <outDir>/all.gcount (one tab-separated line per variant) and the geno_count_file
+// <outDir>/all.gcount.transpose.bin read by ReadGenoStatsFromFile (uint32, one row per count type)
+func ComputeGenoCounts(pgenTemplate, keepFile string, numChroms int, outDir string) {
+	var counts [numGenoCountTypes][]uint32
+
+	for chr := 1; chr <= numChroms; chr++ {
+		pgenPrefix := fmt.Sprintf(pgenTemplate, chr)
+		sampleFilt := PgenSampleFilter(pgenPrefix+".psam", keepFile)
+
+		pr := NewPgenReader(pgenPrefix + ".pgen")
+		if len(sampleFilt) != pr.NumSamples() {
+			panic(fmt.Sprintf("%s.psam has %d samples but %s.pgen has %d", pgenPrefix, len(sampleFilt), pgenPrefix, pr.NumSamples()))
+		}
+
+		geno := make([]int8, pr.NumSamples())
+		for pr.Next(geno) {
+			var c [numGenoCountTypes]uint32
+			for i, x := range geno {
+				if !sampleFilt[i] {
+					continue
+				}
+				switch x {
+				case 0:
+					c[gcountHomRef]++
+				case 1:
+					c[gcountHet]++
+				case 2:
+					c[gcountHomAlt]++
+				default:
+					c[gcountMissing]++
+				}
+			}
+			for t := range counts {
+				counts[t] = append(counts[t], c[t])
+			}
+		}
+		pr.Close()
+
+		log.LLvl1(time.Now().Format(time.RFC3339), "Geno counts computed for chromosome", chr)
+	}
+
+	textFile := filepath.Join(outDir, "all.gcount")
+	text, err := os.Create(textFile)
+	if err != nil {
+		panic(err)
+	}
+	defer text.Close()
+	writer := bufio.NewWriter(text)
+	for j := range counts[0] {
+		for t := range counts {
+			if t > 0 {
+				writer.WriteByte('\t')
+			}
+			writer.WriteString(strconv.FormatUint(uint64(counts[t][j]), 10))
+		}
+		writer.WriteByte('\n')
+	}
+	if err := writer.Flush(); err != nil {
+		panic(err)
+	}
+
+	binFile := textFile + ".transpose.bin"
+	bin, err := os.Create(binFile)
+	if err != nil {
+		panic(err)
+	}
+	defer bin.Close()
+	writer = bufio.NewWriter(bin)
+	for t := range counts {
+		if err := binary.Write(writer, binary.LittleEndian, counts[t]); err != nil {
+			panic(err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		panic(err)
+	}
+
+	log.LLvl1(time.Now().Format(time.RFC3339), "Saved geno counts of", len(counts[0]), "variants to:", binFile)
+}
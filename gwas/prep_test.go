@@ -0,0 +1,31 @@
+package gwas
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// The example geno_count_file, snp_position_file, snp_ids_file and geno_block_size_file were
+// produced by the scripts of earlier releases (plink2 --geno-counts, createSnpInfoFiles.py);
+// the Go preparation must reproduce them byte for byte
+func TestPrepMatchesScripts(t *testing.T) {
+	outDir := t.TempDir()
+	ComputeGenoCounts(examplePgenTemplate, exampleKeepFile, 22, outDir)
+	WriteSnpInfoFiles(examplePgenTemplate, 22, outDir)
+
+	for _, name := range []string{"all.gcount.transpose.bin", "chrom_sizes.txt", "snp_pos.txt", "snp_ids.txt"} {
+		want, err := os.ReadFile(filepath.Join("../example_data/party1", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := os.ReadFile(filepath.Join(outDir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s differs from the reference (%d bytes, want %d)", name, len(got), len(want))
+		}
+	}
+}
@@ -415,6 +415,7 @@ func (qc *QC) SNPMAFAndHWEFilters() []bool {
 
 	xSum := make([]int, numSnp)
 	xCount := make([]int, numSnp)
+	xDosage := make([]float64, numSnp) // Rounded into xSum; equal to it for hard calls
 
 	// Over control cohort only
 	xSumCtrl := make([]int, numSnp)
@@ -440,14 +441,14 @@ func (qc *QC) SNPMAFAndHWEFilters() []bool {
 
 		for i, genoFs := range qc.general.genoBlocks {
 
-			for indiv, rowIndex := genoFs.NextRow(), 0; indiv != nil; indiv, rowIndex = genoFs.NextRow(), rowIndex+1 {
+			for indiv, rowIndex := genoFs.NextRowDosage(), 0; indiv != nil; indiv, rowIndex = genoFs.NextRowDosage(), rowIndex+1 {
 
-				yi := int(phenoF.At(rowIndex, 0))
+				yi := int(phenoF.At(rowIndex, 0)) // Controls for HWE are taken from the first phenotype
 
 				for j, x := range indiv {
-					snp := int(x)
-					if snp >= 0 { // Not missing
-						xSum[shifts[i]+j] += snp
+					if x != DosageMissing { // Not missing
+						snp := int(DosageHardCall(x)) // HWE uses hard calls, MAF uses dosages
+						xDosage[shifts[i]+j] += float64(x) / DosageScale
 						xCount[shifts[i]+j] += 2
 
 						if yi < 1 { // Control cohort
@@ -462,6 +463,10 @@ func (qc *QC) SNPMAFAndHWEFilters() []bool {
 			genoFs.Reset()
 		}
 
+		for j := range xSum {
+			xSum[j] = int(math.Round(xDosage[j]))
+		}
+
 		log.LLvl1(time.Now().Format(time.RFC3339), "done.", time.Since(start))
 	}
 
@@ -784,7 +789,7 @@ func (qc *QC) QualityControlProtocol(useCache bool) {
 
 	// Filter pheno and cov data
 	if pid > 0 {
-		qc.general.pheno = FilterVec(qc.general.pheno, indFilt)
+		qc.general.pheno = FilterMat(qc.general.pheno, OnesBool(qc.general.NumPhenos()), indFilt)
 		qc.general.cov = FilterMat(qc.general.cov, OnesBool(qc.general.gwasParams.NumCov()), indFilt)
 	}
 
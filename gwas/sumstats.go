@@ -0,0 +1,298 @@
+package gwas
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.dedis.ch/onet/v3/log"
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+// SumStatsInfo describes how the association statistics were computed
+type SumStatsInfo struct {
+	NumSamples    int  // OBS_CT: filtered samples over all parties
+	NumCovariates int  // Columns regressed out, including the intercept and PCs
+	Logistic      bool // Score test for a binary phenotype (Z_STAT) instead of linear regression (T_STAT)
+	BetaSE        bool // Output BETA and SE columns
+}
+
+// SumStat is one row of the summary statistics output
+type SumStat struct {
+	Chrom uint64
+	Pos   uint64
+	ID    string
+	Stat  float64 // Output of GetAssociationStats: correlation (linear) or z / sqrt(n) (logistic)
+	Beta  float64
+	SE    float64
+}
+
+// StdErr returns the standard error of the effect size from the correlation and
+// sqrt(vary / varx): SE = sqrt(vary * (1 - r^2) / (df * varx)). It is 0 for |r| >= 1
+// (a perfect fit, or rounding noise beyond it) and NaN without degrees of freedom
+func (info SumStatsInfo) StdErr(stat, seScale float64) float64 {
+	df := info.DegreesOfFreedom()
+	if df <= 0 {
+		return math.NaN()
+	}
+	if math.Abs(stat) >= 1 {
+		return 0
+	}
+	return seScale * math.Sqrt((1-stat*stat)/float64(df))
+}
+
+// TestStatistic converts the correlation-scale statistic to a t statistic with
+// n - k - 1 degrees of freedom (linear) or a z statistic (logistic). The t statistic
+// is infinite for |r| >= 1 and NaN without degrees of freedom
+func (info SumStatsInfo) TestStatistic(stat float64) float64 {
+	if info.Logistic {
+		return stat * math.Sqrt(float64(info.NumSamples))
+	}
+	df := info.DegreesOfFreedom()
+	if df <= 0 {
+		return math.NaN()
+	}
+	if math.Abs(stat) >= 1 {
+		return math.Copysign(math.Inf(1), stat)
+	}
+	return stat * math.Sqrt(float64(df)/(1-stat*stat))
+}
+
+// DegreesOfFreedom of the linear test: samples minus covariates minus the tested SNP
+func (info SumStatsInfo) DegreesOfFreedom() int {
+	return info.NumSamples - info.NumCovariates - 1
+}
+
+// PValue returns the two-sided p-value of a statistic from TestStatistic
+func (info SumStatsInfo) PValue(t float64) float64 {
+	if math.IsNaN(t) {
+		return math.NaN()
+	}
+	if info.Logistic {
+		return math.Erfc(math.Abs(t) / math.Sqrt2)
+	}
+	if math.IsInf(t, 0) {
+		return 0
+	}
+	dist := distuv.StudentsT{Mu: 0, Sigma: 1, Nu: float64(info.DegreesOfFreedom())}
+	return 2 * dist.Survival(math.Abs(t))
+}
+
+func (info SumStatsInfo) statColumn() string {
+	if info.Logistic {
+		return "Z_STAT"
+	}
+	return "T_STAT"
+}
+
+// WriteSumStats writes tab-separated summary statistics with PLINK2 --glm column names
+// (#CHROM POS ID TEST OBS_CT [BETA SE] T_STAT/Z_STAT P), gzip-compressed if useGzip is set
+func WriteSumStats(filename string, useGzip bool, rows []SumStat, info SumStatsInfo) {
+	file, err := os.Create(filename)
+	if err != nil {
+		panic(fmt.Sprintf("failed to create summary statistics file %s: %v", filename, err))
+	}
+	defer file.Close()
+
+	var out io.Writer = file
+	var gz *gzip.Writer
+	if useGzip {
+		gz = gzip.NewWriter(file)
+		out = gz
+	}
+	writer := bufio.NewWriter(out)
+
+	header := []string{"#CHROM", "POS", "ID", "TEST", "OBS_CT"}
+	if info.BetaSE {
+		header = append(header, "BETA", "SE")
+	}
+	header = append(header, info.statColumn(), "P")
+	writer.WriteString(strings.Join(header, "\t") + "\n")
+
+	obsCt := strconv.Itoa(info.NumSamples)
+	for _, r := range rows {
+		t := info.TestStatistic(r.Stat)
+		fields := []string{
+			strconv.FormatUint(r.Chrom, 10),
+			strconv.FormatUint(r.Pos, 10),
+			r.ID,
+			"ADD",
+			obsCt,
+		}
+		if info.BetaSE {
+			fields = append(fields, formatSumStat(r.Beta), formatSumStat(r.SE))
+		}
+		fields = append(fields, formatSumStat(t), formatSumStat(info.PValue(t)))
+		writer.WriteString(strings.Join(fields, "\t") + "\n")
+	}
+
+	if err := writer.Flush(); err != nil {
+		panic(err)
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func formatSumStat(v float64) string {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return "NA"
+	}
+	return strconv.FormatFloat(v, 'g', 6, 64)
+}
+
+// LoadSNPIDFile reads one SNP ID per line
+func LoadSNPIDFile(filename string) []string {
+	file, err := os.Open(filename)
+	if err != nil {
+		panic(err)
+	}
+	defer file.Close()
+
+	var ids []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		ids = append(ids, strings.TrimSpace(scanner.Text()))
+	}
+	if err := scanner.Err(); err != nil {
+		panic(err)
+	}
+	return ids
+}
+
+// assocSnpIndices returns the index (into snp_ids_file / snp_position_file) of every
+// statistic kept by outFilter, the output filter of ComputeAssocStatistics. outFilter holds
+// the ciphertext slots of the tested blocks in order, assocNumCtx(n) ciphertexts for a block
+// with n QC-passing SNPs, and the slots it keeps in a block are those SNPs in order
+func (g *ProtocolInfo) assocSnpIndices(outFilter []bool) []int {
+	snpFilt := g.gwasParams.SnpFilt()
+	slots := g.cps.GetSlots()
+
+	var idx []int
+	shift, offset := 0, 0
+	for b, size := range g.genoBlockSizes {
+		if g.IsBlockForAssocTest(b) {
+			var kept []int
+			for j := shift; j < shift+size; j++ {
+				if snpFilt == nil || snpFilt[j] {
+					kept = append(kept, j)
+				}
+			}
+
+			end := offset + g.assocNumCtx(len(kept))*slots
+			if end > len(outFilter) {
+				panic(fmt.Sprintf("association output filter has %d slots, but block %d ends at slot %d", len(outFilter), b+1, end))
+			}
+			k := 0
+			for _, keep := range outFilter[offset:end] {
+				if !keep {
+					continue
+				}
+				if k == len(kept) {
+					panic(fmt.Sprintf("association output filter keeps more than the %d QC-passing SNPs of block %d", len(kept), b+1))
+				}
+				idx = append(idx, kept[k])
+				k++
+			}
+			offset = end
+		}
+		shift += size
+	}
+	for k, keep := range outFilter[offset:] {
+		if keep {
+			panic(fmt.Sprintf("association output filter keeps slot %d, after the last tested block ends at slot %d", offset+k, offset))
+		}
+	}
+	return idx
+}
+
+// SumStatsPath returns the summary statistics file of the given phenotype (column of pheno_file)
+func (g *ProtocolInfo) SumStatsPath(pheno int) string {
+	name := "assoc" + g.phenoSuffix(pheno) + ".glm.linear"
+	if g.IsLogisticAssoc() {
+		name = "assoc" + g.phenoSuffix(pheno) + ".glm.logistic"
+	}
+	if g.config.SumStatsGzip {
+		name += ".gz"
+	}
+	return g.OutPath(name)
+}
+
+// sumStatsInfo returns the sample and covariate counts behind the association statistics
+func (g *ProtocolInfo) sumStatsInfo(numPCs int) SumStatsInfo {
+	nrowsTotal := 0
+	for _, n := range g.gwasParams.FiltNumInds()[1:] {
+		nrowsTotal += n
+	}
+
+	ncov := g.gwasParams.NumCov() + numPCs
+	if !g.config.CovAllOnes {
+		ncov++ // all-ones covariate added in GetAssociationStats
+	}
+
+	return SumStatsInfo{
+		NumSamples:    nrowsTotal,
+		NumCovariates: ncov,
+		Logistic:      g.IsLogisticAssoc(),
+	}
+}
+
+// WriteAssocSumStats joins the decrypted statistics of a phenotype (the entries kept by outFilter,
+// see decryptAssocOutput) with SNP IDs and positions and writes them to SumStatsPath. beta and
+// seScale are nil unless assoc_beta_se is set
+func (g *ProtocolInfo) WriteAssocSumStats(pheno int, stats, beta, seScale []float64, outFilter []bool, numPCs int) {
+	snpIdx := g.assocSnpIndices(outFilter)
+	if len(snpIdx) != len(stats) {
+		panic(fmt.Sprintf("association output filter keeps %d SNPs, but there are %d statistics", len(snpIdx), len(stats)))
+	}
+	if beta != nil && (len(beta) != len(stats) || len(seScale) != len(stats)) {
+		panic(fmt.Sprintf("%d betas and %d standard error scales for %d statistics", len(beta), len(seScale), len(stats)))
+	}
+
+	var ids []string
+	if g.config.SnpIdsFile != "" {
+		ids = LoadSNPIDFile(g.config.SnpIdsFile)
+		if len(ids) != len(g.pos) {
+			panic(fmt.Sprintf("%s has %d SNP IDs but %s has %d positions", g.config.SnpIdsFile, len(ids), g.config.SnpPosFile, len(g.pos)))
+		}
+	} else if g.config.GenoFileFormat == "bed" {
+		for _, v := range LoadBimFile(g.config.GenoFilePrefix + ".bim") {
+			ids = append(ids, v.ID)
+		}
+	}
+
+	info := g.sumStatsInfo(numPCs)
+	info.BetaSE = beta != nil
+	if !info.Logistic && info.DegreesOfFreedom() <= 0 {
+		log.LLvl1(time.Now().Format(time.RFC3339), fmt.Sprintf("Warning: %d samples leave no degrees of freedom for %d covariates and the SNP; %s reports NA statistics",
+			info.NumSamples, info.NumCovariates, g.SumStatsPath(pheno)))
+	}
+
+	rows := make([]SumStat, len(stats))
+	for i, j := range snpIdx {
+		rows[i] = SumStat{
+			Chrom: g.pos[j] / 1e9, // LoadSNPPositionFile encodes chrom * 1e9 + pos
+			Pos:   g.pos[j] % 1e9,
+			Stat:  stats[i],
+		}
+		if info.BetaSE {
+			rows[i].Beta = beta[i]
+			rows[i].SE = info.StdErr(stats[i], seScale[i])
+		}
+		if ids != nil {
+			rows[i].ID = ids[j]
+		} else {
+			rows[i].ID = fmt.Sprintf("%d:%d", rows[i].Chrom, rows[i].Pos)
+		}
+	}
+
+	WriteSumStats(g.SumStatsPath(pheno), g.config.SumStatsGzip, rows, info)
+}
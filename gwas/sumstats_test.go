@@ -0,0 +1,127 @@
+package gwas
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/hhcho/sfgwas-private/crypto"
+	"github.com/ldsec/lattigo/v2/ckks"
+)
+
+func TestAssocSnpIndices(t *testing.T) {
+	cps := &crypto.CryptoParams{Params: ckks.DefaultParams[ckks.PN12QP109]}
+	slots := cps.GetSlots()
+
+	// Blocks of 5, 3000 and 4 SNPs; block 0 is not tested, and QC drops the first 10 SNPs
+	// of block 1 and the odd SNPs of block 2
+	sizes := []int{5, 3000, 4}
+	snpFilt := make([]bool, 3009)
+	for j := range snpFilt {
+		snpFilt[j] = j < 5 || (j >= 15 && j < 3005) || (j >= 3005 && (j-3005)%2 == 0)
+	}
+
+	var want []int
+	for j := 15; j < 3005; j++ {
+		want = append(want, j)
+	}
+	want = append(want, 3005, 3007)
+
+	// keepRuns returns the slots of whole ciphertexts with the first n[i] slots of run i kept
+	keepRuns := func(ctx []int, n []int) []bool {
+		var filt []bool
+		for i := range ctx {
+			run := make([]bool, ctx[i]*slots)
+			for k := 0; k < n[i]; k++ {
+				run[k] = true
+			}
+			filt = append(filt, run...)
+		}
+		return filt
+	}
+
+	tests := []struct {
+		name      string
+		format    string
+		outFilter []bool
+	}{
+		// pgen: 2990 SNPs in batches of 2000 and 990, each padded to one ciphertext
+		{"pgen", "pgen", keepRuns([]int{1, 1, 1}, []int{2000, 990, 2})},
+		// Other formats: the whole block in 2 ciphertexts
+		{"bed", "bed", keepRuns([]int{2, 1}, []int{2990, 2})},
+	}
+	for _, tt := range tests {
+		g := &ProtocolInfo{
+			cps:            cps,
+			genoBlockSizes: sizes,
+			gwasParams:     &GWASParams{snpFilt: snpFilt},
+			config:         &Config{GenoFileFormat: tt.format, PgenBatchSize: 2000, BlocksForAssoc: []int{1, 2}},
+		}
+		if got := g.assocSnpIndices(tt.outFilter); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: assocSnpIndices returned %d indices, want %d (first %v)", tt.name, len(got), len(want), got[:Min(len(got), 5)])
+		}
+	}
+
+	// A filter that keeps a slot after the last tested block does not match the blocks
+	g := &ProtocolInfo{
+		cps:            cps,
+		genoBlockSizes: sizes,
+		gwasParams:     &GWASParams{snpFilt: snpFilt},
+		config:         &Config{GenoFileFormat: "bed", BlocksForAssoc: []int{1, 2}},
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("assocSnpIndices accepted a filter that keeps a slot after the last block")
+		}
+	}()
+	g.assocSnpIndices(keepRuns([]int{2, 1, 1}, []int{2990, 2, 1}))
+}
+
+func TestSumStats(t *testing.T) {
+	// Simple regressions (intercept only, n = 10, df = 8) with the coefficient table of
+	// lm(y ~ x): x = 0,1,2,0,1,2,1,0,2,1, y = 1.2,0.8,2.9,0.3,2.2,1.9,0.7,1.1,3.4,1.5
+	// has r = 0.764616, beta = 0.933333, SE = 0.278139, t = 3.35564, p = 0.00999624;
+	// x = 1..10, y = 2.1,3.9,6.2,7.8,9.7,12.4,13.8,16.1,18.3,19.6 has r = 0.999020,
+	// SE = 0.0311930, t = 63.8255, p = 4.03821e-12. seScale is sqrt(vary / varx).
+	// The other p-values are the two-sided 0.05 critical values of Student's t and the normal.
+	linear := SumStatsInfo{NumSamples: 10, NumCovariates: 1}
+	tests := []struct {
+		name          string
+		info          SumStatsInfo
+		stat, seScale float64
+		t, p, se      float64 // se is NaN where there is no reference, unless df <= 0
+	}{
+		{"linear", linear, 0.7646164591511044, 1.2206555615733703, 3.355640499187086, 0.00999624321180026, 0.27813865447131064},
+		{"linear, strong", linear, 0.9990195349428683, 1.9928630234672504, 63.82552484503403, 4.038214207469082e-12, 0.0311929920786855},
+		{"linear, negative", linear, -0.7646164591511044, 1.2206555615733703, -3.355640499187086, 0.00999624321180026, 0.27813865447131064},
+		{"linear, critical", SumStatsInfo{NumSamples: 12, NumCovariates: 1}, 2.228139 / math.Sqrt(10+2.228139*2.228139), 1, 2.228139, 0.05, math.NaN()},
+		{"linear, r = 1", linear, 1, 2, math.Inf(1), 0, 0},
+		{"linear, r < -1", linear, -1.0000001, 2, math.Inf(-1), 0, 0},
+		{"linear, df = 0", SumStatsInfo{NumSamples: 3, NumCovariates: 2}, 0.5, 1, math.NaN(), math.NaN(), math.NaN()},
+		{"linear, df < 0", SumStatsInfo{NumSamples: 3, NumCovariates: 5}, 0.5, 1, math.NaN(), math.NaN(), math.NaN()},
+		{"logistic", SumStatsInfo{NumSamples: 100, Logistic: true}, 0.1959964, 1, 1.959964, 0.05, math.NaN()},
+	}
+
+	near := func(got, want float64) bool {
+		if math.IsNaN(want) || math.IsInf(want, 0) {
+			return math.IsNaN(got) == math.IsNaN(want) && math.IsInf(got, 1) == math.IsInf(want, 1) && math.IsInf(got, -1) == math.IsInf(want, -1)
+		}
+		return math.Abs(got-want) <= 1e-6*math.Abs(want)+1e-12
+	}
+
+	for _, tt := range tests {
+		tstat := tt.info.TestStatistic(tt.stat)
+		if !near(tstat, tt.t) {
+			t.Errorf("%s: TestStatistic(%g) = %g, want %g", tt.name, tt.stat, tstat, tt.t)
+		}
+		if p := tt.info.PValue(tstat); !near(p, tt.p) {
+			t.Errorf("%s: PValue(%g) = %g, want %g", tt.name, tstat, p, tt.p)
+		}
+		if tt.info.Logistic || (math.IsNaN(tt.se) && tt.info.DegreesOfFreedom() > 0) {
+			continue // No SE reference
+		}
+		if se := tt.info.StdErr(tt.stat, tt.seScale); !near(se, tt.se) {
+			t.Errorf("%s: StdErr(%g, %g) = %g, want %g", tt.name, tt.stat, tt.seScale, se, tt.se)
+		}
+	}
+}
@@ -8,7 +8,6 @@ import (
 	"io"
 	"log"
 	"os"
-	"os/exec"
 	"strconv"
 	"strings"
 
@@ -138,64 +137,119 @@ func writeFilterToFile(filename string, filter []bool, isBinary bool) {
 	writer.Flush()
 }
 
-func FilterMatrixFilePgen(pgenPrefix string, nrows, ncols int, rowFiltFile, colNamesFile string, colStartPos int, colFilt []bool, outputFile string) {
-	colFiltFile := outputFile + ".colFilter.bin"
+// FilterMatrixFilePgen extracts the variants of a .pgen fileset whose IDs are on lines
+// colStartPos + j (colFilt[j] true) of colNamesFile, for the samples in rowFiltFile
+// (plink2 --keep format, empty for all), into a sample-major int8 matrix file (dosage
+// matrix file if useDosage is set)
+func FilterMatrixFilePgen(pgenPrefix string, nrows, ncols int, rowFiltFile, colNamesFile string, colStartPos int, colFilt []bool, useDosage bool, outputFile string) {
+	ids := LoadFilteredLines(colNamesFile, colStartPos, colFilt)
+	PgenFilesetToGenoFile(pgenPrefix, rowFiltFile, ids, nrows, ncols, useDosage, outputFile)
+}
 
-	writeFilterToFile(colFiltFile, colFilt, true)
+// LoadFilteredLines returns the lines start + j of a file for which filt[j] is true
+func LoadFilteredLines(filename string, start int, filt []bool) []string {
+	file, err := os.Open(filename)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
 
-	cmd := exec.Command("/bin/sh", "scripts/filterMatrixPgen.sh", pgenPrefix, strconv.Itoa(nrows), strconv.Itoa(ncols), rowFiltFile, colFiltFile, colNamesFile, strconv.Itoa(colStartPos), outputFile)
-	cout, e := cmd.CombinedOutput()
-	fmt.Print(string(cout))
-	if e != nil {
-		log.Fatalln(e)
+	var out []string
+	scanner := bufio.NewScanner(file)
+	for lineno := 0; lineno < start+len(filt) && scanner.Scan(); lineno++ {
+		if lineno >= start && filt[lineno-start] {
+			out = append(out, strings.TrimSpace(scanner.Text()))
+		}
 	}
+	if err := scanner.Err(); err != nil {
+		log.Fatal(err)
+	}
+	return out
 }
 
+// FilterMatrixFile writes the entries of an int8 matrix file (nrows by ncols, sample-major)
+// in the kept rows and columns to outputFile
 func FilterMatrixFile(inputFile string, nrows, ncols int, rowFilt, colFilt []bool, outputFile string) {
-	rowFiltFile := inputFile + ".pcaRowFilter.bin"
-	colFiltFile := inputFile + ".pcaColFilter.bin"
+	filterGenoFileStream(NewGenoFileStream(inputFile, uint64(nrows), uint64(ncols), false), rowFilt, colFilt, outputFile)
+}
+
+// FilterDosageMatrixFile is FilterMatrixFile for a dosage matrix file (see NewDosageFileStream),
+// writing the hard calls of the kept entries to an int8 matrix file
+func FilterDosageMatrixFile(inputFile string, nrows, ncols int, rowFilt, colFilt []bool, outputFile string) {
+	filterGenoFileStream(NewDosageFileStream(inputFile, uint64(nrows), uint64(ncols), false), rowFilt, colFilt, outputFile)
+}
 
-	writeFilterToFile(rowFiltFile, rowFilt, true)
-	writeFilterToFile(colFiltFile, colFilt, true)
+func filterGenoFileStream(gfs *GenoFileStream, rowFilt, colFilt []bool, outputFile string) {
+	log.Println("FilterMatrixFile:", gfs.Filename(), gfs.NumRows(), gfs.NumCols(), outputFile)
 
-	cmd := exec.Command("/bin/sh", "scripts/filterMatrix.sh", inputFile, strconv.Itoa(nrows), strconv.Itoa(ncols), rowFiltFile, colFiltFile, outputFile)
-	cout, e := cmd.CombinedOutput()
-	fmt.Print(string(cout))
-	if e != nil {
-		log.Fatalln(e)
+	gfs.UpdateRowFilt(rowFilt)
+	gfs.UpdateColFilt(colFilt)
+
+	out, err := os.Create(outputFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer out.Close()
+
+	writer := bufio.NewWriter(out)
+	buf := make([]byte, gfs.NumColsToKeep())
+	for row := gfs.NextRow(); row != nil; row = gfs.NextRow() {
+		for j := range row {
+			buf[j] = byte(row[j])
+		}
+		if _, err := writer.Write(buf); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		log.Fatal(err)
 	}
+
+	log.Println("FilterMatrixFile: row filter", gfs.NumRowsToKeep(), "/", gfs.NumRows(), "column filter", gfs.NumColsToKeep(), "/", gfs.NumCols())
 }
 
+// TransposeMatrixFile writes the transpose of an int8 matrix file (nrows by ncols) to outputFile
 func TransposeMatrixFile(inputFile string, nrows, ncols int, outputFile string) {
-	cmd := exec.Command("/bin/sh", "scripts/transposeMatrix.sh", inputFile, strconv.Itoa(nrows), strconv.Itoa(ncols), outputFile)
-	cout, e := cmd.CombinedOutput()
-	fmt.Print(string(cout))
-	if e != nil {
-		log.Fatalln(e)
-	}
+	log.Println("TransposeMatrixFile:", inputFile, nrows, ncols, outputFile)
+
+	gfs := NewGenoFileStream(inputFile, uint64(nrows), uint64(ncols), false)
+	WriteGenoFileByVariant(outputFile, OnesBool(ncols), nrows, func(r int, row []int8) {
+		copy(row, gfs.NextRow())
+	})
 }
 
+// MergeBlockFiles concatenates the columns of the int8 matrix files <inputBlockFilePrefix>.<i>.bin
+// (nrows by ncolsPerBlock[i]) into outputFile
 func MergeBlockFiles(inputBlockFilePrefix string, nrows int, ncolsPerBlock []int, outputFile string) {
-	datFile := inputBlockFilePrefix + ".merge.sizes.txt"
+	log.Println("MergeBlockFiles:", inputBlockFilePrefix, nrows, ncolsPerBlock, outputFile)
 
-	// Write dimensions to file
-	file, err := os.Create(datFile)
-	defer file.Close()
+	blocks := make([]*GenoFileStream, len(ncolsPerBlock))
+	for i := range blocks {
+		blocks[i] = NewGenoFileStream(fmt.Sprintf("%s.%d.bin", inputBlockFilePrefix, i), uint64(nrows), uint64(ncolsPerBlock[i]), false)
+	}
+
+	out, err := os.Create(outputFile)
 	if err != nil {
 		log.Fatal(err)
 	}
+	defer out.Close()
 
-	writer := bufio.NewWriter(file)
-	for _, v := range ncolsPerBlock {
-		writer.WriteString(strconv.Itoa(v) + "\n")
+	writer := bufio.NewWriter(out)
+	buf := make([]byte, Sum(ncolsPerBlock))
+	for r := 0; r < nrows; r++ {
+		shift := 0
+		for _, gfs := range blocks {
+			for _, x := range gfs.NextRow() {
+				buf[shift] = byte(x)
+				shift++
+			}
+		}
+		if _, err := writer.Write(buf); err != nil {
+			log.Fatal(err)
+		}
 	}
-	writer.Flush()
-
-	cmd := exec.Command("/bin/sh", "scripts/mergeMatrices.sh", inputBlockFilePrefix, strconv.Itoa(nrows), datFile, outputFile)
-	cout, e := cmd.CombinedOutput()
-	fmt.Print(string(cout))
-	if e != nil {
-		log.Fatalln(e)
+	if err := writer.Flush(); err != nil {
+		log.Fatal(err)
 	}
 }
 
@@ -376,6 +430,29 @@ func LoadSNPPositionFile(filename string, delim rune) []uint64 {
 	return data
 }
 
+// LoadBlockSizeFile reads the number of SNPs in each genotype block, one per line
+func LoadBlockSizeFile(filename string) []int {
+	file, err := os.Open(filename)
+	if err != nil {
+		panic(err)
+	}
+	defer file.Close()
+
+	var sizes []int
+	scanner := bufio.NewScanner(file)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		size, err := strconv.Atoi(strings.TrimSpace(scanner.Text()))
+		if err != nil {
+			panic(fmt.Sprintf("%s:%d: %v", filename, lineno, err))
+		}
+		sizes = append(sizes, size)
+	}
+	if err := scanner.Err(); err != nil {
+		panic(err)
+	}
+	return sizes
+}
+
 func SaveMatrixToFile(cps *crypto.CryptoParams, mpcObj *mpc.MPC, cm crypto.CipherMatrix, nElemCol int, sourcePid int, filename string) {
 	pid := mpcObj.GetPid()
 	if pid == 0 {
@@ -0,0 +1,476 @@
+package gwas
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"go.dedis.ch/onet/v3/log"
+
+	"github.com/hhcho/sfgwas-private/mpc"
+)
+
+// Input validation run before the protocol (validate mode): each party checks its input
+// files against the config, then the hub compares the SNP counts and hashes of all parties
+
+// Per-party status sent back by the hub
+const (
+	inputsInvalid      = 1 // Local checks failed
+	inputsInconsistent = 2 // SNPs or config differ from the hub's
+)
+
+// InputSummary describes one party's inputs. Parties running the protocol together must
+// agree on everything but Problems
+type InputSummary struct {
+	Problems   []string
+	NumSnps    int
+	BlockSizes []int
+	ConfigHash [sha256.Size]byte // Parameters that must be identical at every party
+	SnpIDHash  [sha256.Size]byte // Zero if unknown (e.g. "blocks" without snp_ids_file)
+	SnpPosHash [sha256.Size]byte
+}
+
+func (s *InputSummary) problemf(format string, args ...interface{}) {
+	s.Problems = append(s.Problems, fmt.Sprintf(format, args...))
+}
+
+// check runs fn, recording a panic of the file loaders as a problem
+func (s *InputSummary) check(what string, fn func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.problemf("%s: %v", what, r)
+		}
+	}()
+	fn()
+}
+
+// ValidateGWASInputs checks this party's inputs against the config, then connects to the
+// other parties to check that all of them agree on the SNPs and parameters. Logs every
+// problem found and returns whether the protocol can start
+func ValidateGWASInputs(config *Config, pid int) bool {
+	log.LLvl1(time.Now().Format(time.RFC3339), "Validating inputs of party", pid)
+
+	s := ValidateLocalInputs(config, pid)
+	for _, p := range s.Problems {
+		log.LLvl1(time.Now().Format(time.RFC3339), "Invalid input:", p)
+	}
+	if config.HubPartyId < 1 || config.HubPartyId > config.NumMainParties {
+		log.LLvl1(time.Now().Format(time.RFC3339), fmt.Sprintf("hub_party_id is %d, expected 1 to %d", config.HubPartyId, config.NumMainParties))
+		return false
+	}
+
+	networks := initNetworks(config, pid, 1)
+	defer networks[0].CloseAll()
+
+	ok := CrossCheckInputs(networks[0], config, s)
+	if ok {
+		log.LLvl1(time.Now().Format(time.RFC3339), "Inputs of all parties are valid and consistent")
+	}
+	return ok
+}
+
+// ValidateLocalInputs checks the dimensions and contents of this party's input files
+// against the config without starting the protocol
+func ValidateLocalInputs(config *Config, pid int) *InputSummary {
+	s := &InputSummary{ConfigHash: sharedConfigHash(config)}
+
+	if len(config.NumInds) != config.NumMainParties+1 {
+		s.problemf("num_inds has %d entries, expected %d (party 0 first)", len(config.NumInds), config.NumMainParties+1)
+		return s
+	}
+	if config.AssocTest != "" && config.AssocTest != "linear" && config.AssocTest != "logistic" {
+		s.problemf("unsupported assoc_test: %s", config.AssocTest)
+	}
+	if pid == 0 {
+		return s
+	}
+
+	numInds := config.NumInds[pid]
+	if numInds <= 0 {
+		s.problemf("num_inds is %d for party %d", numInds, pid)
+		return s
+	}
+
+	var snpIDs []string
+	var bimVariants []BimVariant
+	switch config.GenoFileFormat {
+	case "bed":
+		s.check(config.GenoFilePrefix+".bim", func() {
+			bimVariants = LoadBimFile(config.GenoFilePrefix + ".bim")
+			s.BlockSizes = BimChromBlocks(bimVariants)
+			snpIDs = make([]string, len(bimVariants))
+			for i, v := range bimVariants {
+				snpIDs[i] = v.ID
+			}
+		})
+		if config.GenoDosage {
+			s.problemf("geno_dosage is not supported for .bed input (hard calls only)")
+		}
+	case "blocks", "pgen", "vcf":
+		if config.GenoFileFormat == "vcf" {
+			s.check("VCF sidecar files", func() { prepareVcfSidecarFiles(config) })
+		}
+		s.check("geno_block_size_file", func() { s.BlockSizes = LoadBlockSizeFile(config.GenoBlockSizeFile) })
+		if config.SnpIdsFile != "" {
+			s.check("snp_ids_file", func() { snpIDs = LoadSNPIDFile(config.SnpIdsFile) })
+		} else if config.GenoFileFormat == "pgen" {
+			s.problemf("snp_ids_file is required for \"pgen\" input")
+		}
+	default:
+		s.problemf("unsupported geno_file_format: %s", config.GenoFileFormat)
+		return s
+	}
+
+	s.NumSnps = Sum(s.BlockSizes)
+	if len(s.BlockSizes) != config.GenoNumBlocks {
+		s.problemf("found %d genotype blocks but geno_num_blocks is %d", len(s.BlockSizes), config.GenoNumBlocks)
+	}
+	// With align_snps the parties may hold different SNPs; num_snps is set by the alignment
+	numSnps := config.NumSnps
+	if config.AlignSnps {
+		numSnps = s.NumSnps
+	} else if s.NumSnps != config.NumSnps {
+		s.problemf("genotype blocks have %d SNPs in total but num_snps is %d", s.NumSnps, config.NumSnps)
+	}
+
+	if snpIDs != nil {
+		checkSnpIDs(s, snpIDs, numSnps)
+	}
+
+	var pos []uint64
+	if bimVariants != nil && config.SnpPosFile == "" {
+		pos = BimPositions(bimVariants)
+	} else if config.SnpPosFile == "" {
+		s.problemf("snp_position_file is not set")
+	} else {
+		s.check("snp_position_file", func() { pos = LoadSNPPositionFile(config.SnpPosFile, '\t') })
+	}
+	if pos != nil {
+		if len(pos) != numSnps {
+			s.problemf("%d SNP positions, expected %d", len(pos), numSnps)
+		}
+		h := sha256.New()
+		binary.Write(h, binary.LittleEndian, pos)
+		copy(s.SnpPosHash[:], h.Sum(nil))
+	}
+
+	if config.AlignSnps && config.GenoFileFormat == "blocks" {
+		if config.SnpAllelesFile == "" {
+			s.problemf("snp_alleles_file is required to align \"blocks\" input")
+		} else {
+			s.check("snp_alleles_file", func() {
+				if n := len(loadAllelesFile(config.SnpAllelesFile)); n != numSnps {
+					s.problemf("%s has %d lines, expected %d", config.SnpAllelesFile, n, numSnps)
+				}
+			})
+		}
+	}
+
+	if len(s.BlockSizes) == config.GenoNumBlocks {
+		checkGenoFiles(s, config, numInds, snpIDs, len(bimVariants))
+	}
+
+	checkMatrixFile(s, config.PhenoFile, numInds, numPhenos(config), "num_phenos", config.AssocTest == "logistic")
+	checkMatrixFile(s, config.CovFile, numInds, config.NumCovs, "num_covs", false)
+
+	if config.UsePrecomputedGenoCount {
+		expected := int64(4 * numGenoCountTypes * numSnps)
+		if info, err := os.Stat(config.GenoCountFile); err != nil {
+			s.problemf("geno_count_file: %v", err)
+		} else if info.Size() != expected {
+			s.problemf("%s has %d bytes, expected %d for %d SNPs", config.GenoCountFile, info.Size(), expected, numSnps)
+		}
+	}
+
+	return s
+}
+
+// checkSnpIDs checks the number of SNP IDs and that they are unique, and hashes them
+func checkSnpIDs(s *InputSummary, ids []string, numSnps int) {
+	if len(ids) != numSnps {
+		s.problemf("%d SNP IDs but num_snps is %d", len(ids), numSnps)
+	}
+
+	seen := make(map[string]int, len(ids))
+	h := sha256.New()
+	for i, id := range ids {
+		if j, dup := seen[id]; dup {
+			s.problemf("duplicate SNP ID %s (SNPs %d and %d)", id, j+1, i+1)
+			return
+		}
+		seen[id] = i
+		h.Write([]byte(id))
+		h.Write([]byte{'\n'})
+	}
+	copy(s.SnpIDHash[:], h.Sum(nil))
+}
+
+// checkGenoFiles checks that the genotype files of every block have num_inds samples
+// (after sample_keep_file) and the expected number of SNPs
+func checkGenoFiles(s *InputSummary, config *Config, numInds int, snpIDs []string, numBimVariants int) {
+	switch config.GenoFileFormat {
+	case "blocks":
+		entrySize := int64(1)
+		if config.GenoDosage {
+			entrySize = 2
+		}
+		for i, size := range s.BlockSizes {
+			filename := fmt.Sprintf("%s.%d.bin", config.GenoFilePrefix, i)
+			expected := int64(numInds) * int64(size) * entrySize
+			if info, err := os.Stat(filename); err != nil {
+				s.problemf("%v", err)
+			} else if info.Size() != expected {
+				s.problemf("%s has %d bytes, expected %d (%d samples x %d SNPs x %d bytes)", filename, info.Size(), expected, numInds, size, entrySize)
+			}
+		}
+
+	case "pgen":
+		shift := 0
+		for i, size := range s.BlockSizes {
+			pgenPrefix := fmt.Sprintf(config.GenoFilePrefix, i+1) // 1-based
+			s.check(pgenPrefix, func() {
+				sampleFilt := PgenSampleFilter(pgenPrefix+".psam", config.SampleKeepFile)
+				if n := SumBool(sampleFilt); n != numInds {
+					s.problemf("%s.psam has %d samples to keep but num_inds is %d", pgenPrefix, n, numInds)
+				}
+
+				pr := NewPgenReader(pgenPrefix + ".pgen")
+				defer pr.Close()
+				if pr.NumSamples() != len(sampleFilt) {
+					s.problemf("%s.pgen has %d samples but the .psam has %d", pgenPrefix, pr.NumSamples(), len(sampleFilt))
+				}
+				if n := len(LoadPvarVariantIDs(pgenPrefix + ".pvar")); pr.NumVariants() != n {
+					s.problemf("%s.pgen has %d variants but the .pvar has %d", pgenPrefix, pr.NumVariants(), n)
+				}
+				if len(snpIDs) == s.NumSnps {
+					PgenVariantIndices(pgenPrefix+".pvar", snpIDs[shift:shift+size])
+				}
+			})
+			shift += size
+		}
+
+	case "bed":
+		s.check(config.GenoFilePrefix+".bed", func() {
+			sampleFilt := PgenSampleFilter(config.GenoFilePrefix+".fam", config.SampleKeepFile)
+			if n := SumBool(sampleFilt); n != numInds {
+				s.problemf("%s.fam has %d samples to keep but num_inds is %d", config.GenoFilePrefix, n, numInds)
+			}
+			NewBedReader(config.GenoFilePrefix+".bed", len(sampleFilt), numBimVariants).Close()
+		})
+
+	case "vcf":
+		for i := range s.BlockSizes {
+			vcfFile := fmt.Sprintf(config.GenoFilePrefix, i+1) // 1-based
+			s.check(vcfFile, func() {
+				if n := SumBool(VcfSampleFilter(vcfFile, config.SampleKeepFile)); n != numInds {
+					s.problemf("%s has %d samples to keep but num_inds is %d", vcfFile, n, numInds)
+				}
+			})
+		}
+	}
+}
+
+// checkMatrixFile checks that a tab-separated matrix file (pheno_file, covar_file) has
+// one row per sample, the configured number of columns and only numeric entries (0/1 if
+// isBinary). LoadMatrixFromFile silently reads malformed entries as zero
+func checkMatrixFile(s *InputSummary, filename string, rows, cols int, colsKey string, isBinary bool) {
+	file, err := os.Open(filename)
+	if err != nil {
+		s.problemf("%v", err)
+		return
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.Comma = '\t'
+	reader.FieldsPerRecord = -1
+
+	n := 0
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			s.problemf("%s: %v", filename, err)
+			return
+		}
+		n++
+
+		if len(record) != cols {
+			s.problemf("%s:%d has %d columns but %s is %d", filename, n, len(record), colsKey, cols)
+			return
+		}
+		for j, field := range record {
+			v, err := strconv.ParseFloat(field, 64)
+			if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+				s.problemf("%s:%d: column %d is not a number: %q", filename, n, j+1, field)
+				return
+			}
+			if isBinary && v != 0 && v != 1 {
+				s.problemf("%s:%d: column %d is %v, expected 0 or 1 for assoc_test = \"logistic\"", filename, n, j+1, v)
+				return
+			}
+		}
+	}
+
+	if n != rows {
+		s.problemf("%s has %d rows but num_inds is %d", filename, n, rows)
+	}
+}
+
+// sharedConfigHash hashes the parameters that must be identical at every party
+func sharedConfigHash(config *Config) [sha256.Size]byte {
+	numSnps := config.NumSnps
+	if config.AlignSnps { // Set by the alignment
+		numSnps = 0
+	}
+	shared := fmt.Sprint(
+		config.NumMainParties, config.HubPartyId, config.CkksParams, config.DivSqrtMaxLen,
+		config.NumInds, numSnps, config.NumCovs, numPhenos(config), config.CovAllOnes,
+		config.ItersPerEval, config.NumPCs, config.NumOversample, config.NumPowerIters,
+		config.SkipQC, config.SkipPCA, config.AssocTest, config.LogisticNumIters, config.AssocBetaSE,
+		config.IndMissUB, config.HetLB, config.HetUB, config.SnpMissUB, config.MafLB, config.HweUB, config.SnpDistThres,
+		config.UsePrecomputedGenoCount, config.GenoNumBlocks, config.BlocksForAssoc,
+		config.AlignSnps, config.AlignKeepAmbiguous, config.Checkpoint,
+		config.MpcFieldSize, config.MpcDataBits, config.MpcFracBits, config.MpcNumThreads, config.MpcBooleanShares,
+	)
+	return sha256.Sum256([]byte(shared))
+}
+
+// CrossCheckInputs sends this party's input summary to the hub, which compares it with its
+// own and returns the status of every party to all parties (including party 0). Returns
+// whether the inputs of all parties are valid and consistent
+func CrossCheckInputs(net *mpc.Network, config *Config, s *InputSummary) bool {
+	pid, hub := net.GetPid(), config.HubPartyId
+	status := make([]uint64, config.NumMainParties+1)
+
+	if pid == hub {
+		if len(s.Problems) > 0 {
+			status[pid] |= inputsInvalid
+		}
+		for p := 1; p <= config.NumMainParties; p++ {
+			if p == hub {
+				continue
+			}
+
+			other, numProblems := receiveInputSummary(net, p)
+			if numProblems > 0 {
+				status[p] |= inputsInvalid
+			}
+			for _, msg := range compareInputSummaries(s, other, config.AlignSnps) {
+				log.LLvl1(time.Now().Format(time.RFC3339), fmt.Sprintf("Party %d: %s", p, msg))
+				status[p] |= inputsInconsistent
+			}
+		}
+
+		for p := 0; p <= config.NumMainParties; p++ {
+			if p != hub {
+				net.SendIntVector(status, p)
+			}
+		}
+	} else {
+		if pid > 0 {
+			sendInputSummary(net, s, hub)
+		}
+		status = net.ReceiveIntVector(len(status), hub)
+	}
+
+	ok := true
+	for p := 1; p < len(status); p++ {
+		if status[p]&inputsInvalid != 0 {
+			log.LLvl1(time.Now().Format(time.RFC3339), fmt.Sprintf("Party %d: inputs failed validation (see the log of party %d)", p, p))
+			ok = false
+		}
+		if status[p]&inputsInconsistent != 0 {
+			log.LLvl1(time.Now().Format(time.RFC3339), fmt.Sprintf("Party %d: inputs do not match the hub's (see the log of party %d)", p, hub))
+			ok = false
+		}
+	}
+	return ok
+}
+
+// compareInputSummaries describes every difference between the inputs of two parties; the
+// SNPs are not compared if they are aligned at startup
+func compareInputSummaries(a, b *InputSummary, aligned bool) []string {
+	var out []string
+	if a.ConfigHash != b.ConfigHash {
+		out = append(out, "shared config parameters differ (num_inds, num_snps, QC/PCA/MPC settings, ...)")
+	}
+	if aligned {
+		if len(a.BlockSizes) != len(b.BlockSizes) {
+			out = append(out, fmt.Sprintf("%d genotype blocks, hub has %d", len(b.BlockSizes), len(a.BlockSizes)))
+		}
+		return out
+	}
+	if a.NumSnps != b.NumSnps {
+		out = append(out, fmt.Sprintf("%d SNPs, hub has %d", b.NumSnps, a.NumSnps))
+	}
+	if len(a.BlockSizes) != len(b.BlockSizes) {
+		out = append(out, fmt.Sprintf("%d genotype blocks, hub has %d", len(b.BlockSizes), len(a.BlockSizes)))
+	} else {
+		for i := range a.BlockSizes {
+			if a.BlockSizes[i] != b.BlockSizes[i] {
+				out = append(out, fmt.Sprintf("block %d has %d SNPs, hub has %d", i, b.BlockSizes[i], a.BlockSizes[i]))
+				break
+			}
+		}
+	}
+
+	var zero [sha256.Size]byte
+	if a.SnpIDHash != zero && b.SnpIDHash != zero && a.SnpIDHash != b.SnpIDHash {
+		out = append(out, "SNP IDs differ from the hub's (order matters)")
+	}
+	if a.SnpPosHash != zero && b.SnpPosHash != zero && a.SnpPosHash != b.SnpPosHash {
+		out = append(out, "SNP positions differ from the hub's")
+	}
+	return out
+}
+
+// Input summaries are sent as a header of numProblems, numSnps, numBlocks and the three
+// hashes, followed by the block sizes
+const inputSummaryHeaderLen = 3 + 3*sha256.Size/8
+
+func sendInputSummary(net *mpc.Network, s *InputSummary, to int) {
+	header := []uint64{uint64(len(s.Problems)), uint64(s.NumSnps), uint64(len(s.BlockSizes))}
+	for _, h := range [][sha256.Size]byte{s.ConfigHash, s.SnpIDHash, s.SnpPosHash} {
+		for i := 0; i < sha256.Size; i += 8 {
+			header = append(header, binary.LittleEndian.Uint64(h[i:]))
+		}
+	}
+	net.SendIntVector(header, to)
+
+	sizes := make([]uint64, len(s.BlockSizes))
+	for i, v := range s.BlockSizes {
+		sizes[i] = uint64(v)
+	}
+	if len(sizes) > 0 {
+		net.SendIntVector(sizes, to)
+	}
+}
+
+func receiveInputSummary(net *mpc.Network, from int) (*InputSummary, int) {
+	header := net.ReceiveIntVector(inputSummaryHeaderLen, from)
+	s := &InputSummary{NumSnps: int(header[1])}
+
+	offset := 3
+	for _, h := range []*[sha256.Size]byte{&s.ConfigHash, &s.SnpIDHash, &s.SnpPosHash} {
+		for i := 0; i < sha256.Size; i += 8 {
+			binary.LittleEndian.PutUint64(h[i:], header[offset])
+			offset++
+		}
+	}
+
+	if numBlocks := int(header[2]); numBlocks > 0 {
+		s.BlockSizes = make([]int, numBlocks)
+		for i, v := range net.ReceiveIntVector(numBlocks, from) {
+			s.BlockSizes[i] = int(v)
+		}
+	}
+	return s, int(header[0])
+}
@@ -0,0 +1,689 @@
+package gwas
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.dedis.ch/onet/v3/log"
+)
+
+// VcfVariant holds the site fields of a VCF/BCF record
+type VcfVariant struct {
+	Chrom string
+	Pos   uint64
+	ID    string
+	Ref   string
+	Alt   string // comma-separated if multiallelic
+}
+
+// VcfReader streams the records of a VCF file (plain, gzip or bgzip) or a BCF file,
+// returning per sample the number of non-reference alleles from the genotype field
+// (GT hard calls, DS dosages or GP genotype probabilities). Missing values are -1 (NaN
+// for dosages)
+type VcfReader struct {
+	filename string
+	file     *os.File
+	reader   *bufio.Reader
+	isBcf    bool
+
+	field   string // "GT", "DS" or "GP"
+	samples []string
+	variant VcfVariant
+	dosage  []float64
+
+	// VCF: sample columns of the current record
+	line []byte
+
+	// BCF: header dictionaries and the sample data of the current record
+	contigs []string
+	dict    []string
+	nfmt    int
+	indiv   []byte
+}
+
+// bcfMissing and bcfEndOfVector are the reserved values of the typed BCF integer and
+// float vectors, indexed by type
+var bcfMissing = map[byte]uint32{1: 0x80, 2: 0x8000, 3: 0x80000000, 5: 0x7F800001}
+var bcfEndOfVector = map[byte]uint32{1: 0x81, 2: 0x8001, 3: 0x80000001, 5: 0x7F800002}
+
+// NewVcfReader opens a VCF/BCF file and reads its header. field selects the FORMAT field
+// the allele counts come from: "GT" (the default if empty), "DS" or "GP". Records without
+// the field fall back to GT
+func NewVcfReader(filename, field string) *VcfReader {
+	if field == "" {
+		field = "GT"
+	}
+	if field != "GT" && field != "DS" && field != "GP" {
+		panic(fmt.Sprintf("unsupported VCF genotype field: %s (expected GT, DS or GP)", field))
+	}
+
+	vr := &VcfReader{filename: filename, field: field}
+	vr.Reset()
+	return vr
+}
+
+// Reset reopens the file and positions the reader at the first record
+func (vr *VcfReader) Reset() {
+	if vr.file != nil {
+		vr.file.Close()
+	}
+
+	file, err := os.Open(vr.filename)
+	if err != nil {
+		panic(err)
+	}
+	vr.file = file
+	vr.reader = bufio.NewReaderSize(file, 1<<20)
+
+	if magic, err := vr.reader.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(vr.reader) // Reads all members of bgzip files
+		if err != nil {
+			panic(fmt.Sprintf("%s: %v", vr.filename, err))
+		}
+		vr.reader = bufio.NewReaderSize(gz, 1<<20)
+	}
+
+	magic, _ := vr.reader.Peek(3)
+	vr.isBcf = string(magic) == "BCF"
+	if vr.isBcf {
+		vr.readBcfHeader()
+	} else {
+		vr.readVcfHeader()
+	}
+}
+
+func (vr *VcfReader) Close() {
+	vr.file.Close()
+}
+
+// Samples returns the sample IDs of the header
+func (vr *VcfReader) Samples() []string {
+	return vr.samples
+}
+
+func (vr *VcfReader) NumSamples() int {
+	return len(vr.samples)
+}
+
+// Variant returns the site fields of the current record
+func (vr *VcfReader) Variant() VcfVariant {
+	return vr.variant
+}
+
+// Next decodes the next record into geno (allele counts, dosages rounded to the nearest
+// integer, missing is -1). Returns false after the last record
+func (vr *VcfReader) Next(geno []int8) bool {
+	if !vr.NextVariant() {
+		return false
+	}
+	vr.Genotypes(geno)
+	return true
+}
+
+// NextDosage decodes the next record into dosage (missing is NaN). Returns false after
+// the last record
+func (vr *VcfReader) NextDosage(dosage []float64) bool {
+	if !vr.NextVariant() {
+		return false
+	}
+	vr.Dosages(dosage)
+	return true
+}
+
+// NextVariant moves to the next record, reading only its site fields
+func (vr *VcfReader) NextVariant() bool {
+	if vr.isBcf {
+		return vr.nextBcfRecord()
+	}
+	return vr.nextVcfRecord()
+}
+
+// Genotypes decodes the current record into geno, as in Next
+func (vr *VcfReader) Genotypes(geno []int8) {
+	if len(vr.dosage) != len(vr.samples) {
+		vr.dosage = make([]float64, len(vr.samples))
+	}
+	vr.Dosages(vr.dosage)
+	for i, d := range vr.dosage {
+		if math.IsNaN(d) {
+			geno[i] = -1
+		} else {
+			geno[i] = int8(math.Round(d))
+		}
+	}
+}
+
+// Dosages decodes the current record into dosage, as in NextDosage
+func (vr *VcfReader) Dosages(dosage []float64) {
+	if vr.isBcf {
+		vr.bcfDosages(dosage)
+	} else {
+		vr.vcfDosages(dosage)
+	}
+}
+
+func (vr *VcfReader) readVcfHeader() {
+	for {
+		line, err := vr.reader.ReadString('\n')
+		if err != nil {
+			panic(fmt.Sprintf("%s: no #CHROM header line", vr.filename))
+		}
+		if strings.HasPrefix(line, "#CHROM") {
+			tok := strings.Split(strings.TrimRight(line, "\r\n"), "\t")
+			if len(tok) > 9 {
+				vr.samples = tok[9:]
+			}
+			return
+		}
+	}
+}
+
+func (vr *VcfReader) nextVcfRecord() bool {
+	for {
+		line, err := vr.reader.ReadBytes('\n')
+		if err == io.EOF && len(line) == 0 {
+			return false
+		} else if err != nil && err != io.EOF {
+			panic(fmt.Sprintf("%s: %v", vr.filename, err))
+		}
+		line = bytes.TrimRight(line, "\r\n")
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+
+		// CHROM POS ID REF ALT QUAL FILTER INFO [FORMAT samples...]
+		var tok [8][]byte
+		rest := line
+		for i := range tok {
+			end := bytes.IndexByte(rest, '\t')
+			if end < 0 {
+				if i < 7 {
+					panic(fmt.Sprintf("%s: truncated record: %.50s", vr.filename, line))
+				}
+				end = len(rest)
+			}
+			tok[i] = rest[:end]
+			rest = rest[Min(end+1, len(rest)):]
+		}
+
+		pos, err := strconv.ParseUint(string(tok[1]), 10, 64)
+		if err != nil {
+			panic(fmt.Sprintf("%s: %v", vr.filename, err))
+		}
+		vr.variant = VcfVariant{
+			Chrom: string(tok[0]),
+			Pos:   pos,
+			ID:    string(tok[2]),
+			Ref:   string(tok[3]),
+			Alt:   string(tok[4]),
+		}
+		vr.line = rest
+		return true
+	}
+}
+
+func (vr *VcfReader) vcfDosages(dosage []float64) {
+	for i := range dosage {
+		dosage[i] = math.NaN()
+	}
+	if len(vr.samples) == 0 {
+		return
+	}
+
+	rest := vr.line
+	end := bytes.IndexByte(rest, '\t')
+	if end < 0 {
+		panic(fmt.Sprintf("%s: no sample columns at %s:%d", vr.filename, vr.variant.Chrom, vr.variant.Pos))
+	}
+	format := bytes.Split(rest[:end], []byte(":"))
+	rest = rest[end+1:]
+
+	field, parse := -1, parseVcfGT
+	for k, key := range format {
+		if string(key) == vr.field {
+			field = k
+		}
+	}
+	if field >= 0 && vr.field == "DS" {
+		parse = parseVcfDS
+	} else if field >= 0 && vr.field == "GP" {
+		parse = parseVcfGP
+	} else {
+		for k, key := range format {
+			if string(key) == "GT" {
+				field = k
+			}
+		}
+	}
+	if field < 0 {
+		return
+	}
+
+	for i := range dosage {
+		if len(rest) == 0 {
+			panic(fmt.Sprintf("%s: expected %d samples at %s:%d", vr.filename, len(dosage), vr.variant.Chrom, vr.variant.Pos))
+		}
+		end := bytes.IndexByte(rest, '\t')
+		if end < 0 {
+			end = len(rest)
+		}
+		value := rest[:end]
+		rest = rest[Min(end+1, len(rest)):]
+
+		for k := 0; k < field && value != nil; k++ { // Trailing subfields may be dropped
+			if c := bytes.IndexByte(value, ':'); c >= 0 {
+				value = value[c+1:]
+			} else {
+				value = nil
+			}
+		}
+		if c := bytes.IndexByte(value, ':'); c >= 0 {
+			value = value[:c]
+		}
+		if len(value) > 0 && !(len(value) == 1 && value[0] == '.') {
+			dosage[i] = parse(value)
+		}
+	}
+}
+
+// parseVcfGT counts the non-reference alleles of a genotype such as 0/1, 1|1 or 1
+func parseVcfGT(value []byte) float64 {
+	count := 0.0
+	for _, allele := range bytes.FieldsFunc(value, func(r rune) bool { return r == '/' || r == '|' }) {
+		if len(allele) == 1 && allele[0] == '.' {
+			return math.NaN()
+		}
+		if !(len(allele) == 1 && allele[0] == '0') {
+			count++
+		}
+	}
+	return count
+}
+
+// parseVcfDS sums the ALT dosages
+func parseVcfDS(value []byte) float64 {
+	sum := 0.0
+	for _, v := range bytes.Split(value, []byte(",")) {
+		ds, err := strconv.ParseFloat(string(v), 64)
+		if err != nil {
+			return math.NaN()
+		}
+		sum += ds
+	}
+	return sum
+}
+
+// parseVcfGP returns the expected ALT count P(het) + 2 P(hom alt) of a biallelic site
+func parseVcfGP(value []byte) float64 {
+	gp := bytes.Split(value, []byte(","))
+	if len(gp) != 3 {
+		return math.NaN()
+	}
+	het, err1 := strconv.ParseFloat(string(gp[1]), 64)
+	hom, err2 := strconv.ParseFloat(string(gp[2]), 64)
+	if err1 != nil || err2 != nil {
+		return math.NaN()
+	}
+	return het + 2*hom
+}
+
+func (vr *VcfReader) readBcfHeader() {
+	head := make([]byte, 9)
+	if _, err := io.ReadFull(vr.reader, head); err != nil {
+		panic(fmt.Sprintf("%s: failed to read header: %v", vr.filename, err))
+	}
+	if head[3] != 2 {
+		panic(fmt.Sprintf("%s: unsupported BCF version %d.%d", vr.filename, head[3], head[4]))
+	}
+
+	text := make([]byte, binary.LittleEndian.Uint32(head[5:]))
+	if _, err := io.ReadFull(vr.reader, text); err != nil {
+		panic(fmt.Sprintf("%s: failed to read header: %v", vr.filename, err))
+	}
+
+	// Strings (FILTER/INFO/FORMAT IDs) and contigs are numbered in order of first
+	// appearance unless an explicit IDX is given; PASS is always 0
+	vr.dict = []string{"PASS"}
+	vr.contigs = nil
+	seen := map[string]bool{"PASS": true}
+	for _, line := range strings.Split(strings.TrimRight(string(text), "\x00"), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.HasPrefix(line, "#CHROM") {
+			tok := strings.Split(line, "\t")
+			if len(tok) > 9 {
+				vr.samples = tok[9:]
+			}
+			continue
+		}
+
+		var kind string
+		for _, k := range []string{"contig", "FILTER", "INFO", "FORMAT"} {
+			if strings.HasPrefix(line, "##"+k+"=<") {
+				kind = k
+			}
+		}
+		if kind == "" {
+			continue
+		}
+
+		id, idx := headerAttr(line, "ID"), headerAttr(line, "IDX")
+		if kind == "contig" {
+			vr.contigs = setDictEntry(vr.contigs, id, idx)
+		} else if idx != "" || !seen[id] {
+			vr.dict = setDictEntry(vr.dict, id, idx)
+			seen[id] = true
+		}
+	}
+}
+
+// headerAttr returns the value of key in a structured header line such as ##INFO=<ID=DP,...>
+func headerAttr(line, key string) string {
+	start := strings.Index(line, "<"+key+"=")
+	if start < 0 {
+		start = strings.Index(line, ","+key+"=")
+	}
+	if start < 0 {
+		return ""
+	}
+	value := line[start+len(key)+2:]
+	if end := strings.IndexAny(value, ",>"); end >= 0 {
+		value = value[:end]
+	}
+	return value
+}
+
+func setDictEntry(dict []string, id, idx string) []string {
+	if idx == "" {
+		return append(dict, id)
+	}
+	i, err := strconv.Atoi(idx)
+	if err != nil {
+		panic(fmt.Sprintf("invalid IDX in BCF header: %s", idx))
+	}
+	for len(dict) <= i {
+		dict = append(dict, "")
+	}
+	dict[i] = id
+	return dict
+}
+
+func (vr *VcfReader) nextBcfRecord() bool {
+	var lens [8]byte
+	if _, err := io.ReadFull(vr.reader, lens[:]); err == io.EOF {
+		return false
+	} else if err != nil {
+		panic(fmt.Sprintf("%s: %v", vr.filename, err))
+	}
+
+	shared := make([]byte, binary.LittleEndian.Uint32(lens[:4]))
+	vr.indiv = make([]byte, binary.LittleEndian.Uint32(lens[4:]))
+	if _, err := io.ReadFull(vr.reader, shared); err != nil {
+		panic(fmt.Sprintf("%s: truncated record: %v", vr.filename, err))
+	}
+	if _, err := io.ReadFull(vr.reader, vr.indiv); err != nil {
+		panic(fmt.Sprintf("%s: truncated record: %v", vr.filename, err))
+	}
+
+	// CHROM POS rlen QUAL n_allele_info n_fmt_sample ID alleles...
+	chrom := int(binary.LittleEndian.Uint32(shared[0:]))
+	pos := binary.LittleEndian.Uint32(shared[4:])
+	nallele := int(binary.LittleEndian.Uint32(shared[16:]) >> 16)
+	fmtSample := binary.LittleEndian.Uint32(shared[20:])
+	vr.nfmt = int(fmtSample >> 24)
+	if int(fmtSample&0xFFFFFF) != len(vr.samples) {
+		panic(fmt.Sprintf("%s: record has %d samples, header has %d", vr.filename, fmtSample&0xFFFFFF, len(vr.samples)))
+	}
+	if chrom >= len(vr.contigs) {
+		panic(fmt.Sprintf("%s: contig %d not in header", vr.filename, chrom))
+	}
+
+	off := 24
+	var id string
+	id, off = bcfString(shared, off)
+	alleles := make([]string, nallele)
+	for i := range alleles {
+		alleles[i], off = bcfString(shared, off)
+	}
+	if id == "" {
+		id = "."
+	}
+
+	vr.variant = VcfVariant{
+		Chrom: vr.contigs[chrom],
+		Pos:   uint64(pos) + 1, // 0-based in BCF
+		ID:    id,
+	}
+	if nallele > 0 {
+		vr.variant.Ref = alleles[0]
+		vr.variant.Alt = strings.Join(alleles[1:], ",")
+	}
+	if vr.variant.Alt == "" {
+		vr.variant.Alt = "."
+	}
+	return true
+}
+
+func (vr *VcfReader) bcfDosages(dosage []float64) {
+	for i := range dosage {
+		dosage[i] = math.NaN()
+	}
+
+	// Per FORMAT field: key (typed int), then a type descriptor shared by n_sample vectors
+	type fmtField struct {
+		typ   byte
+		count int
+		data  []byte
+	}
+	fields := make(map[string]fmtField)
+	off := 0
+	for f := 0; f < vr.nfmt; f++ {
+		var key int
+		key, off = bcfTypedInt(vr.indiv, off)
+		typ, count, next := bcfTypeDescriptor(vr.indiv, off)
+		size := len(dosage) * count * bcfTypeSize(typ)
+		if key >= len(vr.dict) {
+			panic(fmt.Sprintf("%s: FORMAT key %d not in header", vr.filename, key))
+		}
+		fields[vr.dict[key]] = fmtField{typ, count, vr.indiv[next : next+size]}
+		off = next + size
+	}
+
+	field, ok := fields[vr.field]
+	isGT := vr.field == "GT"
+	if !ok {
+		field, ok = fields["GT"]
+		isGT = true
+	}
+	if !ok {
+		return
+	}
+
+	for i := range dosage {
+		values := make([]float64, 0, field.count)
+		missing := false
+		for k := 0; k < field.count; k++ {
+			v, isMissing, eov := bcfValue(field.data, field.typ, i*field.count+k)
+			if eov {
+				break
+			}
+			missing = missing || isMissing
+			values = append(values, v)
+		}
+		if missing || len(values) == 0 {
+			continue
+		}
+
+		switch {
+		case isGT: // (allele + 1) << 1 | phased, 0 allele index is missing
+			count := 0.0
+			for _, v := range values {
+				allele := int(v)>>1 - 1
+				if allele < 0 {
+					count = math.NaN()
+					break
+				}
+				if allele > 0 {
+					count++
+				}
+			}
+			dosage[i] = count
+		case vr.field == "DS":
+			dosage[i] = 0
+			for _, v := range values {
+				dosage[i] += v
+			}
+		case vr.field == "GP" && len(values) == 3:
+			dosage[i] = values[1] + 2*values[2]
+		}
+	}
+}
+
+// bcfTypeDescriptor returns the type and count of a typed BCF value at off, and the
+// offset of its data
+func bcfTypeDescriptor(buf []byte, off int) (byte, int, int) {
+	typ, count := buf[off]&0xF, int(buf[off]>>4)
+	off++
+	if count == 15 {
+		count, off = bcfTypedInt(buf, off)
+	}
+	return typ, count, off
+}
+
+func bcfTypeSize(typ byte) int {
+	switch typ {
+	case 0, 1, 7:
+		return 1
+	case 2:
+		return 2
+	case 3, 5:
+		return 4
+	}
+	panic(fmt.Sprintf("unsupported BCF value type %d", typ))
+}
+
+// bcfTypedInt reads a typed scalar integer
+func bcfTypedInt(buf []byte, off int) (int, int) {
+	typ, _, off := bcfTypeDescriptor(buf, off)
+	v, _, _ := bcfValue(buf[off:], typ, 0)
+	return int(v), off + bcfTypeSize(typ)
+}
+
+// bcfString reads a typed character vector
+func bcfString(buf []byte, off int) (string, int) {
+	typ, count, off := bcfTypeDescriptor(buf, off)
+	if typ != 7 && count > 0 {
+		panic(fmt.Sprintf("expected a BCF string, found type %d", typ))
+	}
+	return strings.TrimRight(string(buf[off:off+count]), "\x00"), off + count
+}
+
+// bcfValue returns element i of a typed vector, and whether it is missing or marks
+// the end of a shorter vector
+func bcfValue(buf []byte, typ byte, i int) (float64, bool, bool) {
+	var raw uint32
+	var v float64
+	switch typ {
+	case 1:
+		raw = uint32(buf[i])
+		v = float64(int8(buf[i]))
+	case 2:
+		raw = uint32(binary.LittleEndian.Uint16(buf[2*i:]))
+		v = float64(int16(raw))
+	case 3:
+		raw = binary.LittleEndian.Uint32(buf[4*i:])
+		v = float64(int32(raw))
+	case 5:
+		raw = binary.LittleEndian.Uint32(buf[4*i:])
+		v = float64(math.Float32frombits(raw))
+	default:
+		panic(fmt.Sprintf("unsupported BCF value type %d", typ))
+	}
+	return v, raw == bcfMissing[typ], raw == bcfEndOfVector[typ]
+}
+
+// VcfSampleFilter returns which samples of a VCF/BCF file are listed in a keep file in the
+// format of plink2 --keep, matching the VCF sample IDs against the IIDs
+func VcfSampleFilter(vcfFile, keepFile string) []bool {
+	vr := NewVcfReader(vcfFile, "")
+	defer vr.Close()
+	return KeepFileSampleFilter(vr.Samples(), keepFile)
+}
+
+// VcfSnpID returns the ID of a variant, or CHROM:POS:REF:ALT if it has none
+func VcfSnpID(v VcfVariant) string {
+	if v.ID == "." || v.ID == "" {
+		return fmt.Sprintf("%s:%d:%s:%s", v.Chrom, v.Pos, v.Ref, v.Alt)
+	}
+	return v.ID
+}
+
+// WriteVcfSidecarFiles scans the variants of one VCF/BCF file per block and writes the
+// SNP IDs (snp_ids_file), the positions (snp_position_file, "chrom\tpos") and the number
+// of variants per file (geno_block_size_file)
+func WriteVcfSidecarFiles(vcfFiles []string, snpIdsFile, snpPosFile, blockSizeFile string) {
+	w := newSnpInfoWriter(snpIdsFile, snpPosFile, blockSizeFile)
+	defer w.Close()
+
+	for _, vcfFile := range vcfFiles {
+		log.LLvl1(time.Now().Format(time.RFC3339), "Scanning variants:", vcfFile)
+
+		vr := NewVcfReader(vcfFile, "")
+		for vr.NextVariant() {
+			v := vr.Variant()
+			w.Add(v.Chrom, v.Pos, VcfSnpID(v))
+		}
+		vr.Close()
+
+		w.EndBlock()
+	}
+}
+
+// VcfToGenoFile decodes all ncols variants of the kept samples of a VCF/BCF file into a
+// sample-major int8 matrix file read by NewGenoFileStream. field is as in NewVcfReader;
+// dosages are rounded to the nearest integer
+func VcfToGenoFile(vcfFile, field string, sampleFilt []bool, ncols int, outputFile string) {
+	vr := NewVcfReader(vcfFile, field)
+	defer vr.Close()
+
+	if len(sampleFilt) != vr.NumSamples() {
+		panic(fmt.Sprintf("%s has %d samples but the sample filter has %d", vcfFile, vr.NumSamples(), len(sampleFilt)))
+	}
+
+	WriteGenoFileByVariant(outputFile, sampleFilt, ncols, func(c int, geno []int8) {
+		if !vr.Next(geno) {
+			panic(fmt.Sprintf("%s has %d variants, expected %d", vcfFile, c, ncols))
+		}
+	})
+	if vr.NextVariant() {
+		panic(fmt.Sprintf("%s has more than the expected %d variants", vcfFile, ncols))
+	}
+}
+
+// VcfToDosageFile decodes the dosages of all ncols variants of the kept samples of a
+// VCF/BCF file into a sample-major dosage matrix file read by NewDosageFileStream
+func VcfToDosageFile(vcfFile, field string, sampleFilt []bool, ncols int, outputFile string) {
+	vr := NewVcfReader(vcfFile, field)
+	defer vr.Close()
+
+	if len(sampleFilt) != vr.NumSamples() {
+		panic(fmt.Sprintf("%s has %d samples but the sample filter has %d", vcfFile, vr.NumSamples(), len(sampleFilt)))
+	}
+
+	WriteDosageFileByVariant(outputFile, sampleFilt, ncols, func(c int, dosage []float64) {
+		if !vr.NextDosage(dosage) {
+			panic(fmt.Sprintf("%s has %d variants, expected %d", vcfFile, c, ncols))
+		}
+	})
+	if vr.NextVariant() {
+		panic(fmt.Sprintf("%s has more than the expected %d variants", vcfFile, ncols))
+	}
+}
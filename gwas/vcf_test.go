@@ -0,0 +1,87 @@
+package gwas
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// testdata/toy.vcf is written by hand: 4 samples, with a missing genotype (./. and .), phased
+// and haploid calls, a multiallelic site, a sample with trailing subfields dropped, and
+// records without DS or GP, which fall back to GT. toy.vcf.gz is the same file gzipped
+var toyVcfVariants = []VcfVariant{
+	{"1", 10583, "rs1", "A", "G"},
+	{"1", 10611, "rs2", "C", "G"},
+	{"2", 200, ".", "T", "C,A"},
+	{"X", 300, "rs4", "G", "A"},
+}
+
+func TestVcfReader(t *testing.T) {
+	nan := math.NaN()
+	tests := []struct {
+		field  string
+		dosage [][]float64
+		geno   [][]int8
+	}{
+		{
+			"GT",
+			[][]float64{{0, 1, 2, nan}, {1, 2, 0, 1}, {2, 1, 0, 2}, {1, 1, nan, 0}},
+			[][]int8{{0, 1, 2, -1}, {1, 2, 0, 1}, {2, 1, 0, 2}, {1, 1, -1, 0}},
+		},
+		{
+			"DS",
+			[][]float64{{0.1, 1.2, 1.9, nan}, {0.8, 2, 0, nan}, {1.2, 1, 0, 2}, {1, 1, nan, 0}},
+			[][]int8{{0, 1, 2, -1}, {1, 2, 0, -1}, {1, 1, 0, 2}, {1, 1, -1, 0}},
+		},
+		{
+			"GP",
+			[][]float64{{0.1, 1.2, 1.9, nan}, {1, 2, 0, 1}, {2, 1, 0, 2}, {1, 1, nan, 0}},
+			[][]int8{{0, 1, 2, -1}, {1, 2, 0, 1}, {2, 1, 0, 2}, {1, 1, -1, 0}},
+		},
+	}
+
+	for _, file := range []string{"testdata/toy.vcf", "testdata/toy.vcf.gz"} {
+		for _, tt := range tests {
+			vr := NewVcfReader(file, tt.field)
+			if samples := vr.Samples(); !reflect.DeepEqual(samples, []string{"s1", "s2", "s3", "s4"}) {
+				t.Fatalf("%s: samples %v", file, samples)
+			}
+
+			dosage := make([]float64, vr.NumSamples())
+			for v := range tt.dosage {
+				if !vr.NextDosage(dosage) {
+					t.Fatalf("%s %s: no variant %d", file, tt.field, v)
+				}
+				if got := vr.Variant(); got != toyVcfVariants[v] {
+					t.Errorf("%s: variant %d is %+v, want %+v", file, v, got, toyVcfVariants[v])
+				}
+				for i := range dosage {
+					if want := tt.dosage[v][i]; math.Abs(dosage[i]-want) > 1e-12 || math.IsNaN(dosage[i]) != math.IsNaN(want) {
+						t.Errorf("%s %s: variant %d sample %d: dosage %g, want %g", file, tt.field, v, i, dosage[i], want)
+					}
+				}
+			}
+			if vr.NextDosage(dosage) {
+				t.Errorf("%s %s: NextDosage returned a variant after the last one", file, tt.field)
+			}
+
+			vr.Reset()
+			geno := make([]int8, vr.NumSamples())
+			for v := range tt.geno {
+				if vr.Next(geno); !reflect.DeepEqual(geno, tt.geno[v]) {
+					t.Errorf("%s %s: variant %d: genotypes %v, want %v", file, tt.field, v, geno, tt.geno[v])
+				}
+			}
+			vr.Close()
+		}
+	}
+}
+
+func TestVcfSnpID(t *testing.T) {
+	want := []string{"rs1", "rs2", "2:200:T:C,A", "rs4"}
+	for v := range toyVcfVariants {
+		if id := VcfSnpID(toyVcfVariants[v]); id != want[v] {
+			t.Errorf("VcfSnpID(%+v) = %s, want %s", toyVcfVariants[v], id, want[v])
+		}
+	}
+}
@@ -0,0 +1,281 @@
+package mpc
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"go.dedis.ch/onet/v3/log"
+)
+
+// Network failures: an I/O error or timeout on any channel aborts the whole party. The party
+// sends a diagnostic to every peer over its control channel (one per peer, next to the data
+// channels of the threads), closes all its channels so that no thread stays blocked, and the
+// failed operation panics with a *NetError. A party that receives an abort message shuts down
+// the same way, reporting the diagnostic of the party that failed first. The functions that
+// run the protocol turn the panic back into an error with RecoverAbort.
+//
+// Failures panic rather than return errors because the Send/Receive methods are called,
+// directly or through the MPC and MHE routines built on them, throughout mpc and gwas, and
+// their results are used inline. An error at each call would change most signatures in the
+// repository, and a missed check would carry on with a zero value. A panic unwinds the whole
+// protocol to the one RecoverAbort of the party. It cannot cross goroutines, though: worker
+// goroutines (the threads of ParallelMPC, CollectiveRotKeyGen and the association batches)
+// defer WorkerPanics.Recover, and the goroutine that waits for them raises the failure again.
+
+// NetError describes the failure that aborted a party
+type NetError struct {
+	Party  int    // Party reporting the error
+	Peer   int    // Peer of the failed operation, -1 if none
+	Op     string // "send to", "receive from", "connect to", "abort"
+	Err    error
+	Remote bool // Reported by Peer in an abort message
+}
+
+func (e *NetError) Error() string {
+	if e.Remote {
+		return fmt.Sprintf("party %d: aborted: %v (notified by party %d)", e.Party, e.Err, e.Peer)
+	} else if e.Peer < 0 {
+		return fmt.Sprintf("party %d: %s: %v", e.Party, e.Op, e.Err)
+	}
+	return fmt.Sprintf("party %d: %s party %d: %v", e.Party, e.Op, e.Peer, e.Err)
+}
+
+func (e *NetError) Unwrap() error {
+	return e.Err
+}
+
+// Timeouts bound the network operations of a party; zero means no limit
+type Timeouts struct {
+	Connect time.Duration // Waiting for a peer at startup
+	Send    time.Duration // Writing one message
+	Receive time.Duration // Waiting for and reading one message
+}
+
+const (
+	abortGrace     = 2 * time.Second // Time for a peer's abort message to arrive after its channel fails
+	maxAbortMsgLen = 4096
+)
+
+// abortState is shared by the networks of all threads of a party
+type abortState struct {
+	pid  int
+	nets []*Network
+	ctrl map[int]net.Conn // Control channel to each peer
+
+	mu        sync.Mutex
+	err       *NetError
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// Running parties by ID, for RecoverAbort (several with the inproc transport)
+var parties = struct {
+	sync.Mutex
+	m map[int]*abortState
+}{
+	m: make(map[int]*abortState),
+}
+
+func newAbortState(pid int, nets []*Network, ctrl map[int]net.Conn) *abortState {
+	a := &abortState{
+		pid:  pid,
+		nets: nets,
+		ctrl: ctrl,
+		done: make(chan struct{}),
+	}
+	for _, n := range nets {
+		n.abort = a
+	}
+	for from, conn := range ctrl {
+		go a.listen(from, conn)
+	}
+
+	parties.Lock()
+	parties.m[pid] = a
+	parties.Unlock()
+	return a
+}
+
+// listen waits for an abort message from a peer
+func (a *abortState) listen(from int, conn net.Conn) {
+	hdr := make([]byte, 4)
+	if err := ReadFull(&conn, hdr); err != nil {
+		return // Closed at shutdown, or the peer exited; a failure shows up on the data channels
+	}
+
+	size := binary.LittleEndian.Uint32(hdr)
+	if size > maxAbortMsgLen {
+		size = maxAbortMsgLen
+	}
+	msg := make([]byte, size)
+	if err := ReadFull(&conn, msg); err != nil {
+		msg = []byte("diagnostic lost: " + err.Error())
+	}
+	a.abort(&NetError{Party: a.pid, Peer: from, Op: "abort", Err: errors.New(string(msg)), Remote: true})
+}
+
+// abort records the first failure of the party, forwards it to the peers, and closes every
+// channel. Returns the first failure
+func (a *abortState) abort(e *NetError) *NetError {
+	a.mu.Lock()
+	if a.err != nil {
+		first := a.err
+		a.mu.Unlock()
+		return first
+	}
+	a.err = e
+	close(a.done)
+	a.mu.Unlock()
+
+	log.LLvl1(time.Now().Format(time.RFC3339), "Network abort:", e)
+
+	msg := []byte(e.Error())
+	if e.Remote { // Forward the diagnostic of the party that failed first
+		msg = []byte(e.Err.Error())
+	}
+	hdr := make([]byte, 4)
+	binary.LittleEndian.PutUint32(hdr, uint32(len(msg)))
+	for to, conn := range a.ctrl {
+		if e.Remote && to == e.Peer {
+			continue
+		}
+		conn.SetWriteDeadline(time.Now().Add(abortGrace))
+		if err := WriteFull(&conn, append(hdr, msg...)); err != nil {
+			log.LLvl1(time.Now().Format(time.RFC3339), fmt.Sprintf("Network abort: party %d not notified: %v", to, err))
+		}
+	}
+
+	for _, n := range a.nets {
+		n.closeConns()
+	}
+	a.closeControl()
+	return e
+}
+
+func (a *abortState) closeControl() {
+	a.closeOnce.Do(func() {
+		for _, conn := range a.ctrl {
+			conn.Close()
+		}
+		parties.Lock()
+		if parties.m[a.pid] == a {
+			delete(parties.m, a.pid)
+		}
+		parties.Unlock()
+	})
+}
+
+// fail aborts the party after a failed operation and panics with the first failure of the party
+func (n *Network) fail(op string, peer int, err error) {
+	if n.abort == nil {
+		panic(&NetError{Party: n.pid, Peer: peer, Op: op, Err: err})
+	}
+
+	// Another thread or a peer may be aborting, which is the cause rather than this error
+	select {
+	case <-n.abort.done:
+	case <-time.After(abortGrace):
+	}
+	panic(n.abort.abort(&NetError{Party: n.pid, Peer: peer, Op: op, Err: err}))
+}
+
+// Abort stops this party and its peers, which report reason as the cause
+func (n *Network) Abort(reason error) {
+	if n.abort != nil {
+		n.abort.abort(&NetError{Party: n.pid, Peer: -1, Op: "abort", Err: reason})
+	}
+}
+
+// Err returns the failure that aborted the party, or nil
+func (n *Network) Err() error {
+	if n.abort == nil {
+		return nil
+	}
+	n.abort.mu.Lock()
+	defer n.abort.mu.Unlock()
+	if n.abort.err == nil {
+		return nil
+	}
+	return n.abort.err
+}
+
+// Done is closed when the party aborts
+func (n *Network) Done() <-chan struct{} {
+	return n.abort.done
+}
+
+// RecoverAbort is deferred by the functions that run the protocol at party pid. It returns the
+// *NetError of an aborted party in err; any other panic is sent to the peers as the cause of
+// an abort before it is passed on
+func RecoverAbort(pid int, err *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	if e, ok := r.(*NetError); ok {
+		*err = e
+		return
+	}
+
+	abortParty(pid, r)
+	panic(r)
+}
+
+// abortParty aborts party pid, if it is running, after a panic other than a network failure
+func abortParty(pid int, r interface{}) {
+	parties.Lock()
+	a := parties.m[pid]
+	parties.Unlock()
+	if a != nil {
+		a.abort(&NetError{Party: pid, Peer: -1, Op: "abort", Err: fmt.Errorf("panic: %v", r)})
+	}
+}
+
+// WorkerPanics carries the panics of the worker goroutines of party pid to the goroutine that
+// waits for them, since a panic can only be recovered in its own goroutine
+type WorkerPanics struct {
+	pid   int
+	mu    sync.Mutex
+	first interface{}
+}
+
+func NewWorkerPanics(pid int) *WorkerPanics {
+	return &WorkerPanics{pid: pid}
+}
+
+// Recover is deferred by each worker. A panic other than a network failure aborts the party
+// as in RecoverAbort, so that the other workers and the peers stop instead of waiting for
+// this one
+func (w *WorkerPanics) Recover() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	_, isNet := r.(*NetError)
+	if !isNet {
+		log.LLvl1(time.Now().Format(time.RFC3339), fmt.Sprintf("Panic in a worker of party %d: %v\n%s", w.pid, r, debug.Stack()))
+		abortParty(w.pid, r)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, firstIsNet := w.first.(*NetError); w.first == nil || (firstIsNet && !isNet) {
+		w.first = r // The cause rather than the network failures of the other workers
+	}
+}
+
+// Raise panics again with the failure of the workers, if any. It is called after waiting for
+// them, or while dispatching them to stop early
+func (w *WorkerPanics) Raise() {
+	w.mu.Lock()
+	r := w.first
+	w.mu.Unlock()
+	if r != nil {
+		panic(r)
+	}
+}
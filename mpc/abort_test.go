@@ -0,0 +1,85 @@
+package mpc
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	mpc_core "github.com/hhcho/mpc-core"
+)
+
+// A panic in a worker thread of one party aborts every party: the failing party passes the
+// panic on, and its peers return the diagnostic it sent
+func TestWorkerPanicAbortsParties(t *testing.T) {
+	const threads = 2
+	tr, err := NewTransport(TransportInProc, "", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := make([]interface{}, testNumParties)
+	var wg sync.WaitGroup
+	for pid := 0; pid < testNumParties; pid++ {
+		wg.Add(1)
+		go func(pid int) {
+			defer wg.Done()
+			nets, err := InitCommunication(tr, pid, testNumParties, threads, SharedKeyConfig{Exchange: true}, Timeouts{})
+			if err != nil {
+				t.Errorf("party %d: %v", pid, err)
+				return
+			}
+			defer func() {
+				if r := recover(); r != nil {
+					results[pid] = r
+				}
+			}()
+
+			results[pid] = func() (err error) {
+				defer RecoverAbort(pid, &err)
+				mpcObjs := ParallelMPC(InitParallelMPCEnv(nets, mpc_core.LElem128Zero, testDataBits, 20))
+				a := mpc_core.InitRMat(mpc_core.LElem128Zero, 1, 64)
+				mpcObjs.runParallel(a, nil, "test", 0, func(mpcObj *MPC, a mpc_core.RMat, _ mpc_core.RElem) mpc_core.RVec {
+					if pid == 2 && mpcObj == mpcObjs[1] {
+						panic("boom")
+					}
+					return mpcObj.RevealSymVec(a[0])
+				})
+				return nil
+			}()
+		}(pid)
+	}
+	wg.Wait()
+
+	if results[2] != "boom" {
+		t.Errorf("party 2: got %v, want the panic passed on", results[2])
+	}
+	// The dealer takes no part in RevealSymVec
+	var netErr *NetError
+	err, _ = results[1].(error)
+	if !errors.As(err, &netErr) || !netErr.Remote || !strings.Contains(netErr.Error(), "panic: boom") {
+		t.Errorf("party 1: got %v, want the abort of party 2", results[1])
+	}
+}
+
+// A [servers] entry that is missing or malformed fails the connection with a *NetError
+func TestConnectConfigErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		servers map[string]Server
+		want    string
+	}{
+		{"missing server", map[string]Server{"party1": {IpAddr: "127.0.0.1", Ports: map[string]string{}}}, "servers.party0 not found"},
+		{"bad port", map[string]Server{"party0": {IpAddr: "127.0.0.1", Ports: map[string]string{"party1": "80x"}}}, "servers.party0.ports.party1"},
+	} {
+		tr, err := NewTransport(TransportTCP, "", tt.servers, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = InitCommunication(tr, 1, 2, 1, SharedKeyConfig{}, Timeouts{})
+		var netErr *NetError
+		if !errors.As(err, &netErr) || netErr.Peer != 0 || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want a *NetError naming %s", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,417 @@
+package mpc
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	mpc_core "github.com/hhcho/mpc-core"
+	"gonum.org/v1/gonum/mat"
+)
+
+type ApproxMethod int
+
+const (
+	ApproxTaylor ApproxMethod = iota
+	ApproxChebyshev
+	ApproxRemez
+	ApproxFourier
+)
+
+var approxMethodNames = []string{"taylor", "chebyshev", "remez", "fourier"}
+
+func (m ApproxMethod) String() string {
+	if int(m) < 0 || int(m) >= len(approxMethodNames) {
+		return fmt.Sprintf("ApproxMethod(%d)", int(m))
+	}
+	return approxMethodNames[m]
+}
+
+func ParseApproxMethod(s string) (ApproxMethod, error) {
+	for i, name := range approxMethodNames {
+		if strings.ToLower(s) == name {
+			return ApproxMethod(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown approximation method %q (expected one of %s)", s, strings.Join(approxMethodNames, ", "))
+}
+
+// Number of grid points used to report the cleartext approximation error
+const approxErrorGridSize = 1001
+
+// Approximator holds a cleartext approximation of F on [Lo, Hi] that can be evaluated
+// on secret shares with EvaluateApprox.
+// Polynomial methods (Taylor, Chebyshev, Remez) are stored in the power basis of the
+// normalized input t = (x - mid) / halfWidth in [-1, 1], so that all secure powers stay
+// bounded in fixed point. For Fourier, F is treated as periodic with period Hi - Lo and
+// Degree is the number of harmonics.
+type Approximator struct {
+	F      func(float64) float64
+	Lo, Hi float64
+	Method ApproxMethod
+	Degree int
+
+	Periodic bool // Reduce inputs modulo Hi - Lo into [Lo, Hi) before evaluating (polynomial methods)
+
+	Poly     []float64      // Power-basis coefficients in t (polynomial methods)
+	PolyEval PolyEvalMethod // How EvaluateApprox evaluates Poly
+	Fourier  *FourierCoeffs // Series coefficients (Fourier)
+
+	MaxErr, MeanErr float64 // Cleartext approximation error on a uniform grid over [Lo, Hi)
+}
+
+func NewApproximator(f func(float64) float64, lo, hi float64, method ApproxMethod, degree int) *Approximator {
+	if !(lo < hi) {
+		panic(fmt.Sprintf("approximation interval [%f, %f] is empty", lo, hi))
+	}
+	if degree < 1 {
+		panic(fmt.Sprintf("approximation degree must be positive, got %d", degree))
+	}
+
+	ap := &Approximator{F: f, Lo: lo, Hi: hi, Method: method, Degree: degree}
+
+	g := func(t float64) float64 { return f(ap.fromUnit(t)) }
+
+	switch method {
+	case ApproxTaylor:
+		ap.Poly = taylorCoeffs(g, degree)
+	case ApproxChebyshev:
+		ap.Poly = chebToMonomial(chebInterpCoeffs(g, degree))
+	case ApproxRemez:
+		ap.Poly = chebToMonomial(remezCoeffs(g, degree))
+	case ApproxFourier:
+		ap.Fourier = NewFourierCoeffs(f, lo, hi, degree)
+	default:
+		panic(fmt.Sprint("Unsupported approximation method:", method))
+	}
+
+	x := make([]float64, approxErrorGridSize)
+	y := make([]float64, approxErrorGridSize)
+	for i := range x {
+		x[i] = lo + (hi-lo)*float64(i)/float64(approxErrorGridSize)
+		y[i] = ap.Eval(x[i])
+	}
+	ap.MaxErr, ap.MeanErr = ap.Error(x, y)
+
+	return ap
+}
+
+func (ap *Approximator) mid() float64       { return (ap.Lo + ap.Hi) / 2 }
+func (ap *Approximator) halfWidth() float64 { return (ap.Hi - ap.Lo) / 2 }
+
+func (ap *Approximator) fromUnit(t float64) float64 { return ap.mid() + ap.halfWidth()*t }
+
+// Eval evaluates the approximation in cleartext
+func (ap *Approximator) Eval(x float64) float64 {
+	if ap.Method == ApproxFourier {
+		return ap.Fourier.Eval(x)
+	}
+
+	if ap.Periodic {
+		period := ap.Hi - ap.Lo
+		x -= period * math.Floor((x-ap.Lo)/period)
+	}
+	t := (x - ap.mid()) / ap.halfWidth()
+	res := 0.0
+	for k := len(ap.Poly) - 1; k >= 0; k-- {
+		res = res*t + ap.Poly[k]
+	}
+	return res
+}
+
+// Error returns the max and mean absolute error of y against F(x), e.g. for
+// revealed outputs of EvaluateApprox
+func (ap *Approximator) Error(x, y []float64) (maxErr, meanErr float64) {
+	for i := range x {
+		e := math.Abs(y[i] - ap.F(x[i]))
+		maxErr = math.Max(maxErr, e)
+		meanErr += e
+	}
+	if len(x) > 0 {
+		meanErr /= float64(len(x))
+	}
+	return
+}
+
+func (ap *Approximator) String() string {
+	return fmt.Sprintf("%s(degree %d on [%g, %g]): max err %.3e, mean err %.3e",
+		ap.Method, ap.Degree, ap.Lo, ap.Hi, ap.MaxErr, ap.MeanErr)
+}
+
+// EvaluateApprox evaluates ap securely on shares of a (at fracBits)
+func (mpcObj *MPC) EvaluateApprox(ap *Approximator, a mpc_core.RVec) mpc_core.RVec {
+	if ap.Method == ApproxFourier {
+		return mpcObj.ComputeFourierSeriesForVec(a, ap.Fourier)
+	}
+
+	if ap.Periodic {
+		a = mpcObj.ReduceModPeriod(a, ap.Lo, ap.Hi-ap.Lo)
+	}
+	t := mpcObj.affineVec(a, 1/ap.halfWidth(), -ap.mid()/ap.halfWidth())
+	return mpcObj.EvaluatePolynomialVec(ap.Poly, t, ap.PolyEval)
+}
+
+// ReduceModPeriod returns shares of a[i] reduced modulo period into [lo, lo + period),
+// without revealing anything about the range of a. The quotient floor((a - lo)/period)
+// is obtained by truncation; since truncation may be off by one, a single batched
+// comparison of the remainder against both ends of the interval corrects it.
+func (mpcObj *MPC) ReduceModPeriod(a mpc_core.RVec, lo, period float64) mpc_core.RVec {
+	rtype := a.Type().Zero()
+	fracBits := mpcObj.GetFracBits()
+	dataBits := mpcObj.GetDataBits()
+	pid := mpcObj.GetPid()
+	n := len(a)
+
+	periodFixed := rtype.FromFloat64(period, fracBits)
+
+	q := mpcObj.affineVec(a, 1/period, -lo/period)
+	q = mpcObj.TruncVec(q, dataBits, fracBits) // integer quotient
+
+	res := a.Copy()
+	q.MulScalar(periodFixed)
+	res.Sub(q)
+
+	// below = [res < lo], inside = [res < lo + period]; res += period * (below + inside - 1)
+	cmp := append(res.Copy(), res...)
+	bounds := mpc_core.InitRVec(rtype, 2*n)
+	if pid == 1 {
+		for i := 0; i < n; i++ {
+			bounds[i] = rtype.FromFloat64(lo, fracBits)
+			bounds[n+i] = rtype.FromFloat64(lo+period, fracBits)
+		}
+	}
+	bits := mpcObj.LessThan(cmp, bounds, mpcObj.GetBooleanShareFlag())
+
+	shift := bits[:n].Copy()
+	shift.Add(bits[n:])
+	if pid == 1 {
+		shift.AddScalar(rtype.One().Neg())
+	}
+	shift.MulScalar(periodFixed)
+	res.Add(shift)
+
+	return res
+}
+
+// EvaluatePolynomialPeriodic evaluates a polynomial on a[i] reduced into [lo, lo + period)
+func (mpcObj *MPC) EvaluatePolynomialPeriodic(coefficients []float64, a mpc_core.RVec, lo, period float64, method PolyEvalMethod) mpc_core.RVec {
+	return mpcObj.EvaluatePolynomialVec(coefficients, mpcObj.ReduceModPeriod(a, lo, period), method)
+}
+
+// Degree of the minimax approximations used by SSSinCosVec
+const sinCosApproxDegree = 15
+
+var sinCosApprox struct {
+	once     sync.Once
+	sin, cos *Approximator
+}
+
+// SSSinCosVec returns shares of sin(a[i]) and cos(a[i]) for inputs of any range:
+// inputs are reduced into [-pi, pi) and both functions are evaluated with minimax
+// polynomials that share one batched power ladder. Unlike SSTrigVec, the result does
+// not depend on how the ring wraps around.
+func (mpcObj *MPC) SSSinCosVec(a mpc_core.RVec) (mpc_core.RVec, mpc_core.RVec) {
+	sinCosApprox.once.Do(func() {
+		sinCosApprox.sin = NewApproximator(math.Sin, -math.Pi, math.Pi, ApproxRemez, sinCosApproxDegree)
+		sinCosApprox.cos = NewApproximator(math.Cos, -math.Pi, math.Pi, ApproxRemez, sinCosApproxDegree)
+	})
+	sinAp, cosAp := sinCosApprox.sin, sinCosApprox.cos
+
+	x := mpcObj.ReduceModPeriod(a, -math.Pi, 2*math.Pi)
+	t := mpcObj.affineVec(x, 1/sinAp.halfWidth(), -sinAp.mid()/sinAp.halfWidth())
+
+	pow := mpcObj.PowersVec(t, sinCosApproxDegree)
+	rtype := t.Type().Zero()
+	fracBits := mpcObj.GetFracBits()
+
+	coeff := mpc_core.RMat{
+		mpc_core.FloatToRVec(rtype, sinAp.Poly, fracBits),
+		mpc_core.FloatToRVec(rtype, cosAp.Poly, fracBits),
+	}
+	res := mpc_core.RMultMat(coeff, pow)
+	res = mpcObj.TruncMat(res, mpcObj.GetDataBits(), fracBits)
+
+	return res[0], res[1]
+}
+
+// affineVec returns shares of scale * a + shift
+func (mpcObj *MPC) affineVec(a mpc_core.RVec, scale, shift float64) mpc_core.RVec {
+	rtype := a.Type()
+	fracBits := mpcObj.GetFracBits()
+
+	res := a.Copy()
+	res.MulScalar(rtype.FromFloat64(scale, fracBits))
+	if mpcObj.GetPid() == 1 {
+		res.AddScalar(rtype.FromFloat64(shift, 2*fracBits))
+	}
+	return mpcObj.TruncVec(res, mpcObj.GetDataBits(), fracBits)
+}
+
+/* Cleartext coefficient computation */
+
+// chebInterpCoeffs returns Chebyshev-basis coefficients of the degree-deg interpolant
+// of g at the Chebyshev nodes of [-1, 1]
+func chebInterpCoeffs(g func(float64) float64, deg int) []float64 {
+	n := deg + 1
+	vals := make([]float64, n)
+	for j := range vals {
+		vals[j] = g(math.Cos(math.Pi * (float64(j) + 0.5) / float64(n)))
+	}
+
+	c := make([]float64, n)
+	for k := range c {
+		for j := range vals {
+			c[k] += vals[j] * math.Cos(math.Pi*float64(k)*(float64(j)+0.5)/float64(n))
+		}
+		c[k] *= 2 / float64(n)
+	}
+	c[0] /= 2
+	return c
+}
+
+// chebToMonomial converts Chebyshev-basis coefficients to the power basis
+func chebToMonomial(c []float64) []float64 {
+	n := len(c)
+	res := make([]float64, n)
+
+	// Power-basis coefficients of T_{k-1} and T_k
+	prev := make([]float64, n)
+	cur := make([]float64, n)
+	prev[0] = 1
+	if n > 1 {
+		cur[1] = 1
+	}
+
+	res[0] = c[0]
+	for k := 1; k < n; k++ {
+		for i := range res {
+			res[i] += c[k] * cur[i]
+		}
+		// T_{k+1} = 2t T_k - T_{k-1}
+		next := make([]float64, n)
+		for i := 0; i < n-1; i++ {
+			next[i+1] = 2 * cur[i]
+		}
+		for i := range next {
+			next[i] -= prev[i]
+		}
+		prev, cur = cur, next
+	}
+	return res
+}
+
+// taylorCoeffs returns the Taylor coefficients of g around 0. Derivatives are read off
+// an interpolant on a neighbourhood of 0, which avoids the cancellation that high-order
+// finite differences suffer from.
+func taylorCoeffs(g func(float64) float64, deg int) []float64 {
+	const radius = 0.5
+	interpDeg := deg + 8
+
+	local := chebToMonomial(chebInterpCoeffs(func(s float64) float64 { return g(radius * s) }, interpDeg))
+
+	res := make([]float64, deg+1)
+	scale := 1.0
+	for k := range res {
+		res[k] = local[k] / scale
+		scale *= radius
+	}
+	return res
+}
+
+// remezCoeffs returns Chebyshev-basis coefficients of the degree-deg minimax
+// approximation of g on [-1, 1], computed with the Remez exchange algorithm
+func remezCoeffs(g func(float64) float64, deg int) []float64 {
+	const maxIters = 50
+	const gridSize = 4000
+	const tol = 1e-9
+
+	n := deg + 2 // size of the reference set
+
+	ref := make([]float64, n)
+	for i := range ref {
+		ref[i] = -math.Cos(math.Pi * float64(i) / float64(n-1))
+	}
+
+	grid := make([]float64, gridSize)
+	for i := range grid {
+		grid[i] = -math.Cos(math.Pi * float64(i) / float64(gridSize-1))
+	}
+
+	var c []float64
+	for iter := 0; iter < maxIters; iter++ {
+		// Solve sum_k c_k T_k(ref_i) + (-1)^i E = g(ref_i)
+		A := mat.NewDense(n, n, nil)
+		b := mat.NewVecDense(n, nil)
+		for i, t := range ref {
+			for k := 0; k <= deg; k++ {
+				A.Set(i, k, math.Cos(float64(k)*math.Acos(t)))
+			}
+			A.Set(i, n-1, math.Pow(-1, float64(i)))
+			b.SetVec(i, g(t))
+		}
+		var sol mat.VecDense
+		if err := sol.SolveVec(A, b); err != nil {
+			if c == nil {
+				return chebInterpCoeffs(g, deg)
+			}
+			break
+		}
+		c = make([]float64, deg+1)
+		for k := range c {
+			c[k] = sol.AtVec(k)
+		}
+		levelled := math.Abs(sol.AtVec(n - 1))
+
+		errAt := func(t float64) float64 { return g(t) - chebEval(c, t) }
+
+		// One extremum per run of constant error sign
+		var ext []float64
+		var extErr []float64
+		for i := 0; i < gridSize; {
+			j := i
+			best := i
+			for j < gridSize && (errAt(grid[j]) >= 0) == (errAt(grid[i]) >= 0) {
+				if math.Abs(errAt(grid[j])) > math.Abs(errAt(grid[best])) {
+					best = j
+				}
+				j++
+			}
+			ext = append(ext, grid[best])
+			extErr = append(extErr, math.Abs(errAt(grid[best])))
+			i = j
+		}
+		if len(ext) < n {
+			break
+		}
+		// Drop the smaller extremum at either end until the reference set has n points
+		for len(ext) > n {
+			if extErr[0] < extErr[len(ext)-1] {
+				ext, extErr = ext[1:], extErr[1:]
+			} else {
+				ext, extErr = ext[:len(ext)-1], extErr[:len(extErr)-1]
+			}
+		}
+		copy(ref, ext)
+		sort.Float64s(ref)
+
+		maxErr := 0.0
+		for _, e := range extErr {
+			maxErr = math.Max(maxErr, e)
+		}
+		if maxErr-levelled <= tol*math.Max(maxErr, 1e-300) {
+			break
+		}
+	}
+	return c
+}
+
+func chebEval(c []float64, t float64) float64 {
+	// Clenshaw recurrence
+	b1, b2 := 0.0, 0.0
+	for k := len(c) - 1; k >= 1; k-- {
+		b1, b2 = 2*t*b1-b2+c[k], b1
+	}
+	return t*b1 - b2 + c[0]
+}
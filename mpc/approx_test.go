@@ -0,0 +1,224 @@
+package mpc
+
+import (
+	"math"
+	"testing"
+
+	mpc_core "github.com/hhcho/mpc-core"
+)
+
+func sigmoid(x float64) float64 { return 1 / (1 + math.Exp(-x)) }
+
+func checkCoeffs(t *testing.T, name string, got, want []float64, tol float64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: %d coefficients, want %d", name, len(got), len(want))
+	}
+	for k := range want {
+		if math.Abs(got[k]-want[k]) > tol {
+			t.Errorf("%s: coefficient %d = %g, want %g", name, k, got[k], want[k])
+		}
+	}
+}
+
+func TestChebyshevCoeffs(t *testing.T) {
+	cube := func(t float64) float64 { return t * t * t }
+
+	// t^3 = (3 T_1 + T_3) / 4
+	c := chebInterpCoeffs(cube, 3)
+	checkCoeffs(t, "chebInterpCoeffs(t^3)", c, []float64{0, 0.75, 0, 0.25}, 1e-12)
+	checkCoeffs(t, "chebToMonomial", chebToMonomial(c), []float64{0, 0, 0, 1}, 1e-12)
+
+	// T_4 = 8t^4 - 8t^2 + 1
+	checkCoeffs(t, "chebToMonomial(T_4)", chebToMonomial([]float64{0, 0, 0, 0, 1}), []float64{1, 0, -8, 0, 8}, 1e-12)
+
+	for _, x := range linspace(-1, 1, 9) {
+		if got := chebEval(c, x); math.Abs(got-cube(x)) > 1e-12 {
+			t.Errorf("chebEval(%g) = %g, want %g", x, got, cube(x))
+		}
+	}
+}
+
+func TestTaylorCoeffs(t *testing.T) {
+	want := make([]float64, 7)
+	fact := 1.0
+	for k := range want {
+		if k > 0 {
+			fact *= float64(k)
+		}
+		want[k] = 1 / fact
+	}
+	checkCoeffs(t, "taylorCoeffs(exp)", taylorCoeffs(math.Exp, 6), want, 1e-9)
+	checkCoeffs(t, "taylorCoeffs(sin)", taylorCoeffs(math.Sin, 5), []float64{0, 1, 0, -1.0 / 6, 0, 1.0 / 120}, 1e-9)
+}
+
+func TestRemezCoeffs(t *testing.T) {
+	// The linear minimax approximation of exp on [-1, 1] has slope sinh(1) and levels
+	// the error at -1, log(sinh(1)) and 1
+	slope := math.Sinh(1)
+	xs := math.Log(slope)
+	c0 := (math.Exp(-1) + slope + math.Exp(xs) - slope*xs) / 2
+	checkCoeffs(t, "remezCoeffs(exp, 1)", remezCoeffs(math.Exp, 1), []float64{c0, slope}, 1e-6)
+
+	// |t| on [-1, 1] at degree 3: t^2 + 1/8 = T_2/2 + 5/8, error 1/8 levelled at 0, +-1/2, +-1,
+	// up to the resolution of the search grid at the kink
+	checkCoeffs(t, "remezCoeffs(|t|, 3)", remezCoeffs(math.Abs, 3), []float64{0.625, 0, 0.5, 0}, 1e-3)
+}
+
+func TestNewFourierCoeffs(t *testing.T) {
+	// A trigonometric polynomial is its own series
+	f := func(theta float64) float64 { return 1 + 2*math.Cos(theta) + 3*math.Sin(2*theta) }
+	fc := NewFourierCoeffs(f, -math.Pi, math.Pi, 3)
+	if math.Abs(fc.A0-1) > 1e-9 {
+		t.Errorf("A0 = %g, want 1", fc.A0)
+	}
+	checkCoeffs(t, "A", fc.A, []float64{2, 0, 0}, 1e-9)
+	checkCoeffs(t, "B", fc.B, []float64{0, 3, 0}, 1e-9)
+
+	// The series is in the angle of x over the period [lo, hi)
+	shifted := NewFourierCoeffs(func(x float64) float64 { return f(x - 2) }, 2-math.Pi, 2+math.Pi, 3)
+	for _, x := range linspace(2-math.Pi, 2+math.Pi, 11) {
+		if got := shifted.Eval(x); math.Abs(got-f(x-2)) > 1e-9 {
+			t.Errorf("Eval(%g) = %g, want %g", x, got, f(x-2))
+		}
+	}
+}
+
+func TestSawtoothCoeffs(t *testing.T) {
+	// Over [-pi/2, pi/2), Sawtooth(x) = 2x/pi: b_n = 2 (-1)^(n+1) / (n pi), a_n = 0
+	fc := NewFourierCoeffs(Sawtooth, -math.Pi/2, math.Pi/2, 5)
+	wantB := make([]float64, 5)
+	for n := 1; n <= 5; n++ {
+		wantB[n-1] = 2 * math.Pow(-1, float64(n+1)) / (float64(n) * math.Pi)
+	}
+	checkCoeffs(t, "A", fc.A, make([]float64, 5), 1e-3)
+	checkCoeffs(t, "B", fc.B, wantB, 1e-3)
+
+	// Over [-pi, pi) the period is covered twice and only even harmonics remain
+	twice := NewFourierCoeffs(Sawtooth, -math.Pi, math.Pi, 4)
+	checkCoeffs(t, "B over two periods", twice.B, []float64{0, wantB[0], 0, wantB[1]}, 1e-3)
+}
+
+func TestComputeFourierSeriesForVec(t *testing.T) {
+	// One series evaluated on inputs inside and outside its period [0, 4)
+	fc := &FourierCoeffs{Lo: 0, Hi: 4, A0: 0.5, A: []float64{1, 0, -0.25}, B: []float64{0, 2, 0}}
+	x := []float64{0, 0.5, 1.7, 2, 3.9, -1.25, 6.5, 13}
+	want := make([]float64, len(x))
+	for i := range x {
+		want[i] = fc.Eval(x[i])
+	}
+
+	for _, rtype := range []mpc_core.RElem{mpc_core.LElem256Zero, mpc_core.LElem128Zero} {
+		runParties(t, rtype, 20, func(mpcObj *MPC) {
+			got := revealVec(mpcObj, mpcObj.ComputeFourierSeriesForVec(inputVec(mpcObj, x), fc))
+			if mpcObj.GetPid() == 1 {
+				checkVec(t, "ComputeFourierSeriesForVec", x, got, want, 1e-3)
+			}
+		})
+	}
+}
+
+func TestApproximatorError(t *testing.T) {
+	tests := []struct {
+		name   string
+		f      func(float64) float64
+		lo, hi float64
+		method ApproxMethod
+		degree int
+		maxErr float64
+	}{
+		{"taylor exp", math.Exp, -1, 1, ApproxTaylor, 9, 1e-6},
+		{"chebyshev sigmoid", sigmoid, -4, 4, ApproxChebyshev, 9, 1e-3},
+		{"remez sigmoid", sigmoid, -4, 4, ApproxRemez, 9, 1e-3},
+		{"remez log", math.Log, 1, 3, ApproxRemez, 7, 1e-5},
+	}
+	for _, tt := range tests {
+		ap := NewApproximator(tt.f, tt.lo, tt.hi, tt.method, tt.degree)
+		if ap.MaxErr > tt.maxErr {
+			t.Errorf("%s: max error %g, want at most %g", tt.name, ap.MaxErr, tt.maxErr)
+		}
+	}
+
+	// Remez is no worse than interpolation at the same degree
+	cheb := NewApproximator(sigmoid, -4, 4, ApproxChebyshev, 9)
+	remez := NewApproximator(sigmoid, -4, 4, ApproxRemez, 9)
+	if remez.MaxErr > cheb.MaxErr*1.01 {
+		t.Errorf("remez max error %g exceeds chebyshev %g", remez.MaxErr, cheb.MaxErr)
+	}
+}
+
+// Tolerance for fixed-point rounding at the fracBits of the secure tests
+func fixedPointTol(fracBits int) float64 {
+	return 64 * math.Pow(2, -float64(fracBits))
+}
+
+func TestEvaluateApprox(t *testing.T) {
+	tests := []struct {
+		name   string
+		f      func(float64) float64
+		lo, hi float64
+		method ApproxMethod
+		degree int
+	}{
+		{"taylor exp", math.Exp, -1, 1, ApproxTaylor, 9},
+		{"chebyshev sigmoid", sigmoid, -6, 6, ApproxChebyshev, 11},
+		{"remez tanh", math.Tanh, -2, 2, ApproxRemez, 11},
+		{"remez log", math.Log, 1, 3, ApproxRemez, 7},
+		{"fourier exp(sin)", func(x float64) float64 { return math.Exp(math.Sin(x)) }, -math.Pi, math.Pi, ApproxFourier, 8},
+	}
+
+	for _, rtype := range []mpc_core.RElem{mpc_core.LElem256Zero, mpc_core.LElem128Zero} {
+		for _, fracBits := range []int{20, 30} {
+			for _, tt := range tests {
+				ap := NewApproximator(tt.f, tt.lo, tt.hi, tt.method, tt.degree)
+				x := linspace(tt.lo, tt.hi, 17)
+				want := make([]float64, len(x))
+				for i := range x {
+					want[i] = tt.f(x[i])
+				}
+
+				runParties(t, rtype, fracBits, func(mpcObj *MPC) {
+					got := revealVec(mpcObj, mpcObj.EvaluateApprox(ap, inputVec(mpcObj, x)))
+					if mpcObj.GetPid() == 1 {
+						checkVec(t, tt.name, x, got, want, ap.MaxErr+fixedPointTol(fracBits))
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestReduceModPeriod(t *testing.T) {
+	x := []float64{0.5, -2.5, 3, 7.5, -9, 0.1, 20.25, -31.3}
+	lo, period := -math.Pi, 2*math.Pi
+	want := make([]float64, len(x))
+	for i := range x {
+		want[i] = x[i] - period*math.Floor((x[i]-lo)/period)
+	}
+
+	for _, rtype := range []mpc_core.RElem{mpc_core.LElem256Zero, mpc_core.LElem128Zero} {
+		runParties(t, rtype, 20, func(mpcObj *MPC) {
+			got := revealVec(mpcObj, mpcObj.ReduceModPeriod(inputVec(mpcObj, x), lo, period))
+			if mpcObj.GetPid() == 1 {
+				checkVec(t, "ReduceModPeriod", x, got, want, fixedPointTol(20))
+			}
+		})
+	}
+}
+
+func TestSSSinCosVec(t *testing.T) {
+	x := linspace(-20, 20, 41)
+	wantSin, wantCos := make([]float64, len(x)), make([]float64, len(x))
+	for i := range x {
+		wantSin[i], wantCos[i] = math.Sin(x[i]), math.Cos(x[i])
+	}
+
+	runParties(t, mpc_core.LElem256Zero, 20, func(mpcObj *MPC) {
+		s, c := mpcObj.SSSinCosVec(inputVec(mpcObj, x))
+		gotSin, gotCos := revealVec(mpcObj, s), revealVec(mpcObj, c)
+		if mpcObj.GetPid() == 1 {
+			checkVec(t, "sin", x, gotSin, wantSin, 1e-4)
+			checkVec(t, "cos", x, gotCos, wantCos, 1e-4)
+		}
+	})
+}
@@ -153,7 +153,7 @@ func (mpcObj *MPC) BeaverMultElemMat(ar, am, br, bm mpc_core.RMat) mpc_core.RMat
 
 	// Party 0 just does a plaintext multiply
 	if pid == 0 {
-		return mpc_core.RMultMat(am, bm)
+		return mpc_core.RMultElemMat(am, bm)
 	}
 
 	// Initialize the output matrix
@@ -315,95 +315,72 @@ func (mpcObj *MPC) BeaverSinCos(ar, am mpc_core.RElem) (mpc_core.RElem, mpc_core
 	return sin_first, cos_first
 }
 
-// func (mpcObj *MPC) BeaverSinCosVec(ar, am mpc_core.RVec) (mpc_core.RVec, mpc_core.RVec) {
-
-// 	pid := mpcObj.Network.pid
-// 	rtype := mpcObj.GetRType().Zero()
-// 	fracBits := mpcObj.GetFracBits()
-
-// 	last := mpcObj.Network.NumParties - 1
-
-// 	if pid == 0 {
-
-// 		sin_am, cos_am := am.Copy(), am.Copy()
-// 		// Turn the am into a float
-// 		sin_am_float := sin_am.Float64(fracBits)
-// 		cos_am_float := cos_am.Float64(fracBits)
-
-// 		// Take sin of float and turn the sin and cos back into ring elements
-// 		sin_am = rtype.FromFloat64(math.Sin(sin_am_float), fracBits)
-// 		cos_am = rtype.FromFloat64(math.Cos(cos_am_float), fracBits)
-
-// 		// fmt.Printf("value of sin(am): %v\n", sin_am.Float64(fracBits))
-// 		// fmt.Printf("value of cos(am) %v\n", cos_am.Float64(fracBits))
-
-// 		// take our copy and subtract off random value for party 1 (store this share in PRG 1)
-// 		// send remaining value to party 2 and return that value
-// 		mask_sin := sin_am.Copy()
-// 		mask_cos := cos_am.Copy()
-// 		for to := 1; to < mpcObj.Network.NumParties-1; to++ {
-// 			mpcObj.Network.Rand.SwitchPRG(to)
+// BeaverSinCosVec is the batched version of BeaverSinCos: party 0 shares sin(am) and
+// cos(am) for the whole vector in a single message to the last party
+func (mpcObj *MPC) BeaverSinCosVec(ar, am mpc_core.RVec) (mpc_core.RVec, mpc_core.RVec) {
 
-// 			share_sin := mpcObj.Network.Rand.RandElem(rtype) // generates their shares of sine of c
-// 			share_cos := mpcObj.Network.Rand.RandElem(rtype) // generates their shares of cos of c
-// 			mpcObj.Network.Rand.RestorePRG()
-
-// 			mask_sin = mask_sin.Sub(share_sin)
-// 			mask_cos = mask_cos.Sub(share_cos)
-// 		}
-
-// 		mpcObj.Network.SendRData(mask_sin, last)
-// 		mpcObj.Network.SendRData(mask_cos, last)
+	pid := mpcObj.Network.pid
+	rtype := mpcObj.GetRType().Zero()
+	fracBits := mpcObj.GetFracBits()
+	n := len(am)
 
-// 		return mask_sin, mask_cos
-// 	}
+	last := mpcObj.Network.NumParties - 1
 
-// 	sin_ar, cos_ar := ar.Copy(), ar.Copy()
-// 	// Turn the ar into a float
-// 	sin_ar_float := sin_ar.Float64(fracBits)
-// 	cos_ar_float := cos_ar.Float64(fracBits)
+	if pid == 0 {
 
-// 	// Take sin of float and turn the sin and cos back into ring elements
-// 	sin_ar = rtype.FromFloat64(math.Sin(sin_ar_float), fracBits)
-// 	cos_ar = rtype.FromFloat64(math.Cos(cos_ar_float), fracBits)
+		// Row 0 holds sin(am), row 1 holds cos(am)
+		mask := mpc_core.InitRMat(rtype, 2, n)
+		for i := range am {
+			amFloat := am[i].Float64(fracBits)
+			mask[0][i] = rtype.FromFloat64(math.Sin(amFloat), fracBits)
+			mask[1][i] = rtype.FromFloat64(math.Cos(amFloat), fracBits)
+		}
 
-// 	// fmt.Printf("sin_ar is %v\n", sin_ar.Float64(fracBits))
-// 	// fmt.Printf("cos_ar is %v\n", cos_ar.Float64(fracBits))
+		// take our copy and subtract off random values for parties 1..last-1 (stored in their PRGs)
+		// send remaining values to the last party in one batch and return them
+		for to := 1; to < mpcObj.Network.NumParties-1; to++ {
+			mpcObj.Network.Rand.SwitchPRG(to)
+			share := mpcObj.Network.Rand.RandMat(rtype, 2, n) // their shares of sin(am) and cos(am)
+			mpcObj.Network.Rand.RestorePRG()
+			mask.Sub(share)
+		}
 
-// 	var mask_sin mpc_core.RElem
-// 	var mask_cos mpc_core.RElem
+		mpcObj.Network.SendRData(mask, last)
 
-// 	// party 2 recieves the share from party 0
-// 	if pid == last {
-// 		// shares of sin(am) and cos(am) now
-// 		mask_sin = mpcObj.Network.ReceiveRElem(rtype, 0)
-// 		mask_cos = mpcObj.Network.ReceiveRElem(rtype, 0)
-// 	} else {
-// 		mpcObj.Network.Rand.SwitchPRG(0)
-// 		mask_sin = mpcObj.Network.Rand.RandElem(rtype) // retrieve their shares of sin of c
-// 		mask_cos = mpcObj.Network.Rand.RandElem(rtype) // retrieve their shares of cos of c
-// 		mpcObj.Network.Rand.RestorePRG()
-// 	}
+		return mask[0], mask[1]
+	}
 
-// 	// // [sin(a)]cos(x-a) + [cos(a)]sin(x-a)
-// 	// sin := mask_sin.Mul(cos_ar).Add(mask_cos.Mul(sin_ar))
+	sinAr := mpc_core.InitRVec(rtype, n)
+	cosAr := mpc_core.InitRVec(rtype, n)
+	for i := range ar {
+		arFloat := ar[i].Float64(fracBits)
+		sinAr[i] = rtype.FromFloat64(math.Sin(arFloat), fracBits)
+		cosAr[i] = rtype.FromFloat64(math.Cos(arFloat), fracBits)
+	}
 
-// 	// // [cos(a)]cos(x-a) - [sin(a)]sin(x-a)
-// 	// cos := mask_cos.Mul(cos_ar).Sub(mask_sin.Mul(sin_ar))
+	var mask mpc_core.RMat
 
-// 	// [sin(a)]cos(x-a) + [cos(a)]sin(x-a)
-// 	sin_first := mask_sin.Mul(cos_ar)
-// 	sin_second := mask_cos.Mul(sin_ar)
+	// last party receives its shares of sin(am) and cos(am) from party 0
+	if pid == last {
+		mask = mpcObj.Network.ReceiveRMat(rtype, 2, n, 0)
+	} else {
+		mpcObj.Network.Rand.SwitchPRG(0)
+		mask = mpcObj.Network.Rand.RandMat(rtype, 2, n)
+		mpcObj.Network.Rand.RestorePRG()
+	}
 
-// 	// [cos(a)]cos(x-a) - [sin(a)]sin(x-a)
-// 	cos_first := mask_cos.Mul(cos_ar)
-// 	cos_second := mask_sin.Mul(sin_ar)
+	sin := mpc_core.InitRVec(rtype, n)
+	cos := mpc_core.InitRVec(rtype, n)
+	for i := range sin {
+		// [sin(a)]cos(x-a) + [cos(a)]sin(x-a)
+		sin[i] = mask[0][i].Mul(cosAr[i]).Add(mask[1][i].Mul(sinAr[i]))
 
-// 	sin_first = sin_first.Add(sin_second)
-// 	cos_first = cos_first.Sub(cos_second)
+		// [cos(a)]cos(x-a) - [sin(a)]sin(x-a)
+		cos[i] = mask[1][i].Mul(cosAr[i]).Sub(mask[0][i].Mul(sinAr[i]))
+	}
 
-// 	return sin_first, cos_first
-// }
+	return sin, cos
+}
 
 func (mpcObj *MPC) BeaverSigmoid(ar, am mpc_core.RElem) (mpc_core.RElem, mpc_core.RElem) {
 	pid := mpcObj.Network.pid
@@ -0,0 +1,199 @@
+package mpc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+)
+
+// Wire format: every message sent by the Send* methods is one frame, a header followed by the
+// payload. The header carries the kind of message, its sequence number on the channel (counted
+// separately in each direction), the dimensions of vectors and matrices, the payload length
+// and, if checksums are enabled at the sender, the CRC-32C of the payload. The Receive* methods
+// check the header against what they expect, so that parties that fall out of step abort with a
+// *DesyncError instead of reading garbage.
+//
+//	offset  size  field
+//	0       2     magic (frameMagic)
+//	2       1     kind (msgKind)
+//	3       1     flags (frameChecksum)
+//	4       4     sequence number
+//	8       4     rows (vector length, or matrix rows)
+//	12      4     cols (matrix columns)
+//	16      8     payload length
+//	24      4     CRC-32C of the payload, or 0
+
+const (
+	frameHeaderLen = 28
+	frameMagic     = 0x5347 // "GS"
+	frameChecksum  = 1      // Flag: the header carries the checksum of the payload
+
+	anyDim      = -1      // Dimension not checked by the receiver
+	maxFrameLen = 1 << 32 // Bound on payloads whose size the receiver cannot tell in advance
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// msgKind tags each frame with the type of the message
+type msgKind uint8
+
+const (
+	msgNone msgKind = iota // Not a frame header
+	msgInts
+	msgBytes
+	msgRElem
+	msgRVec
+	msgRMat
+	msgPoly
+	msgPolyMat
+	msgCiphertext
+	msgCipherVector
+	msgCipherMatrix
+)
+
+var msgKindNames = map[msgKind]string{
+	msgNone:         "no message",
+	msgInts:         "ints",
+	msgBytes:        "bytes",
+	msgRElem:        "ring element",
+	msgRVec:         "ring vector",
+	msgRMat:         "ring matrix",
+	msgPoly:         "polynomial",
+	msgPolyMat:      "polynomial matrix",
+	msgCiphertext:   "ciphertext",
+	msgCipherVector: "ciphertext vector",
+	msgCipherMatrix: "ciphertext matrix",
+}
+
+func (k msgKind) String() string {
+	if name, ok := msgKindNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown kind %d", uint8(k))
+}
+
+// DesyncError reports a message that does not match the one the receiver expects
+type DesyncError struct {
+	Expected    msgKind
+	Received    msgKind // msgNone if the bytes read are not a frame header
+	ExpectedSeq uint32
+	ReceivedSeq uint32
+	Detail      string // What does not match when the kind and sequence number do
+}
+
+func (e *DesyncError) Error() string {
+	if e.Received == msgNone {
+		return fmt.Sprintf("protocol desync: expected %s message #%d, received bytes that are not a message header",
+			e.Expected, e.ExpectedSeq)
+	} else if e.Received != e.Expected || e.ReceivedSeq != e.ExpectedSeq {
+		return fmt.Sprintf("protocol desync: expected %s message #%d, received %s message #%d",
+			e.Expected, e.ExpectedSeq, e.Received, e.ReceivedSeq)
+	}
+	return fmt.Sprintf("protocol desync: %s message #%d: %s", e.Received, e.ReceivedSeq, e.Detail)
+}
+
+// EnableChecksums adds the checksum of the payload to every frame this network sends.
+// Receivers check it whenever it is present
+func (n *Network) EnableChecksums()  { n.checksums = true }
+func (n *Network) DisableChecksums() { n.checksums = false }
+
+// sendFrame sends parts, concatenated, as the payload of one message
+func (n *Network) sendFrame(to int, kind msgKind, rows, cols int, parts ...[]byte) {
+	hdr := make([]byte, frameHeaderLen)
+	binary.LittleEndian.PutUint16(hdr[0:], frameMagic)
+	hdr[2] = byte(kind)
+	binary.LittleEndian.PutUint32(hdr[4:], n.sendSeq[to])
+	binary.LittleEndian.PutUint32(hdr[8:], uint32(rows))
+	binary.LittleEndian.PutUint32(hdr[12:], uint32(cols))
+
+	size, crc := 0, uint32(0)
+	for _, p := range parts {
+		size += len(p)
+		if n.checksums {
+			crc = crc32.Update(crc, crcTable, p)
+		}
+	}
+	binary.LittleEndian.PutUint64(hdr[16:], uint64(size))
+	if n.checksums {
+		hdr[3] |= frameChecksum
+		binary.LittleEndian.PutUint32(hdr[24:], crc)
+	}
+
+	n.sendSeq[to]++
+	n.write(to, append([][]byte{hdr}, parts...)...)
+	n.UpdateSenderLog(to, frameHeaderLen+size)
+}
+
+// receiveFrame returns the payload of the next message from a peer, aborting the party if it
+// is not a message of the given kind and dimensions (anyDim if unknown) or its payload is
+// longer than maxLen
+func (n *Network) receiveFrame(from int, kind msgKind, rows, cols int, maxLen uint64) []byte {
+	hdr := make([]byte, frameHeaderLen)
+	n.read(from, hdr)
+
+	expected := n.recvSeq[from]
+	desync := func(received msgKind, seq uint32, detail string) {
+		n.fail("receive from", from, &DesyncError{Expected: kind, Received: received, ExpectedSeq: expected, ReceivedSeq: seq, Detail: detail})
+	}
+
+	if binary.LittleEndian.Uint16(hdr[0:]) != frameMagic {
+		desync(msgNone, 0, "")
+	}
+	received, seq := msgKind(hdr[2]), binary.LittleEndian.Uint32(hdr[4:])
+	if received != kind || seq != expected {
+		desync(received, seq, "")
+	}
+	r, c := int(binary.LittleEndian.Uint32(hdr[8:])), int(binary.LittleEndian.Uint32(hdr[12:]))
+	if (rows != anyDim && r != rows) || (cols != anyDim && c != cols) {
+		desync(received, seq, fmt.Sprintf("dimensions %d x %d, expected %s", r, c, dimString(rows, cols)))
+	}
+
+	size := binary.LittleEndian.Uint64(hdr[16:])
+	if size > maxLen {
+		desync(received, seq, fmt.Sprintf("payload of %d bytes, expected at most %d", size, maxLen))
+	}
+
+	payload := make([]byte, size)
+	n.read(from, payload)
+	if hdr[3]&frameChecksum != 0 && crc32.Checksum(payload, crcTable) != binary.LittleEndian.Uint32(hdr[24:]) {
+		desync(received, seq, "checksum mismatch")
+	}
+
+	n.recvSeq[from]++
+	n.UpdateReceiverLog(from, frameHeaderLen+len(payload))
+	return payload
+}
+
+func dimString(rows, cols int) string {
+	dim := func(d int) string {
+		if d == anyDim {
+			return "any"
+		}
+		return fmt.Sprint(d)
+	}
+	return dim(rows) + " x " + dim(cols)
+}
+
+// lengthPrefix returns the length of a variable-length part of a payload, which precedes it
+func lengthPrefix(buf []byte) []byte {
+	hdr := make([]byte, 8)
+	binary.LittleEndian.PutUint64(hdr, uint64(len(buf)))
+	return hdr
+}
+
+// splitPayload separates the length-prefixed part at the start of a payload from the rest
+func (n *Network) splitPayload(from int, kind msgKind, payload []byte) ([]byte, []byte) {
+	if len(payload) >= 8 {
+		if size := binary.LittleEndian.Uint64(payload); size <= uint64(len(payload)-8) {
+			return payload[8 : 8+size], payload[8+size:]
+		}
+	}
+	n.malformed(from, kind)
+	return nil, nil
+}
+
+// malformed aborts the party on a payload that does not match the header of the last message
+func (n *Network) malformed(from int, kind msgKind) {
+	seq := n.recvSeq[from] - 1
+	n.fail("receive from", from, &DesyncError{Expected: kind, Received: kind, ExpectedSeq: seq, ReceivedSeq: seq, Detail: "malformed payload"})
+}
@@ -0,0 +1,216 @@
+package mpc
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	mpc_core "github.com/hhcho/mpc-core"
+)
+
+// framePipe returns the networks of parties 0 and 1, connected by a pipe
+func framePipe(t *testing.T) (*Network, *Network) {
+	c0, c1 := net.Pipe()
+	t.Cleanup(func() {
+		c0.Close()
+		c1.Close()
+	})
+	newNet := func(pid int, conns map[int]net.Conn) *Network {
+		return &Network{
+			pid:           pid,
+			NumParties:    2,
+			conns:         conns,
+			SentBytes:     make(map[int]uint64),
+			ReceivedBytes: make(map[int]uint64),
+			commSent:      make(map[int]int),
+			commReceived:  make(map[int]int),
+			sendSeq:       make([]uint32, 2),
+			recvSeq:       make([]uint32, 2),
+		}
+	}
+	return newNet(0, map[int]net.Conn{1: c0}), newNet(1, map[int]net.Conn{0: c1})
+}
+
+// sendAsync runs send in the background; the pipe blocks it until the receiver reads, and a
+// receiver that aborts leaves it failing on the closed pipe
+func sendAsync(send func()) {
+	go func() {
+		defer func() { recover() }()
+		send()
+	}()
+}
+
+// writeRaw writes the given bytes to the peer of n in the background
+func writeRaw(n *Network, to int, bufs ...[]byte) {
+	sendAsync(func() { n.write(to, bufs...) })
+}
+
+// frameHeader encodes a header as sendFrame does, with a checksum if crc is not nil
+func frameHeader(kind msgKind, seq uint32, rows, cols int, size uint64, crc *uint32) []byte {
+	hdr := make([]byte, frameHeaderLen)
+	binary.LittleEndian.PutUint16(hdr[0:], frameMagic)
+	hdr[2] = byte(kind)
+	binary.LittleEndian.PutUint32(hdr[4:], seq)
+	binary.LittleEndian.PutUint32(hdr[8:], uint32(rows))
+	binary.LittleEndian.PutUint32(hdr[12:], uint32(cols))
+	binary.LittleEndian.PutUint64(hdr[16:], size)
+	if crc != nil {
+		hdr[3] |= frameChecksum
+		binary.LittleEndian.PutUint32(hdr[24:], *crc)
+	}
+	return hdr
+}
+
+// receiveErr runs receive and returns the error the party aborts with, or nil
+func receiveErr(receive func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			var ok bool
+			if err, ok = r.(error); !ok {
+				panic(r)
+			}
+		}
+	}()
+	receive()
+	return nil
+}
+
+// desyncErr returns the *DesyncError err wraps, failing the test if there is none
+func desyncErr(t *testing.T, err error) *DesyncError {
+	t.Helper()
+	var netErr *NetError
+	var desync *DesyncError
+	if !errors.As(err, &netErr) || !errors.As(err, &desync) {
+		t.Fatalf("got %v, want a *NetError wrapping a *DesyncError", err)
+	}
+	return desync
+}
+
+func TestFrameRoundTrip(t *testing.T) {
+	for _, checksums := range []bool{false, true} {
+		sender, receiver := framePipe(t)
+		if checksums {
+			sender.EnableChecksums()
+		}
+		sendAsync(func() {
+			sender.SendIntVector([]uint64{1, 2, 3}, 1)
+			sender.sendBytes([]byte("key"), 1)
+			sender.SendInt(42, 1)
+		})
+
+		if v := receiver.ReceiveIntVector(3, 0); v[0] != 1 || v[1] != 2 || v[2] != 3 {
+			t.Errorf("checksums %t: received %v, want [1 2 3]", checksums, v)
+		}
+		if b := receiver.receiveBytes(3, 0); string(b) != "key" {
+			t.Errorf("checksums %t: received %q, want \"key\"", checksums, b)
+		}
+		if x := receiver.ReceiveInt(0); x != 42 {
+			t.Errorf("checksums %t: received %d, want 42", checksums, x)
+		}
+		if sender.sendSeq[1] != 3 || receiver.recvSeq[0] != 3 {
+			t.Errorf("checksums %t: sequence numbers %d sent, %d received, want 3", checksums, sender.sendSeq[1], receiver.recvSeq[0])
+		}
+	}
+}
+
+func TestFrameSeqMismatch(t *testing.T) {
+	sender, receiver := framePipe(t)
+	receiver.recvSeq[0] = 1
+	sendAsync(func() { sender.SendInt(1, 1) })
+
+	desync := desyncErr(t, receiveErr(func() { receiver.ReceiveInt(0) }))
+	if desync.ExpectedSeq != 1 || desync.ReceivedSeq != 0 || desync.Received != msgInts {
+		t.Errorf("got %v, want ints message #0 instead of #1", desync)
+	}
+}
+
+func TestFrameKindMismatch(t *testing.T) {
+	sender, receiver := framePipe(t)
+	sendAsync(func() { sender.sendBytes([]byte{1}, 1) })
+
+	desync := desyncErr(t, receiveErr(func() { receiver.ReceiveInt(0) }))
+	if desync.Expected != msgInts || desync.Received != msgBytes {
+		t.Errorf("got %v, want bytes instead of ints", desync)
+	}
+}
+
+func TestFrameChecksumMismatch(t *testing.T) {
+	sender, receiver := framePipe(t)
+	payload := make([]byte, 8)
+	crc := crc32.Checksum(payload, crcTable) + 1
+	writeRaw(sender, 1, frameHeader(msgInts, 0, 1, 0, 8, &crc), payload)
+
+	desync := desyncErr(t, receiveErr(func() { receiver.ReceiveInt(0) }))
+	if desync.Detail != "checksum mismatch" {
+		t.Errorf("got %v, want a checksum mismatch", desync)
+	}
+}
+
+func TestFrameOversized(t *testing.T) {
+	sender, receiver := framePipe(t)
+	writeRaw(sender, 1, frameHeader(msgInts, 0, 1, 0, 1<<40, nil))
+
+	desync := desyncErr(t, receiveErr(func() { receiver.ReceiveInt(0) }))
+	if !strings.Contains(desync.Detail, "expected at most 8") {
+		t.Errorf("got %v, want a payload longer than 8 bytes", desync)
+	}
+}
+
+func TestFrameNotAHeader(t *testing.T) {
+	sender, receiver := framePipe(t)
+	writeRaw(sender, 1, make([]byte, frameHeaderLen))
+
+	desync := desyncErr(t, receiveErr(func() { receiver.ReceiveInt(0) }))
+	if desync.Received != msgNone {
+		t.Errorf("got %v, want bytes that are not a header", desync)
+	}
+}
+
+func TestFrameTruncatedHeader(t *testing.T) {
+	sender, receiver := framePipe(t)
+	sendAsync(func() {
+		sender.write(1, frameHeader(msgInts, 0, 1, 0, 8, nil)[:10])
+		sender.conns[1].Close()
+	})
+
+	err := receiveErr(func() { receiver.ReceiveInt(0) })
+	var netErr *NetError
+	if !errors.As(err, &netErr) || !errors.Is(err, io.EOF) {
+		t.Errorf("got %v, want the end of the stream", err)
+	}
+}
+
+func TestSendRDataUnsupported(t *testing.T) {
+	sender, _ := framePipe(t)
+	defer func() {
+		if r, _ := recover().(string); !strings.Contains(r, "[]int") {
+			t.Errorf("got panic %q, want one naming the type", r)
+		}
+	}()
+	sender.SendRData([]int{1}, 1)
+}
+
+func TestFrameTruncatedPayload(t *testing.T) {
+	rtype := mpc_core.LElem128Zero
+	sender, receiver := framePipe(t)
+	payload := make([]byte, 3*rtype.NumBytes()-1)
+	writeRaw(sender, 1, frameHeader(msgRVec, 0, 3, 0, uint64(len(payload)), nil), payload)
+
+	desync := desyncErr(t, receiveErr(func() { receiver.ReceiveRVec(rtype, 3, 0) }))
+	if desync.Received != msgRVec || desync.Detail != "malformed payload" {
+		t.Errorf("got %v, want a malformed ring vector", desync)
+	}
+
+	sender, receiver = framePipe(t)
+	payload = make([]byte, 2*3*rtype.NumBytes()-rtype.NumBytes())
+	writeRaw(sender, 1, frameHeader(msgRMat, 0, 2, 3, uint64(len(payload)), nil), payload)
+
+	desync = desyncErr(t, receiveErr(func() { receiver.ReceiveRMat(rtype, 2, 3, 0) }))
+	if desync.Received != msgRMat || desync.Detail != "malformed payload" {
+		t.Errorf("got %v, want a malformed ring matrix", desync)
+	}
+}
@@ -0,0 +1,130 @@
+package mpc
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"io"
+
+	"github.com/aead/chacha20/chacha"
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/hkdf"
+)
+
+// SharedKeyConfig selects how the shared PRG seeds are established
+type SharedKeyConfig struct {
+	Path     string // Directory with shared_key_*.bin files
+	Exchange bool   // Derive the keys with an X25519 exchange at startup instead of reading Path
+	Persist  bool   // With Exchange, save the derived keys to Path so later runs can read them
+}
+
+// ExchangeSharedKeys runs a pairwise X25519 key agreement with every other party
+// over the already established channels and derives the pairwise and global PRG seeds.
+// Each pairwise seed is expanded with HKDF-SHA256 from the Diffie-Hellman secret and both
+// public keys. The global seed hashes one random contribution per party, sent to each
+// peer under a mask derived from the pairwise secret. The public keys are not signed, so
+// the exchange relies on the channels to authenticate the peers: InitCommunication only
+// runs it over TLS or in-process channels (see keyExchangeAuthenticated).
+func (n *Network) ExchangeSharedKeys() *SharedKeys {
+	pid := n.pid
+
+	priv := make([]byte, curve25519.ScalarSize)
+	contrib := make([]byte, chacha.KeySize)
+	if _, err := rand.Read(priv); err != nil {
+		panic(err)
+	}
+	if _, err := rand.Read(contrib); err != nil {
+		panic(err)
+	}
+	pub, err := curve25519.X25519(priv, curve25519.Basepoint)
+	if err != nil {
+		panic(err)
+	}
+
+	keys := &SharedKeys{
+		Global:   make([]byte, chacha.KeySize),
+		Pairwise: make(map[int][]byte),
+	}
+	contribs := make([][]byte, n.NumParties)
+	contribs[pid] = contrib
+
+	for other := 0; other < n.NumParties; other++ {
+		if other == pid {
+			continue
+		}
+
+		// Smaller ID sends first, as in AssertSync
+		var otherPub []byte
+		if pid < other {
+			n.sendBytes(pub, other)
+			otherPub = n.receiveBytes(len(pub), other)
+		} else {
+			otherPub = n.receiveBytes(len(pub), other)
+			n.sendBytes(pub, other)
+		}
+
+		secret, err := curve25519.X25519(priv, otherPub)
+		if err != nil {
+			panic(fmt.Sprintf("key exchange with party %d failed: %v", other, err))
+		}
+
+		a, b := sortInt(pid, other)
+		var pubA, pubB []byte
+		if pid == a {
+			pubA, pubB = pub, otherPub
+		} else {
+			pubA, pubB = otherPub, pub
+		}
+		info := append(append([]byte(fmt.Sprintf("sfgwas shared key %d %d", a, b)), pubA...), pubB...)
+		kdf := hkdf.New(sha256.New, secret, nil, info)
+
+		keys.Pairwise[other] = make([]byte, chacha.KeySize)
+		mask := make([]byte, chacha.KeySize)
+		if _, err := io.ReadFull(kdf, keys.Pairwise[other]); err != nil {
+			panic(err)
+		}
+		if _, err := io.ReadFull(kdf, mask); err != nil {
+			panic(err)
+		}
+
+		masked := make([]byte, len(contrib))
+		for i := range masked {
+			masked[i] = contrib[i] ^ mask[i]
+		}
+
+		var otherMasked []byte
+		if pid < other {
+			n.sendBytes(masked, other)
+			otherMasked = n.receiveBytes(len(masked), other)
+		} else {
+			otherMasked = n.receiveBytes(len(masked), other)
+			n.sendBytes(masked, other)
+		}
+
+		contribs[other] = make([]byte, len(otherMasked))
+		for i := range otherMasked {
+			contribs[other][i] = otherMasked[i] ^ mask[i]
+		}
+	}
+
+	h := sha256.New()
+	h.Write([]byte("sfgwas global key"))
+	for p := range contribs {
+		h.Write(contribs[p])
+	}
+	copy(keys.Global, h.Sum(nil))
+
+	return keys
+}
+
+func (n *Network) sendBytes(b []byte, to int) {
+	n.sendFrame(to, msgBytes, len(b), 0, b)
+}
+
+func (n *Network) receiveBytes(nbytes, from int) []byte {
+	buf := n.receiveFrame(from, msgBytes, nbytes, 0, uint64(nbytes))
+	if len(buf) != nbytes {
+		n.malformed(from, msgBytes)
+	}
+	return buf
+}
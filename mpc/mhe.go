@@ -445,10 +445,12 @@ func (netObj ParallelNetworks) CollectiveRotKeyGen(parameters *ckks.Parameters,
 
 	// Workers
 	var wg sync.WaitGroup
+	panics := NewWorkerPanics(netObj[0].GetPid())
 	for thread := 0; thread < nproc; thread++ {
 		wg.Add(1)
 		go func(thread int, net *Network, crpGen *ring.UniformSampler) {
 			defer wg.Done()
+			defer panics.Recover()
 			for galEl := range jobChannels[thread] {
 				rtgProtocol := dckks.NewRotKGProtocol(parameters)
 				rtgShare := rtgProtocol.AllocateShares()
@@ -467,6 +469,7 @@ func (netObj ParallelNetworks) CollectiveRotKeyGen(parameters *ckks.Parameters,
 		}(thread, netObj[thread], crpGen[thread])
 	}
 	wg.Wait()
+	panics.Raise()
 
 	return
 }
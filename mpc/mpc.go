@@ -435,107 +435,19 @@ func (mpcObj *MPC) RevealSymMat(a mpc_core.RMat) mpc_core.RMat {
 	return ar
 }
 
-// The i-th column of output contains 0,1,...,pow powers of a[i]
+// The i-th column of output contains 0,1,...,pow powers of a[i], as plain ring elements
+// (no fixed-point truncation; see PowersVec for fixed point). Party 0 gets zeros
 func (mpcObj *MPC) Powers(a mpc_core.RVec, pow int) mpc_core.RMat {
-	pid := mpcObj.Network.pid
-	n := len(a)
-	rtype := a.Type()
-
-	if pow == 1 {
-		b := mpc_core.InitRMat(rtype.Zero(), 2, n)
-		if pid > 0 {
-			if pid == 1 {
-				b[0] = mpc_core.InitRVec(rtype.One(), n)
-			}
-			b[1] = a.Copy()
-		}
-		return b
-	}
-
-	// Pow > 1
-	ar, am := mpcObj.BeaverPartitionVec(a)
-
-	if pid == 0 {
-		ampow := make(mpc_core.RMat, pow-1)
-		ampow[0] = am.Copy()
-		ampow[0].MulElem(am)
-		for p := 1; p < pow-1; p++ {
-			ampow[p] = ampow[p-1].Copy()
-			ampow[p].MulElem(am)
-		}
-
-		// Secret share ampow
-		for p := 1; p < mpcObj.Network.NumParties-1; p++ {
-			mpcObj.Network.Rand.SwitchPRG(p)
-			share := mpcObj.Network.Rand.RandMat(rtype, pow-1, n)
-			mpcObj.Network.Rand.RestorePRG()
-			ampow.Sub(share)
-		}
-
-		mpcObj.Network.SendRData(ampow, mpcObj.Network.NumParties-1)
-
-		b := mpc_core.InitRMat(rtype.Zero(), pow+1, n)
-		return b
-	}
-	var ampow mpc_core.RMat
-	if pid == mpcObj.Network.NumParties-1 {
-		ampow = mpcObj.Network.ReceiveRMat(rtype, pow-1, n, 0)
-	} else {
-		mpcObj.Network.Rand.SwitchPRG(0)
-		ampow = mpcObj.Network.Rand.RandMat(rtype, pow-1, n)
-		mpcObj.Network.Rand.RestorePRG()
-	}
-
-	// Compute powers of ar
-	arpow := make(mpc_core.RMat, pow-1)
-	arpow[0] = ar.Copy()
-	arpow[0].MulElem(ar)
-	for p := 1; p < pow-1; p++ {
-		arpow[p] = arpow[p-1].Copy()
-		arpow[p].MulElem(ar)
-	}
-
-	var t mpc_core.RMat
-	t = mpcObj.GetPascalMatrix(rtype, pow)
-
-	b := mpc_core.InitRMat(rtype.Zero(), pow+1, n)
-	if pid == 1 {
-		b[0] = mpc_core.InitRVec(rtype.One(), n)
+	b := mpcObj.powers(a, pow, false)
+	if mpcObj.Network.pid == 0 {
+		return mpc_core.InitRMat(a.Type().Zero(), pow+1, len(a))
 	}
-	b[1] = a.Copy()
-
-	for p := 2; p <= pow; p++ {
-		if pid == 1 {
-			b[p] = arpow[p-2].Copy()
-		}
-
-		if p == 2 {
-			for i := range b[p] {
-				b[p][i] = b[p][i].Add(t[p][1].Mul(ar[i].Mul(am[i])))
-			}
-		} else {
-			for i := range b[p] {
-				b[p][i] = b[p][i].Add(t[p][1].Mul(arpow[p-3][i].Mul(am[i])))
-			}
-
-			for j := 2; j <= p-2; j++ {
-				for i := range b[p] {
-					b[p][i] = b[p][i].Add(t[p][j].Mul(arpow[p-2-j][i].Mul(ampow[j-2][i])))
-				}
-			}
-
-			for i := range b[p] {
-				b[p][i] = b[p][i].Add(t[p][p-1].Mul(ar[i].Mul(ampow[p-3][i])))
-			}
-		}
-
-		b[p].Add(ampow[p-2])
-	}
-
 	return b
 }
 
-// is a secret shared?
+// EvaluatePoly returns in row j shares of the polynomial with ring coefficients coeff[j]
+// (constant term first) at each element of a, which is secret shared. The powers come from
+// the same batched ladder as EvaluatePolynomialVec, without fixed-point truncation
 func (mpcObj *MPC) EvaluatePoly(a mpc_core.RVec, coeff mpc_core.RMat) mpc_core.RMat {
 	pid := mpcObj.Network.pid
 	n := len(a)
@@ -2729,6 +2641,13 @@ func (mpcObjs ParallelMPC) SqrtInv(a mpc_core.RVec, binaryVersion bool) mpc_core
 		})
 }
 
+func (mpcObjs ParallelMPC) SSSigmoidVec(a mpc_core.RVec) mpc_core.RVec {
+	return mpcObjs.runParallel(mpc_core.RMat{a}, nil, "SSSigmoidVec", mpcObjs[0].divSqrtMaxLen,
+		func(mpc *MPC, mat mpc_core.RMat, aux mpc_core.RElem) mpc_core.RVec {
+			return mpc.SSSigmoidVec(mat[0])
+		})
+}
+
 func (mpcObjs ParallelMPC) IsPositive(a mpc_core.RVec, binaryVersion bool) mpc_core.RVec {
 	return mpcObjs.runParallel(mpc_core.RMat{a}, nil, "IsPositive", mpcObjs[0].divSqrtMaxLen,
 		func(mpc *MPC, mat mpc_core.RMat, aux mpc_core.RElem) mpc_core.RVec {
@@ -2770,6 +2689,7 @@ func (mpcObjs ParallelMPC) runParallel(a mpc_core.RMat, aux mpc_core.RElem, name
 		divSqrtMaxLen := mpcObjs[0].divSqrtMaxLen
 
 		var wg sync.WaitGroup
+		panics := NewWorkerPanics(mpcObjs[0].GetPid())
 		startIndex, endIndex := 0, 0
 		for i := 0; i < numThreads; i++ {
 			if i == numThreads-1 {
@@ -2787,6 +2707,7 @@ func (mpcObjs ParallelMPC) runParallel(a mpc_core.RMat, aux mpc_core.RElem, name
 				wg.Add(1)
 				go func(threadID, startIndex, endIndex, divSqrtMaxLen int, aSub mpc_core.RMat) {
 					defer wg.Done()
+					defer panics.Recover()
 					mpcObjs[threadID].divSqrtMaxLen = divSqrtMaxLen
 					tmp := fn(mpcObjs[threadID], aSub, aux)
 					copy(res[startIndex:endIndex], tmp)
@@ -2797,6 +2718,7 @@ func (mpcObjs ParallelMPC) runParallel(a mpc_core.RMat, aux mpc_core.RElem, name
 			startIndex = endIndex
 		}
 		wg.Wait()
+		panics.Raise()
 
 	}
 
@@ -2894,177 +2816,310 @@ func (mpcObj *MPC) GeneratePowersNaive(a mpc_core.RElem, n int) mpc_core.RVec {
 	return powers
 }
 
+// GeneratePowers returns a^0, ..., a^n of a single element (index 0 unused as before)
 func (mpcObj *MPC) GeneratePowers(a mpc_core.RElem, n int) mpc_core.RVec {
-	// Initialize a slice to store computed powers.
-	rtype := mpcObj.GetRType()
+	pow := mpcObj.PowersVec(mpc_core.RVec{a}, n)
+
+	powers := mpc_core.InitRVec(a.Zero(), n+1)
+	for k := 1; k <= n; k++ {
+		powers[k] = pow[k][0]
+	}
+	return powers
+}
+
+// PowersVec is the fixed-point counterpart of Powers: row k of the output holds a^k
+// (at fracBits) for every element of a, for k = 0..deg
+func (mpcObj *MPC) PowersVec(a mpc_core.RVec, deg int) mpc_core.RMat {
+	return mpcObj.powers(a, deg, true)
+}
+
+// powers returns a^0..a^deg in rows, at fracBits with every product truncated if fixedPoint
+// is set and as plain ring elements otherwise. Each doubling step a^(m+i) = a^i * a^m is a
+// single batched multiplication over the whole vector, so the number of rounds is log(deg)
+// regardless of len(a).
+func (mpcObj *MPC) powers(a mpc_core.RVec, deg int, fixedPoint bool) mpc_core.RMat {
+	rtype := a.Type().Zero()
 	fracBits := mpcObj.GetFracBits()
 	dataBits := mpcObj.GetDataBits()
+	n := len(a)
 
-	// Index 0 is unused for simplicity, so the slice has a length of n+1.
-	powers := mpc_core.InitRVec(rtype.Zero(), n+1)
-	powers[1] = a
+	one := rtype.One()
+	if fixedPoint {
+		one = rtype.FromFloat64(1, fracBits)
+	}
 
-	currentMax := 1 // Current maximum power computed.
+	pow := make(mpc_core.RMat, deg+1)
+	if mpcObj.GetPid() == 1 {
+		pow[0] = mpc_core.InitRVec(one, n)
+	} else {
+		pow[0] = mpc_core.InitRVec(rtype, n)
+	}
+	if deg == 0 {
+		return pow
+	}
+	pow[1] = a.Copy()
 
-	for currentMax < n {
-		nextMax := 2 * currentMax
-		if nextMax > n {
-			nextMax = n
+	for cur := 1; cur < deg; {
+		next := 2 * cur
+		if next > deg {
+			next = deg
 		}
 
-		// Extract the slice [x^1, x^2, ..., x^currentMax]
-		vec := powers[1 : currentMax+1]
+		lhs := make(mpc_core.RVec, 0, (next-cur)*n)
+		rhs := make(mpc_core.RVec, 0, (next-cur)*n)
+		for i := 1; i <= next-cur; i++ {
+			lhs = append(lhs, pow[i]...)
+			rhs = append(rhs, pow[cur]...)
+		}
 
-		// Multiply vec by x^currentMax using vectorTimesScalar
-		newPowers := mpcObj.SSMultElemVecScalar(vec, powers[currentMax])
-		newPowers = mpcObj.TruncVec(newPowers, dataBits, fracBits)
+		prod := mpcObj.SSMultElemVec(lhs, rhs)
+		if fixedPoint {
+			prod = mpcObj.TruncVec(prod, dataBits, fracBits)
+		}
+		for i := 1; i <= next-cur; i++ {
+			pow[cur+i] = prod[(i-1)*n : i*n]
+		}
 
-		// Determine how many new powers to compute
-		newPowersCount := nextMax - currentMax
+		cur = next
+	}
 
-		// Assign newPowers to powers[currentMax+1 : nextMax+1]
-		for i := 0; i < newPowersCount; i++ {
-			powers[currentMax+1+i] = newPowers[i]
-			// fun := mpcObj.RevealSym(newPowers[i]).Float64(fracBits)
-			// fmt.Printf("Computed x^%d = x^%d * x^%d = %.6f\n", currentMax+1+i, currentMax, i+1, fun)
-		}
+	return pow
+}
+
+type PolyEvalMethod int
+
+const (
+	PolyEvalPowers             PolyEvalMethod = iota // All powers, then one linear combination (log(deg) rounds)
+	PolyEvalHorner                                   // deg rounds, one multiplication per element per round
+	PolyEvalPatersonStockmeyer                       // About 2*sqrt(deg) multiplications per element
+)
 
-		currentMax = nextMax // Update the current maximum power.
+// EvaluatePolynomialVec returns shares of sum_k coefficients[k] * a[i]^k (at fracBits)
+// for the whole vector a
+func (mpcObj *MPC) EvaluatePolynomialVec(coefficients []float64, a mpc_core.RVec, method PolyEvalMethod) mpc_core.RVec {
+	deg := len(coefficients) - 1
+	if deg < 0 {
+		panic("EvaluatePolynomialVec: no coefficients")
 	}
 
-	return powers
+	switch method {
+	case PolyEvalPowers:
+		return mpcObj.evaluatePolyPowers(coefficients, mpcObj.PowersVec(a, deg))
+	case PolyEvalHorner:
+		return mpcObj.evaluatePolyHorner(coefficients, a)
+	case PolyEvalPatersonStockmeyer:
+		return mpcObj.evaluatePolyPS(coefficients, a)
+	default:
+		panic(fmt.Sprint("Unsupported polynomial evaluation method:", method))
+	}
 }
 
-func (mpcObj *MPC) EvaluatePolynomial(coefficients []float64, numbers mpc_core.RVec) mpc_core.RVec {
-	rtype := mpcObj.GetRType()
+// evaluatePolyPowers combines the rows of pow (from PowersVec) with public coefficients,
+// in the same way EvaluatePoly combines the output of Powers
+func (mpcObj *MPC) evaluatePolyPowers(coefficients []float64, pow mpc_core.RMat) mpc_core.RVec {
+	rtype := pow[0].Type().Zero()
 	fracBits := mpcObj.GetFracBits()
-	// dataBits := mpcObj.GetDataBits()
-	n := len(coefficients) - 1
-	m := len(numbers)
 
-	// Convert coefficients to RVec (not secret-shared, but plain)
-	coefficientsVec := mpc_core.FloatToRVec(rtype, coefficients, fracBits)
+	coeff := mpc_core.RMat{mpc_core.FloatToRVec(rtype, coefficients, fracBits)}
+	res := mpc_core.RMultMat(coeff, pow[:len(coefficients)])[0]
 
-	results := mpc_core.InitRVec(rtype.Zero(), m)
+	return mpcObj.TruncVec(res, mpcObj.GetDataBits(), fracBits)
+}
 
-	for i := 0; i < m; i++ {
-		// Generate powers of the current number starting from a^1
-		// a^1, a^2, ... a^(n-1)
-		powers := mpcObj.GeneratePowers(numbers[i], n)
+func (mpcObj *MPC) evaluatePolyHorner(coefficients []float64, a mpc_core.RVec) mpc_core.RVec {
+	rtype := a.Type().Zero()
+	fracBits := mpcObj.GetFracBits()
+	dataBits := mpcObj.GetDataBits()
+	pid := mpcObj.GetPid()
+	deg := len(coefficients) - 1
 
-		// Initialize the polynomial result with the constant term (a^0 * coefficient[0])
-		polynomial := rtype.Zero()
+	res := mpc_core.InitRVec(rtype, len(a))
+	if pid == 1 {
+		res = mpc_core.InitRVec(rtype.FromFloat64(coefficients[deg], fracBits), len(a))
+	}
 
-		// Loop through and multiply powers by coefficients (starting from coefficient[1] and a^1)
-		// Loop through coefficients and corresponding powers to compute the polynomial
-		for j := 1; j <= n; j++ {
-			// Multiply a^j by coefficient[j]
-			term := powers[j].Mul(coefficientsVec[j])
+	for k := deg - 1; k >= 0; k-- {
+		res = mpcObj.SSMultElemVec(res, a)
+		res = mpcObj.TruncVec(res, dataBits, fracBits)
+		if pid == 1 {
+			res.AddScalar(rtype.FromFloat64(coefficients[k], fracBits))
+		}
+	}
+
+	return res
+}
 
-			// Add the term to the polynomial
-			polynomial = polynomial.Add(term)
+// evaluatePolyPS splits the polynomial into blocks of k = ceil(sqrt(deg+1)) coefficients,
+// p(a) = sum_j q_j(a) * (a^k)^j, evaluates every q_j from a^0..a^k without further
+// multiplications and runs Horner's rule in a^k over the blocks
+func (mpcObj *MPC) evaluatePolyPS(coefficients []float64, a mpc_core.RVec) mpc_core.RVec {
+	rtype := a.Type().Zero()
+	fracBits := mpcObj.GetFracBits()
+	dataBits := mpcObj.GetDataBits()
+	deg := len(coefficients) - 1
+	n := len(a)
 
-			// Optional: Debugging output (can be removed in production)
-			// fmt.Printf("Computed term a^%d * coeff[%d] = %v * %v = %v\n", j, j, powers[j], coefficientsVec[j], term)
+	k := int(math.Ceil(math.Sqrt(float64(deg + 1))))
+	numBlocks := (deg + k) / k
+
+	pow := mpcObj.PowersVec(a, k)
+
+	// q_j for all blocks, truncated back to fracBits in one batch
+	blocks := make(mpc_core.RMat, numBlocks)
+	for j := range blocks {
+		end := (j + 1) * k
+		if end > deg+1 {
+			end = deg + 1
 		}
+		coeff := mpc_core.RMat{mpc_core.FloatToRVec(rtype, coefficients[j*k:end], fracBits)}
+		blocks[j] = mpc_core.RMultMat(coeff, pow[:end-j*k])[0]
+	}
+	flat := make(mpc_core.RVec, 0, numBlocks*n)
+	for j := range blocks {
+		flat = append(flat, blocks[j]...)
+	}
+	flat = mpcObj.TruncVec(flat, dataBits, fracBits)
 
-		// Store the result
-		results[i] = polynomial
+	res := flat[(numBlocks-1)*n:].Copy()
+	for j := numBlocks - 2; j >= 0; j-- {
+		res = mpcObj.SSMultElemVec(res, pow[k])
+		res = mpcObj.TruncVec(res, dataBits, fracBits)
+		res.Add(flat[j*n : (j+1)*n])
 	}
 
-	return results
+	return res
 }
 
-func f(x float64) float64 {
+// EvaluatePolynomial returns shares of sum_k coefficients[k] * x^k at each element x of
+// numbers, scaled by 2*fracBits (not truncated). The sum includes the constant term
+// coefficients[0], which earlier versions of this function left out. Powers for the whole
+// vector are computed with PowersVec.
+func (mpcObj *MPC) EvaluatePolynomial(coefficients []float64, numbers mpc_core.RVec) mpc_core.RVec {
+	rtype := mpcObj.GetRType()
+	fracBits := mpcObj.GetFracBits()
+
+	pow := mpcObj.PowersVec(numbers, len(coefficients)-1)
+
+	coeff := mpc_core.RMat{mpc_core.FloatToRVec(rtype, coefficients, fracBits)}
+	return mpc_core.RMultMat(coeff, pow)[0]
+}
+
+// Sawtooth is the pi-periodic sawtooth 2x/pi on [-pi/2, pi/2), the original
+// Fourier series demo target
+func Sawtooth(x float64) float64 {
 	return 2 * (x/math.Pi - math.Floor(x/math.Pi+0.5))
 }
 
-// Everything below this only works with LElem2N
+// FourierCoeffs is a truncated Fourier series of a function periodic on [Lo, Hi):
+// f(x) ~ A0 + sum_n A[n-1] cos(n theta) + B[n-1] sin(n theta),
+// with theta = 2*pi*(x - Lo)/(Hi - Lo) - pi. Precomputed tables can be built directly.
+type FourierCoeffs struct {
+	Lo, Hi float64
+	A0     float64
+	A, B   []float64
+}
 
-func (mpcObj *MPC) PrecomputeMultiples(x mpc_core.RElem, N int) (mpc_core.RVec, mpc_core.RVec) {
-	rtype := mpc_core.LElem2N(0)
-	// fmt.Printf("rtype: %T\n", rtype)
+// Number of samples per period used to compute Fourier coefficients
+const fourierGridSize = 4096
 
-	cos_nx := mpc_core.InitRVec(rtype.Zero(), int(N))
-	sin_nx := mpc_core.InitRVec(rtype.Zero(), int(N))
+// NewFourierCoeffs computes the first N Fourier coefficients of f over the period [lo, hi)
+// with the rectangle rule, which is exact for trigonometric polynomials of degree below
+// the grid size
+func NewFourierCoeffs(f func(float64) float64, lo, hi float64, N int) *FourierCoeffs {
+	if !(lo < hi) {
+		panic(fmt.Sprintf("Fourier period [%f, %f] is empty", lo, hi))
+	}
 
-	sin_nx[0], cos_nx[0] = mpcObj.SSTrigElem(x)
+	fc := &FourierCoeffs{Lo: lo, Hi: hi, A: make([]float64, N), B: make([]float64, N)}
 
-	for n := 2; n <= N; n += 1 {
-		n_RElem := rtype.FromInt(n)
+	theta := make([]float64, fourierGridSize)
+	vals := make([]float64, fourierGridSize)
+	for j := range vals {
+		theta[j] = -math.Pi + 2*math.Pi*float64(j)/fourierGridSize
+		vals[j] = f(fc.fromAngle(theta[j]))
+		fc.A0 += vals[j]
+	}
+	fc.A0 /= fourierGridSize
 
-		nx := (n_RElem).Mul(x)
-		sin_nx[int(n)-1], cos_nx[int(n)-1] = mpcObj.SSTrigElem(nx)
+	for n := 1; n <= N; n++ {
+		for j := range vals {
+			fc.A[n-1] += vals[j] * math.Cos(float64(n)*theta[j])
+			fc.B[n-1] += vals[j] * math.Sin(float64(n)*theta[j])
+		}
+		fc.A[n-1] *= 2.0 / fourierGridSize
+		fc.B[n-1] *= 2.0 / fourierGridSize
 	}
-	return sin_nx, cos_nx
+	return fc
 }
 
-func trapz(y []float64, x []float64) float64 {
-	sum := 0.0
-	for i := 1; i < len(y); i++ {
-		sum += (y[i] + y[i-1]) * (x[i] - x[i-1]) / 2
-	}
-	return sum
+func (fc *FourierCoeffs) NumTerms() int {
+	return len(fc.A)
 }
 
-func integrate(f func(float64) float64) float64 {
-	x := make([]float64, 1000)
-	y := make([]float64, 1000)
-	for i := 0; i < 1000; i++ {
-		x[i] = -math.Pi + 2*math.Pi*float64(i)/999
-		y[i] = f(x[i])
-	}
-	return trapz(y, x) / math.Pi
+func (fc *FourierCoeffs) toAngle(x float64) float64 {
+	return 2*math.Pi*(x-fc.Lo)/(fc.Hi-fc.Lo) - math.Pi
 }
 
-func a0() float64 {
-	return integrate(f) / 2
+func (fc *FourierCoeffs) fromAngle(theta float64) float64 {
+	return fc.Lo + (theta+math.Pi)/(2*math.Pi)*(fc.Hi-fc.Lo)
 }
 
-func an(n int) float64 {
-	cosineComponent := func(x float64) float64 {
-		return f(x) * math.Cos(float64(n)*x)
+// Eval evaluates the series at x in cleartext
+func (fc *FourierCoeffs) Eval(x float64) float64 {
+	theta := fc.toAngle(x)
+	res := fc.A0
+	for n := 1; n <= len(fc.A); n++ {
+		res += fc.A[n-1]*math.Cos(float64(n)*theta) + fc.B[n-1]*math.Sin(float64(n)*theta)
 	}
-	return integrate(cosineComponent)
+	return res
 }
 
-func bn(n int) float64 {
-	sineComponent := func(x float64) float64 {
-		return f(x) * math.Sin(float64(n)*x)
+// FourierSeries returns one element's share of sum_n an[n-1] cos(nx) + bn[n-1] sin(nx),
+// scaled by 2*fracBits, given shares of sin(nx) and cos(nx) and fixed-point coefficients
+func (mpcObj *MPC) FourierSeries(sin_nx, cos_nx, an, bn mpc_core.RVec) mpc_core.RElem {
+	result := sin_nx.Type().Zero()
+	for n := range an {
+		result = result.Add(an[n].Mul(cos_nx[n]))
+		result = result.Add(bn[n].Mul(sin_nx[n]))
 	}
-	return integrate(sineComponent)
+	return result
 }
 
-func (mpcObj *MPC) FourierSeries(sin_nx mpc_core.RVec, cos_nx mpc_core.RVec, N int) mpc_core.RElem {
-	rtype := mpc_core.LElem256Zero
+// ComputeFourierSeriesForVec evaluates the series fc on shares of a (at fracBits); inputs
+// outside [Lo, Hi) get the value of the periodic extension. The coefficients are converted
+// to fixed point once and sin(n theta)/cos(n theta) for all elements and harmonics come from
+// a single batched SSSinCosVec call, so any ring type works.
+func (mpcObj *MPC) ComputeFourierSeriesForVec(a mpc_core.RVec, fc *FourierCoeffs) mpc_core.RVec {
+	rtype := a.Type().Zero()
 	fracBits := mpcObj.GetFracBits()
+	dataBits := mpcObj.GetDataBits()
+	N := fc.NumTerms()
+	n := len(a)
 
-	result := rtype.FromFloat64(a0(), fracBits)
-	for n := 1; n <= N; n++ {
-		anRElem := rtype.FromFloat64(an(n), fracBits)
-		bnRElem := rtype.FromFloat64(bn(n), fracBits)
-
-		anRElem = anRElem.Mul(cos_nx[n-1])
-		bnRElem = bnRElem.Mul(sin_nx[n-1])
+	// theta = 2*pi*(x - lo)/period - pi, in [-pi, pi) when x is in the period
+	period := fc.Hi - fc.Lo
+	theta := mpcObj.affineVec(a, 2*math.Pi/period, -2*math.Pi*fc.Lo/period-math.Pi)
 
-		result = result.Add(anRElem)
-		result = result.Add(bnRElem)
+	// Multiples n*theta for n = 1..N, element i in [i*N, (i+1)*N)
+	ntheta := mpc_core.InitRVec(rtype, n*N)
+	for i := range theta {
+		for k := 1; k <= N; k++ {
+			ntheta[i*N+k-1] = theta[i].Mul(rtype.FromInt(k))
+		}
 	}
-	return result
-}
-
-func (mpcObj *MPC) ComputeFourierSeriesForVec(a mpc_core.RVec, N int) []mpc_core.RElem {
-	// Initialize the output slice to store the results
-	resultVec := make([]mpc_core.RElem, len(a))
+	sin_nx, cos_nx := mpcObj.SSSinCosVec(ntheta)
 
-	// Iterate over each RElem in the input vector
-	for i, relem := range a {
-		// Compute sin_nx and cos_nx for this specific relem
-		sin_nx, cos_nx := mpcObj.PrecomputeMultiples(relem, N)
+	an := mpc_core.FloatToRVec(rtype, fc.A, fracBits)
+	bn := mpc_core.FloatToRVec(rtype, fc.B, fracBits)
+	a0 := rtype.FromFloat64(fc.A0, 2*fracBits)
 
-		// Compute the Fourier series for this relem using the computed sin_nx and cos_nx
-		resultVec[i] = mpcObj.FourierSeries(sin_nx, cos_nx, N)
+	resultVec := mpc_core.InitRVec(rtype, n)
+	for i := range resultVec {
+		resultVec[i] = mpcObj.FourierSeries(sin_nx[i*N:(i+1)*N], cos_nx[i*N:(i+1)*N], an, bn)
+		if mpcObj.GetPid() == 1 {
+			resultVec[i] = resultVec[i].Add(a0)
+		}
 	}
 
-	return resultVec
+	return mpcObj.TruncVec(resultVec, dataBits, fracBits)
 }
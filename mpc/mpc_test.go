@@ -0,0 +1,149 @@
+package mpc
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+
+	mpc_core "github.com/hhcho/mpc-core"
+)
+
+const (
+	testNumParties = 3
+	testDataBits   = 60
+)
+
+// runParties runs body at every party (party 0 is the dealer, party 1 the hub) over the inproc
+// transport. A panic at one party aborts the others, and each failure is reported on t
+func runParties(t *testing.T, rtype mpc_core.RElem, fracBits int, body func(mpcObj *MPC)) {
+	t.Helper()
+	tr, err := NewTransport(TransportInProc, "", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for pid := 0; pid < testNumParties; pid++ {
+		wg.Add(1)
+		go func(pid int) {
+			defer wg.Done()
+			nets, err := InitCommunication(tr, pid, testNumParties, 1, SharedKeyConfig{Exchange: true}, Timeouts{})
+			if err != nil {
+				t.Errorf("party %d: %v", pid, err)
+				return
+			}
+			defer func() {
+				if r := recover(); r != nil {
+					if _, ok := r.(*NetError); !ok {
+						nets[0].Abort(fmt.Errorf("panic: %v", r))
+					}
+					t.Errorf("party %d: %v", pid, r)
+				}
+				for _, n := range nets {
+					n.CloseAll()
+				}
+			}()
+
+			mpcObj := InitParallelMPCEnv(nets, rtype, testDataBits, fracBits)[0]
+			mpcObj.SetHubPid(1)
+			body(mpcObj)
+		}(pid)
+	}
+	wg.Wait()
+}
+
+// inputVec returns party 1's shares of x: x itself at party 1 and zeros elsewhere
+func inputVec(mpcObj *MPC, x []float64) mpc_core.RVec {
+	rtype := mpcObj.GetRType().Zero()
+	if mpcObj.GetPid() == 1 {
+		return mpc_core.FloatToRVec(rtype, x, mpcObj.GetFracBits())
+	}
+	return mpc_core.InitRVec(rtype, len(x))
+}
+
+// revealVec reveals shares at fracBits; only meaningful at parties other than 0
+func revealVec(mpcObj *MPC, a mpc_core.RVec) []float64 {
+	return mpcObj.RevealSymVec(a).ToFloat(mpcObj.GetFracBits())
+}
+
+// checkVec reports the entries of got that differ from want by more than tol
+func checkVec(t *testing.T, name string, x, got, want []float64, tol float64) {
+	t.Helper()
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > tol || math.IsNaN(got[i]) {
+			t.Errorf("%s(%g) = %g, want %g (tolerance %g)", name, x[i], got[i], want[i], tol)
+		}
+	}
+}
+
+// linspace returns n evenly spaced points from lo to hi
+func linspace(lo, hi float64, n int) []float64 {
+	x := make([]float64, n)
+	for i := range x {
+		x[i] = lo + (hi-lo)*float64(i)/float64(n-1)
+	}
+	return x
+}
+
+func TestPowers(t *testing.T) {
+	x := []int{0, 1, 2, 3, 7}
+	runParties(t, mpc_core.LElem128Zero, 20, func(mpcObj *MPC) {
+		rtype := mpcObj.GetRType().Zero()
+		a := mpc_core.InitRVec(rtype, len(x))
+		if mpcObj.GetPid() == 1 {
+			for i := range x {
+				a[i] = rtype.FromInt(x[i])
+			}
+		}
+
+		pow := mpcObj.RevealSymMat(mpcObj.Powers(a, 5)).ToInt()
+		if mpcObj.GetPid() != 1 {
+			return
+		}
+		for k := range pow {
+			for i := range x {
+				if want := int(math.Pow(float64(x[i]), float64(k))); pow[k][i] != want {
+					t.Errorf("Powers: %d^%d = %d, want %d", x[i], k, pow[k][i], want)
+				}
+			}
+		}
+	})
+}
+
+func TestEvaluatePolynomialVec(t *testing.T) {
+	coefficients := []float64{0.5, -1, 0.25, 0.125, -0.0625, 0.03125}
+	x := linspace(-2, 2, 9)
+	want := make([]float64, len(x))
+	for i := range x {
+		for k := len(coefficients) - 1; k >= 0; k-- {
+			want[i] = want[i]*x[i] + coefficients[k]
+		}
+	}
+
+	for _, method := range []PolyEvalMethod{PolyEvalPowers, PolyEvalHorner, PolyEvalPatersonStockmeyer} {
+		runParties(t, mpc_core.LElem256Zero, 20, func(mpcObj *MPC) {
+			got := revealVec(mpcObj, mpcObj.EvaluatePolynomialVec(coefficients, inputVec(mpcObj, x), method))
+			if mpcObj.GetPid() == 1 {
+				checkVec(t, fmt.Sprint("EvaluatePolynomialVec method ", method), x, got, want, 1e-4)
+			}
+		})
+	}
+}
+
+func TestEvaluatePolynomial(t *testing.T) {
+	// The constant term is included
+	coefficients := []float64{3, 2, 1}
+	x := []float64{0, 1, -1.5, 2.25}
+	want := make([]float64, len(x))
+	for i := range x {
+		want[i] = 3 + 2*x[i] + x[i]*x[i]
+	}
+
+	runParties(t, mpc_core.LElem256Zero, 20, func(mpcObj *MPC) {
+		res := mpcObj.RevealSymVec(mpcObj.EvaluatePolynomial(coefficients, inputVec(mpcObj, x)))
+		if mpcObj.GetPid() == 1 {
+			checkVec(t, "EvaluatePolynomial", x, res.ToFloat(2*mpcObj.GetFracBits()), want, 1e-4)
+		}
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"strconv"
 	"sync"
 	"time"
 
@@ -17,8 +18,6 @@ import (
 	"github.com/ldsec/lattigo/v2/ring"
 )
 
-const IntBatchSize = 512
-
 // Server holds IP/port info for InitCommunication
 type Server struct {
 	IpAddr string
@@ -44,17 +43,11 @@ type Network struct {
 	commSent, commReceived   map[int]int
 	loggingActive            bool
 
-	intBuf map[int][]uint64
-	intMu  map[int]*sync.Mutex
-
-	// in the type Network { … } block, alongside intBuf/intMu:
-	ctBuf    map[int][]*ckks.Ciphertext
-	ctMu     map[int]*sync.Mutex
-	ctThresh int
+	timeouts Timeouts
+	abort    *abortState // Shared by the threads of the party
 
-	sendChan     map[int]chan interface{} // one channel per peer
-	recvChan     map[int]chan interface{} // one channel per peer
-	cryptoParams *crypto.CryptoParams
+	sendSeq, recvSeq []uint32 // Sequence number of the next message to and from each peer
+	checksums        bool
 }
 
 var pipeRegistry = struct {
@@ -130,27 +123,177 @@ func (n *Network) UpdateReceiverLog(fromPid, nbytes int) {
 	}
 }
 
-// InitCommunication spins up one Network per thread
-func InitCommunication(bindingIP string, servers map[string]Server, pid, np, threads int, sharedKeysPath string) []*Network {
+// Transport names accepted by InitCommunication (config key "transport")
+const (
+	TransportTCP    = "tcp"    // One TCP connection per peer and thread, as described by [servers.partyN]
+	TransportInProc = "inproc" // In-memory net.Pipe connections for tests and single-host simulation
+)
+
+// Transport establishes the connections between this party and every peer for one thread
+// (or, with thread equal to the number of threads, the control channels), waiting up to
+// timeout for each peer (no limit if zero)
+type Transport interface {
+	ConnectPeers(pid, np, thread int, timeout time.Duration) (map[int]net.Conn, map[int]net.Listener, error)
+}
+
+// TCPTransport connects parties over TCP; the party with the smaller ID listens
+// on the port listed under its [servers.partyN] entry, offset by the thread index
+// (the control channel uses the port after those of the threads)
+type TCPTransport struct {
+	BindingIP string
+	Servers   map[string]Server
+
+	tls *tlsMaterial // nil for plaintext channels
+}
+
+// InProcTransport wires parties running in the same process through pipeRegistry
+type InProcTransport struct{}
+
+// NewTransport returns the transport selected by name; an empty name defaults to TCP.
+// A non-nil tlsConf enables mutually authenticated TLS on every TCP channel, and an error
+// is returned if its certificate, key or CA file cannot be loaded.
+func NewTransport(name, bindingIP string, servers map[string]Server, tlsConf *TLSConfig) (Transport, error) {
+	switch name {
+	case "", TransportTCP:
+		tr := &TCPTransport{BindingIP: bindingIP, Servers: servers}
+		if tlsConf != nil {
+			var err error
+			if tr.tls, err = tlsConf.load(); err != nil {
+				return nil, err
+			}
+		}
+		return tr, nil
+	case TransportInProc:
+		if tlsConf != nil {
+			return nil, fmt.Errorf("TLS is only supported with the tcp transport")
+		}
+		return &InProcTransport{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported transport: %q", name)
+	}
+}
+
+// InitCommunication spins up one Network per thread, and the control channels used to abort
+// all parties when one of them fails
+func InitCommunication(tr Transport, pid, np, threads int, keyConf SharedKeyConfig, timeouts Timeouts) ([]*Network, error) {
+	if keyConf.Exchange && !keyExchangeAuthenticated(tr) {
+		return nil, fmt.Errorf("shared_key_exchange = true requires use_tls = true: over plaintext channels " +
+			"a man in the middle could substitute its own keys")
+	}
+
 	nets := make([]*Network, threads)
+	errs := make([]error, threads)
 	var wg sync.WaitGroup
 	for t := 0; t < threads; t++ {
 		wg.Add(1)
 		go func(thread int) {
 			defer wg.Done()
-			nets[thread] = initNetworkForThread(bindingIP, servers, pid, np, thread)
-			fmt.Printf("Thread %d network init\n", thread)
+			nets[thread], errs[thread] = initNetworkForThread(tr, pid, np, thread, timeouts)
+			if errs[thread] == nil {
+				fmt.Printf("Thread %d network init\n", thread)
+			}
 		}(t)
 	}
 	wg.Wait()
+
+	var ctrl map[int]net.Conn
+	var ctrlListeners map[int]net.Listener
+	var err error
+	for _, e := range errs {
+		if e != nil {
+			err = e
+		}
+	}
+	if err == nil {
+		ctrl, ctrlListeners, err = tr.ConnectPeers(pid, np, threads, timeouts.Connect)
+	}
+	if err != nil {
+		for _, n := range nets {
+			if n != nil {
+				n.CloseAll()
+			}
+		}
+		return nil, err
+	}
+	closeAll(nil, ctrlListeners)
+	newAbortState(pid, nets, ctrl)
+
+	var keys *SharedKeys
+	if keyConf.Exchange {
+		keys = nets[0].ExchangeSharedKeys()
+		if keyConf.Persist {
+			SaveSharedKeys(pid, keys, keyConf.Path)
+		}
+	} else {
+		keys = LoadSharedKeys(pid, np, keyConf.Path)
+	}
+
 	for _, nn := range nets {
-		nn.Rand = InitializePRG(pid, np, sharedKeysPath)
+		nn.Rand = NewRandomFromKeys(pid, keys)
+	}
+	return nets, nil
+}
+
+// keyExchangeAuthenticated reports whether tr authenticates the peers, as ExchangeSharedKeys
+// requires: TCP only with TLS, in-process pipes always (nothing can intercept them)
+func keyExchangeAuthenticated(tr Transport) bool {
+	switch t := tr.(type) {
+	case *TCPTransport:
+		return t.tls != nil
+	case *InProcTransport:
+		return true
+	default:
+		return false
 	}
-	return nets
 }
 
-func initNetworkForThread(bindingIP string, servers map[string]Server, pid, np, thread int) *Network {
-	// We no longer need bindingIP or servers for in‑process pipes
+func (tr *TCPTransport) ConnectPeers(pid, np, thread int, timeout time.Duration) (map[int]net.Conn, map[int]net.Listener, error) {
+	conns := make(map[int]net.Conn)
+	listeners := make(map[int]net.Listener)
+
+	for other := 0; other < np; other++ {
+		if other == pid {
+			continue
+		}
+
+		var ip, port string
+		var err error
+		if other < pid { // Peer listens, we dial
+			if ip, port, err = tr.address(other, pid, thread); err == nil {
+				conns[other], err = Connect(ip, port, timeout)
+			}
+		} else { // We listen, peer dials
+			if _, port, err = tr.address(pid, other, thread); err == nil {
+				conns[other], listeners[other], err = OpenChannel(tr.BindingIP, port, timeout)
+			}
+		}
+
+		if err == nil && tr.tls != nil {
+			conns[other], err = tr.tls.secureConn(conns[other], pid, other, timeout)
+		}
+		if err != nil {
+			closeAll(conns, listeners)
+			return nil, nil, &NetError{Party: pid, Peer: other, Op: "connect to", Err: err}
+		}
+	}
+
+	return conns, listeners, nil
+}
+
+// address returns where server listens for peer on the given thread, from [servers.partyN]
+func (tr *TCPTransport) address(server, peer, thread int) (string, string, error) {
+	conf, ok := tr.Servers[pidString(server)]
+	if !ok {
+		return "", "", fmt.Errorf("servers.%s not found in config", pidString(server))
+	}
+	port, err := strconv.Atoi(conf.Ports[pidString(peer)])
+	if err != nil {
+		return "", "", fmt.Errorf("servers.%s.ports.%s: %w", pidString(server), pidString(peer), err)
+	}
+	return conf.IpAddr, strconv.Itoa(port + thread), nil
+}
+
+func (tr *InProcTransport) ConnectPeers(pid, np, thread int, timeout time.Duration) (map[int]net.Conn, map[int]net.Listener, error) {
 	conns := make(map[int]net.Conn)
 	listeners := make(map[int]net.Listener) // still required by the struct but unused
 
@@ -172,14 +315,23 @@ func initNetworkForThread(bindingIP string, servers map[string]Server, pid, np,
 			pipeRegistry.m[fmt.Sprintf("%d-%d-%d", other, pid, thread)] = c2
 			conn = c1
 		}
+		delete(pipeRegistry.m, key) // Each endpoint is used once, so that a later run gets new pipes
 		pipeRegistry.Unlock()
 
 		// Assign this party’s connection to "other"
 		conns[other] = conn
 	}
 
-	// Construct the Network object exactly as before:
-	netObj := &Network{
+	return conns, listeners, nil
+}
+
+func initNetworkForThread(tr Transport, pid, np, thread int, timeouts Timeouts) (*Network, error) {
+	conns, listeners, err := tr.ConnectPeers(pid, np, thread, timeouts.Connect)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Network{
 		pid:           pid,
 		hubPid:        1,
 		NumParties:    np,
@@ -191,295 +343,133 @@ func initNetworkForThread(bindingIP string, servers map[string]Server, pid, np,
 		commSent:      make(map[int]int),
 		commReceived:  make(map[int]int),
 		loggingActive: true,
-		intBuf:        make(map[int][]uint64, np),
-		intMu:         make(map[int]*sync.Mutex, np),
-	}
-
-	netObj.ctThresh = 128
-	netObj.ctBuf = make(map[int][]*ckks.Ciphertext, np)
-	netObj.ctMu = make(map[int]*sync.Mutex, np)
+		timeouts:      timeouts,
+		sendSeq:       make([]uint32, np),
+		recvSeq:       make([]uint32, np),
+	}, nil
+}
 
-	netObj.sendChan = make(map[int]chan interface{}, np)
-	netObj.recvChan = make(map[int]chan interface{}, np)
-	for i := 0; i < np; i++ {
-		if i == pid {
-			continue
+// write sends bufs to a peer as one message, aborting the party if it fails
+func (n *Network) write(to int, bufs ...[]byte) {
+	conn := n.conns[to]
+	if n.timeouts.Send > 0 {
+		conn.SetWriteDeadline(time.Now().Add(n.timeouts.Send))
+	}
+	for _, buf := range bufs {
+		if err := WriteFull(&conn, buf); err != nil {
+			n.fail("send to", to, err)
 		}
-		netObj.sendChan[i] = make(chan interface{}, 256) // buffered channel
-		netObj.recvChan[i] = make(chan interface{}, 256)
-
-		// sender loop
-		go func(to int) {
-			for msg := range netObj.sendChan[to] {
-				switch m := msg.(type) {
-				case *ckks.Ciphertext:
-					netObj.SendCiphertext(m, to)
-				case int:
-					netObj.SendInt(m, to)
-				// add other cases (RData, Poly, etc.) as needed
-				default:
-					panic("unsupported message type")
-				}
-			}
-		}(i)
-
-		// receiver loop
-		go func(from int) {
-			for {
-				// here you must know what to expect; e.g., loop:
-				ct := netObj.ReceiveCiphertextBatch(netObj.cryptoParams, from)
-				netObj.recvChan[from] <- ct
-				// repeat or break based on protocol phases
-			}
-		}(i)
 	}
+}
 
-	for i := 0; i < np; i++ {
-		netObj.ctBuf[i] = make([]*ckks.Ciphertext, 0, netObj.ctThresh)
-		netObj.ctMu[i] = &sync.Mutex{}
+// read fills buf from a peer, aborting the party if it fails
+func (n *Network) read(from int, buf []byte) {
+	conn := n.conns[from]
+	if n.timeouts.Receive > 0 {
+		conn.SetReadDeadline(time.Now().Add(n.timeouts.Receive))
 	}
-
-	// Initialize the int‑buffer and mutex for each peer
-	for i := 0; i < np; i++ {
-		netObj.intBuf[i] = make([]uint64, 0, IntBatchSize)
-		netObj.intMu[i] = &sync.Mutex{}
+	if err := ReadFull(&conn, buf); err != nil {
+		n.fail("receive from", from, err)
 	}
-
-	return netObj
 }
 
-// --- Buffered Ints ---
+// --- Ints ---
 
 func (n *Network) SendInt(val, to int) {
-	n.intMu[to].Lock()
-	defer n.intMu[to].Unlock()
-	n.intBuf[to] = append(n.intBuf[to], uint64(val))
-	if len(n.intBuf[to]) >= IntBatchSize {
-		n.flushIntBuf(to)
-	}
-}
-
-func (n *Network) flushIntBuf(to int) {
-	buf := n.intBuf[to]
-	if len(buf) == 0 {
-		return
-	}
-	n.SendIntVector(buf, to)
-	n.intBuf[to] = buf[:0]
-}
-
-func (n *Network) FlushAllInts() {
-	for to := range n.conns {
-		n.intMu[to].Lock()
-		n.flushIntBuf(to)
-		n.intMu[to].Unlock()
-	}
+	n.SendIntVector([]uint64{uint64(val)}, to)
 }
 
 func (n *Network) SendIntVector(v []uint64, to int) {
-	conn := n.conns[to]
 	b := make([]byte, 8*len(v))
 	for i, x := range v {
 		binary.LittleEndian.PutUint64(b[i*8:(i*8)+8], x)
 	}
-	WriteFull(&conn, b)
-	n.UpdateSenderLog(to, len(b))
+	n.sendFrame(to, msgInts, len(v), 0, b)
 }
 
 func (n *Network) ReceiveInt(from int) int {
-	conn := n.conns[from]
-	buf := make([]byte, 8)
-	ReadFull(&conn, buf)
-	n.UpdateReceiverLog(from, 8)
-	return int(binary.LittleEndian.Uint64(buf))
+	return int(n.ReceiveIntVector(1, from)[0])
 }
 
 func (n *Network) ReceiveIntVector(nElem, from int) []uint64 {
-	conn := n.conns[from]
-	data := make([]byte, nElem*8)
-	ReadFull(&conn, data)
+	data := n.receiveFrame(from, msgInts, nElem, 0, 8*uint64(nElem))
+	if len(data) != 8*nElem {
+		n.malformed(from, msgInts)
+	}
 	out := make([]uint64, nElem)
 	for i := range out {
 		out[i] = binary.LittleEndian.Uint64(data[i*8 : i*8+8])
 	}
-	n.UpdateReceiverLog(from, len(data))
 	return out
 }
 
 // ReceiveRVec reads an RVec of length `length` from peer `from`
 func (n *Network) ReceiveRVec(rtype mpc_core.RElem, length, from int) mpc_core.RVec {
-	conn := n.conns[from]
-	hdr := make([]byte, 4)
-	ReadFull(&conn, hdr)
-	sz := binary.LittleEndian.Uint32(hdr)
-	buf := make([]byte, sz)
-	ReadFull(&conn, buf)
+	buf := n.receiveFrame(from, msgRVec, length, 0, uint64(length)*uint64(rtype.NumBytes()))
+	if len(buf) != length*int(rtype.NumBytes()) {
+		n.malformed(from, msgRVec)
+	}
 	vec := mpc_core.InitRVec(rtype.Zero(), length)
 	vec.UnmarshalBinary(buf)
-	n.UpdateReceiverLog(from, 4+int(sz))
 	return vec
 }
 
 // --- Polynomials ---
 
 func (n *Network) SendPoly(poly *ring.Poly, to int) {
-	conn := n.conns[to]
 	data, _ := poly.MarshalBinary()
-	hdr := make([]byte, 4)
-	binary.LittleEndian.PutUint32(hdr, uint32(len(data)))
-	WriteFull(&conn, hdr)
-	WriteFull(&conn, data)
-	n.UpdateSenderLog(to, len(hdr)+len(data))
+	n.sendFrame(to, msgPoly, 0, 0, data)
 }
 
 func (n *Network) ReceivePoly(from int) *ring.Poly {
-	conn := n.conns[from]
-	hdr := make([]byte, 4)
-	ReadFull(&conn, hdr)
-	size := binary.LittleEndian.Uint32(hdr)
-	data := make([]byte, size)
-	ReadFull(&conn, data)
+	data := n.receiveFrame(from, msgPoly, 0, 0, n.polyMaxLen())
 	poly := new(ring.Poly)
 	poly.UnmarshalBinary(data)
-	n.UpdateReceiverLog(from, len(hdr)+int(size))
 	return poly
 }
 
 func (n *Network) SendPolyMat(mat [][]ring.Poly, to int) {
-	conn := n.conns[to]
 	sizes, data := MarshalPolyMat(mat)
-	hdr := make([]byte, 8)
-	binary.LittleEndian.PutUint64(hdr, uint64(len(sizes)))
-	WriteFull(&conn, hdr)
-	WriteFull(&conn, sizes)
-	binary.LittleEndian.PutUint64(hdr, uint64(len(data)))
-	WriteFull(&conn, hdr)
-	WriteFull(&conn, data)
-	n.UpdateSenderLog(to, 2*len(hdr)+len(sizes)+len(data))
+	cols := 0
+	if len(mat) > 0 {
+		cols = len(mat[0])
+	}
+	n.sendFrame(to, msgPolyMat, len(mat), cols, lengthPrefix(sizes), sizes, data)
 }
 
 func (n *Network) ReceivePolyMat(from int) [][]ring.Poly {
-	conn := n.conns[from]
-	hdr := make([]byte, 8)
-	ReadFull(&conn, hdr)
-	sz := binary.LittleEndian.Uint64(hdr)
-	sizes := make([]byte, sz)
-	ReadFull(&conn, sizes)
-	ReadFull(&conn, hdr)
-	dsz := binary.LittleEndian.Uint64(hdr)
-	data := make([]byte, dsz)
-	ReadFull(&conn, data)
-	n.UpdateReceiverLog(from, 2*len(hdr)+int(sz)+int(dsz))
+	payload := n.receiveFrame(from, msgPolyMat, anyDim, anyDim, maxFrameLen)
+	sizes, data := n.splitPayload(from, msgPolyMat, payload)
 	return UnmarshalPolyMat(sizes, data)
 }
 
-// --- Ciphertexts ---
-
-// SendCiphertext buffers ct; flushes once buffer reaches threshold.
-func (n *Network) SendCiphertext(ct *ckks.Ciphertext, to int) {
-	n.ctMu[to].Lock()
-	defer n.ctMu[to].Unlock()
-
-	n.ctBuf[to] = append(n.ctBuf[to], ct)
-	if len(n.ctBuf[to]) >= n.ctThresh {
-		n.flushCiphertexts(to)
+// polyMaxLen bounds the size of a marshaled polynomial of the ring QP
+func (n *Network) polyMaxLen() uint64 {
+	if n.dckksContext == nil {
+		return maxFrameLen
 	}
+	r := n.dckksContext.RingQP
+	return 2 + 8*uint64(r.N)*uint64(len(r.Modulus))
 }
 
-// flushCiphertexts marshals all buffered cts for peer 'to' and sends in one write.
-func (n *Network) flushCiphertexts(to int) {
-	bufList := n.ctBuf[to]
-	if len(bufList) == 0 {
-		return
-	}
-
-	// 1) count header
-	count := uint32(len(bufList))
-	header := make([]byte, 4)
-	binary.LittleEndian.PutUint32(header, count)
-
-	// 2) marshal each ciphertext: [4‑byte len][bytes]…
-	var payload []byte
-	for _, ct := range bufList {
-		ctBytes, _ := ct.MarshalBinary()
-		tmp := make([]byte, 4+len(ctBytes))
-		binary.LittleEndian.PutUint32(tmp[:4], uint32(len(ctBytes)))
-		copy(tmp[4:], ctBytes)
-		payload = append(payload, tmp...)
-	}
-
-	// 3) write header + payload in one go
-	conn := n.conns[to]
-	WriteFull(&conn, header)
-	WriteFull(&conn, payload)
-	n.UpdateSenderLog(to, len(header)+len(payload))
-
-	// 4) reset buffer
-	n.ctBuf[to] = bufList[:0]
-}
+// --- Ciphertexts ---
 
-// FlushAllCiphertexts forces all leftover buffered cts out on the wire.
-func (n *Network) FlushAllCiphertexts() {
-	for to := range n.ctBuf {
-		n.ctMu[to].Lock()
-		n.flushCiphertexts(to)
-		n.ctMu[to].Unlock()
-	}
+// ctMaxLen bounds the size of a marshaled ciphertext of degree at most 2: the metadata, then
+// one polynomial of the ring Q per degree
+func ctMaxLen(params *ckks.Parameters) uint64 {
+	return 11 + 3*(2+8*uint64(params.N())*uint64(params.QiCount()))
 }
 
-// ReceiveCiphertextBatch reads exactly one batch from 'from' and returns the slice.
-func (n *Network) ReceiveCiphertextBatch(params *crypto.CryptoParams, from int) []*ckks.Ciphertext {
-	conn := n.conns[from]
-
-	// 1) read the count
-	hdr := make([]byte, 4)
-	ReadFull(&conn, hdr)
-	count := int(binary.LittleEndian.Uint32(hdr))
-
-	// 2) for each, read len + payload, unmarshal
-	out := make([]*ckks.Ciphertext, count)
-	for i := 0; i < count; i++ {
-		// read the length
-		ReadFull(&conn, hdr)
-		ctLen := binary.LittleEndian.Uint32(hdr)
-
-		// read the ciphertext bytes
-		ctBytes := make([]byte, ctLen)
-		ReadFull(&conn, ctBytes)
-
-		// unmarshal
-		ct := ckks.NewCiphertext(params.Params, 1, params.Params.MaxLevel(), params.Params.Scale())
-		if err := ct.UnmarshalBinary(ctBytes); err != nil {
-			panic(err)
-		}
-		out[i] = ct
+func (n *Network) SendCiphertext(ct *ckks.Ciphertext, to int) {
+	data, err := ct.MarshalBinary()
+	if err != nil {
+		panic(err)
 	}
-
-	// // 3) logging
-	// totalBytes := 4 + count*(4) // 4 for count + 4 per-length header
-	// for _, ct := range out {
-	// 	totalBytes += int(ct.MarshalBinarySize()) // or track len(ctBytes)
-	// }
-	// n.UpdateReceiverLog(from, totalBytes)
-
-	return out
+	n.sendFrame(to, msgCiphertext, 0, 0, data)
 }
 
 func (n *Network) ReceiveCiphertext(params *crypto.CryptoParams, from int) *ckks.Ciphertext {
-	conn := n.conns[from]
-
-	// read length header
-	hdr := make([]byte, 8)
-	ReadFull(&conn, hdr)
-	sz := binary.LittleEndian.Uint64(hdr)
-
-	// read body
-	data := make([]byte, sz)
-	ReadFull(&conn, data)
-
-	n.UpdateReceiverLog(from, 8+int(sz))
-
-	// unmarshal
+	data := n.receiveFrame(from, msgCiphertext, 0, 0, ctMaxLen(params.Params))
 	ct := ckks.NewCiphertext(params.Params, 1, params.Params.MaxLevel(), params.Params.Scale())
 	if err := ct.UnmarshalBinary(data); err != nil {
 		panic(err)
@@ -488,143 +478,140 @@ func (n *Network) ReceiveCiphertext(params *crypto.CryptoParams, from int) *ckks
 }
 
 func (n *Network) SendCipherMatrix(cm crypto.CipherMatrix, to int) {
-	conn := n.conns[to]
 	sbytes, cmbytes := crypto.MarshalCM(cm)
-	hdr := make([]byte, 8)
-	binary.LittleEndian.PutUint64(hdr, uint64(len(sbytes)))
-	WriteFull(&conn, hdr)
-	WriteFull(&conn, sbytes)
-	binary.LittleEndian.PutUint64(hdr, uint64(len(cmbytes)))
-	WriteFull(&conn, hdr)
-	WriteFull(&conn, cmbytes)
-	n.UpdateSenderLog(to, 2*len(hdr)+len(sbytes)+len(cmbytes))
+	n.sendFrame(to, msgCipherMatrix, len(cm), len(cm[0]), lengthPrefix(sbytes), sbytes, cmbytes)
 }
 
 func (n *Network) ReceiveCipherMatrix(params *crypto.CryptoParams, nv, nct, from int) crypto.CipherMatrix {
-	cm := make(crypto.CipherMatrix, nv)
-	for i := 0; i < nv; i++ {
-		cm[i] = n.ReceiveCipherVector(params, nct, from)
-	}
-	return cm
+	payload := n.receiveFrame(from, msgCipherMatrix, nv, nct, 8+uint64(nv*nct)*(8+ctMaxLen(params.Params)))
+	sbytes, cmbytes := n.splitPayload(from, msgCipherMatrix, payload)
+	return crypto.UnmarshalCM(params, nv, nct, sbytes, cmbytes)
 }
 
 func (n *Network) SendCipherVector(cv crypto.CipherVector, to int) {
-	conn := n.conns[to]
 	sbytes, cvbytes := MarshalCV(cv)
-	hdr := make([]byte, 8)
-	binary.LittleEndian.PutUint64(hdr, uint64(len(sbytes)))
-	WriteFull(&conn, hdr)
-	WriteFull(&conn, sbytes)
-	binary.LittleEndian.PutUint64(hdr, uint64(len(cvbytes)))
-	WriteFull(&conn, hdr)
-	WriteFull(&conn, cvbytes)
-	n.UpdateSenderLog(to, 2*len(hdr)+len(sbytes)+len(cvbytes))
+	n.sendFrame(to, msgCipherVector, len(cv), 0, lengthPrefix(sbytes), sbytes, cvbytes)
 }
 
 func (n *Network) ReceiveCipherVector(params *crypto.CryptoParams, nct, from int) crypto.CipherVector {
-	conn := n.conns[from]
-	hdr := make([]byte, 8)
-	ReadFull(&conn, hdr)
-	ssz := binary.LittleEndian.Uint64(hdr)
-	sdata := make([]byte, ssz)
-	ReadFull(&conn, sdata)
-	ReadFull(&conn, hdr)
-	csz := binary.LittleEndian.Uint64(hdr)
-	cdata := make([]byte, csz)
-	ReadFull(&conn, cdata)
-	n.UpdateReceiverLog(from, 2*len(hdr)+int(ssz)+int(csz))
-	return UnmarshalCV(params, nct, sdata, cdata)
+	payload := n.receiveFrame(from, msgCipherVector, nct, 0, 8+uint64(nct)*(8+ctMaxLen(params.Params)))
+	sbytes, cvbytes := n.splitPayload(from, msgCipherVector, payload)
+	return UnmarshalCV(params, nct, sbytes, cvbytes)
 }
 
 // SendRData / ReceiveRMat / ReceiveRElem
 
 func (n *Network) SendRData(data interface{}, to int) {
-	conn := n.conns[to]
 	buf := MarshalRData(data)
-	var hdr []byte
-	if _, isElem := data.(mpc_core.RElem); !isElem {
-		hdr = make([]byte, 4)
-		binary.LittleEndian.PutUint32(hdr, uint32(len(buf)))
-		WriteFull(&conn, hdr)
+	switch t := data.(type) {
+	case mpc_core.RElem:
+		n.sendFrame(to, msgRElem, 0, 0, buf)
+	case mpc_core.RVec:
+		n.sendFrame(to, msgRVec, len(t), 0, buf)
+	case mpc_core.RMat:
+		cols := 0
+		if len(t) > 0 {
+			cols = len(t[0])
+		}
+		n.sendFrame(to, msgRMat, len(t), cols, buf)
+	default:
+		panic(fmt.Sprintf("SendRData: unsupported type %T", data))
 	}
-	WriteFull(&conn, buf)
-	n.UpdateSenderLog(to, len(hdr)+len(buf))
 }
 
 func (n *Network) ReceiveRMat(rtype mpc_core.RElem, nrows, ncols, from int) mpc_core.RMat {
-	conn := n.conns[from]
-	hdr := make([]byte, 4)
-	ReadFull(&conn, hdr)
-	sz := binary.LittleEndian.Uint32(hdr)
-	data := make([]byte, sz)
-	ReadFull(&conn, data)
+	data := n.receiveFrame(from, msgRMat, nrows, ncols, uint64(nrows*ncols)*uint64(rtype.NumBytes()))
+	if len(data) != nrows*ncols*int(rtype.NumBytes()) {
+		n.malformed(from, msgRMat)
+	}
 	mat := mpc_core.InitRMat(rtype.Zero(), nrows, ncols)
 	mat.UnmarshalBinary(data)
-	n.UpdateReceiverLog(from, 4+int(sz))
 	return mat
 }
 
 func (n *Network) ReceiveRElem(rtype mpc_core.RElem, from int) mpc_core.RElem {
-	conn := n.conns[from]
-	buf := make([]byte, rtype.NumBytes())
-	ReadFull(&conn, buf)
-	n.UpdateReceiverLog(from, len(buf))
+	buf := n.receiveFrame(from, msgRElem, 0, 0, uint64(rtype.NumBytes()))
+	if len(buf) != int(rtype.NumBytes()) {
+		n.malformed(from, msgRElem)
+	}
 	return rtype.FromBytes(buf)
 }
 
 // Utility read/write
 
-func WriteFull(conn *net.Conn, buf []byte) {
+func WriteFull(conn *net.Conn, buf []byte) error {
 	offs, rem := 0, len(buf)
 	for rem > 0 {
 		w, err := (*conn).Write(buf[offs:])
 		if err != nil {
-			panic(err)
+			return err
 		}
 		offs += w
 		rem -= w
 	}
+	return nil
 }
 
-func ReadFull(conn *net.Conn, buf []byte) {
+func ReadFull(conn *net.Conn, buf []byte) error {
 	offs, rem := 0, len(buf)
 	for rem > 0 {
 		r, err := (*conn).Read(buf[offs:])
 		if err != nil {
-			panic(err)
+			return err
 		}
 		offs += r
 		rem -= r
 	}
+	return nil
 }
 
 // Connection helpers
 
-func OpenChannel(ip, port string) (net.Conn, net.Listener) {
+// OpenChannel listens on ip:port and accepts one connection within timeout (no limit if zero)
+func OpenChannel(ip, port string, timeout time.Duration) (net.Conn, net.Listener, error) {
 	addr := ip + ":" + port
 	l, err := net.Listen("tcp", addr)
 	if err != nil {
-		panic(err)
+		return nil, nil, err
+	}
+	if timeout > 0 {
+		l.(*net.TCPListener).SetDeadline(time.Now().Add(timeout))
 	}
 	c, err := l.Accept()
 	if err != nil {
-		panic(err)
+		l.Close()
+		return nil, nil, fmt.Errorf("no connection on %s: %w", addr, err)
 	}
-	return c, l
+	return c, l, nil
 }
 
-func Connect(ip, port string) net.Conn {
+// Connect dials ip:port until it succeeds or timeout has passed (no limit if zero)
+func Connect(ip, port string, timeout time.Duration) (net.Conn, error) {
 	addr := ip + ":" + port
-	var c net.Conn
-	var err error
-	for i := 0; i < 5; i++ {
-		c, err = net.Dial("tcp", addr)
+	deadline := time.Now().Add(timeout)
+	for {
+		c, err := net.Dial("tcp", addr)
 		if err == nil {
-			return c
+			return c, nil
+		}
+		if timeout > 0 && time.Now().Add(time.Second).After(deadline) {
+			return nil, fmt.Errorf("failed to connect to %s: %w", addr, err)
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+func closeAll(conns map[int]net.Conn, listeners map[int]net.Listener) {
+	for _, c := range conns {
+		if c != nil {
+			c.Close()
+		}
+	}
+	for _, l := range listeners {
+		if l != nil {
+			l.Close()
 		}
-		time.Sleep(5 * time.Second)
 	}
-	panic(fmt.Sprintf("failed to connect to %s", addr))
 }
 
 func SaveBytesToFile(b []byte, filename string) {
@@ -637,15 +624,19 @@ func SaveBytesToFile(b []byte, filename string) {
 	f.Sync()
 }
 
+// CloseAll closes the channels of this thread at the end of the protocol, and the control
+// channels of the party
 func (n *Network) CloseAll() {
-	for _, c := range n.conns {
-		c.Close()
-	}
-	for _, l := range n.listeners {
-		l.Close()
+	n.closeConns()
+	if n.abort != nil {
+		n.abort.closeControl()
 	}
 }
 
+func (n *Network) closeConns() {
+	closeAll(n.conns, n.listeners)
+}
+
 func (n *Network) GetConn(to int, threadNum int) net.Conn { return n.conns[to] }
 func (n *Network) SetPid(p int)                           { n.pid = p }
 func (n *Network) GetPid() int                            { return n.pid }
@@ -1,6 +1,7 @@
 package mpc
 
 import (
+	"crypto/sha256"
 	"fmt"
 	"os"
 	"path"
@@ -30,27 +31,35 @@ func sortInt(a, b int) (int, int) {
 }
 
 func InitializePRG(pid int, NumParties int, sharedKeysPath string) *Random {
-	/* TODO:
-	 * Currently samples shared secrets from
-	 * a deterministic stream. Need a key exchange
-	 * protocol in practice.
-	 */
-	prgTable := make(map[int]*frand.RNG)
+	return NewRandomFromKeys(pid, LoadSharedKeys(pid, NumParties, sharedKeysPath))
+}
 
+// SharedKeys holds the seeds of the globally shared PRG and of the
+// pairwise-shared PRGs between this party and every other party
+type SharedKeys struct {
+	Global   []byte
+	Pairwise map[int][]byte
+}
+
+// LoadSharedKeys reads shared_key_global.bin and shared_key_<a>_<b>.bin from sharedKeysPath
+func LoadSharedKeys(pid int, NumParties int, sharedKeysPath string) *SharedKeys {
 	if sharedKeysPath == "" {
-		log.LLvl1("Warning: shared_keys_path not set in config. Falling back on deterministic keys (not secure).")
+		log.LLvl1("Warning: shared_keys_path not set in config and key exchange disabled. Falling back on deterministic keys (not secure).")
+	}
+
+	keys := &SharedKeys{
+		Global:   make([]byte, chacha.KeySize),
+		Pairwise: make(map[int][]byte),
 	}
 
 	// Globally shared PRG
-	seed := make([]byte, chacha.KeySize)
 	if sharedKeysPath != "" {
 		key, err := os.ReadFile(path.Join(sharedKeysPath, "shared_key_global.bin"))
 		if err != nil {
 			panic(err)
 		}
-		copy(seed, key)
+		copy(keys.Global, key)
 	}
-	prgTable[-1] = frand.NewCustom(seed, bufferSize, 20)
 
 	// Pairwise-shared PRG
 	for i := 0; i < NumParties; i++ {
@@ -58,6 +67,7 @@ func InitializePRG(pid int, NumParties int, sharedKeysPath string) *Random {
 			continue
 		}
 
+		seed := make([]byte, chacha.KeySize)
 		a, b := sortInt(pid, i)
 		if sharedKeysPath == "" { // Temporary, insecure way of generating a shared seed
 			seed[0] = byte(a)
@@ -70,10 +80,42 @@ func InitializePRG(pid int, NumParties int, sharedKeysPath string) *Random {
 			copy(seed, key)
 		}
 
-		prgTable[i] = frand.NewCustom(seed, bufferSize, 20)
+		keys.Pairwise[i] = seed
+	}
+
+	return keys
+}
+
+// SaveSharedKeys writes keys to sharedKeysPath using the file names read by LoadSharedKeys
+func SaveSharedKeys(pid int, keys *SharedKeys, sharedKeysPath string) {
+	if err := os.MkdirAll(sharedKeysPath, 0700); err != nil {
+		panic(err)
+	}
+
+	if err := os.WriteFile(path.Join(sharedKeysPath, "shared_key_global.bin"), keys.Global, 0600); err != nil {
+		panic(err)
+	}
+
+	for other, key := range keys.Pairwise {
+		a, b := sortInt(pid, other)
+		filename := path.Join(sharedKeysPath, fmt.Sprintf("shared_key_%d_%d.bin", a, b))
+		if err := os.WriteFile(filename, key, 0600); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// NewRandomFromKeys sets up the global, pairwise and local PRGs from the given shared keys
+func NewRandomFromKeys(pid int, keys *SharedKeys) *Random {
+	prgTable := make(map[int]*frand.RNG)
+
+	prgTable[GlobalPRG] = frand.NewCustom(keys.Global, bufferSize, 20)
+	for other, key := range keys.Pairwise {
+		prgTable[other] = frand.NewCustom(key, bufferSize, 20)
 	}
 
 	// Local PRG
+	seed := make([]byte, chacha.KeySize)
 	frand.Read(seed) // Random seed
 	prgTable[pid] = frand.NewCustom(seed, bufferSize, 20)
 	curPRG := prgTable[pid]
@@ -104,6 +146,43 @@ func (rand *Random) ImportPRG(buf []byte, prgId int) {
 	rand.prgTable[prgId] = frand.Unmarshal(buf, bufferSize)
 }
 
+// ExportSharedPRGs returns the states of the global and pairwise-shared PRGs, keyed by PRG id
+func (rand *Random) ExportSharedPRGs() map[int][]byte {
+	states := make(map[int][]byte)
+	for id := range rand.prgTable {
+		if id != rand.pid {
+			rand.SwitchPRG(id)
+			states[id] = rand.ExportPRG()
+		}
+	}
+	rand.RestorePRG()
+	return states
+}
+
+// ImportSharedPRGs restores the states returned by ExportSharedPRGs
+func (rand *Random) ImportSharedPRGs(states map[int][]byte) {
+	for id, buf := range states {
+		rand.ImportPRG(buf, id)
+	}
+	rand.RestorePRG()
+}
+
+// ReseedSharedPRGs replaces each shared PRG with one seeded by salt and the next bytes of its
+// stream. With the same salt at all parties, the PRGs stay in sync but do not repeat the
+// output of an earlier run from the same state
+func (rand *Random) ReseedSharedPRGs(salt []byte) {
+	for id, prg := range rand.prgTable {
+		if id == rand.pid {
+			continue
+		}
+		buf := make([]byte, chacha.KeySize)
+		prg.Read(buf)
+		seed := sha256.Sum256(append(append([]byte{}, salt...), buf...))
+		rand.prgTable[id] = frand.NewCustom(seed[:], bufferSize, 20)
+	}
+	rand.RestorePRG()
+}
+
 func (rand *Random) RandRead(buf []byte) {
 	rand.curPRG.Read(buf)
 }
@@ -0,0 +1,66 @@
+package mpc
+
+import (
+	"bytes"
+	"testing"
+)
+
+func testRandom(pid int) *Random {
+	return NewRandomFromKeys(pid, &SharedKeys{
+		Global:   bytes.Repeat([]byte{1}, 32),
+		Pairwise: map[int][]byte{0: bytes.Repeat([]byte{2}, 32), 2: bytes.Repeat([]byte{3}, 32)},
+	})
+}
+
+// streams draws from each shared PRG of rand
+func streams(rand *Random) [][]byte {
+	var out [][]byte
+	for _, id := range []int{GlobalPRG, 0, 2} {
+		buf := make([]byte, 100)
+		rand.SwitchPRG(id)
+		rand.RandRead(buf)
+		out = append(out, buf)
+	}
+	rand.RestorePRG()
+	return out
+}
+
+// The states saved with a checkpoint reproduce the shared random streams that followed them
+func TestSharedPRGRoundTrip(t *testing.T) {
+	rand := testRandom(1)
+	streams(rand) // Some earlier step
+	states := rand.ExportSharedPRGs()
+	want := streams(rand)
+
+	restored := testRandom(1)
+	restored.ImportSharedPRGs(states)
+	if got := streams(restored); !bytes.Equal(bytes.Join(got, nil), bytes.Join(want, nil)) {
+		t.Error("the imported PRGs do not reproduce the streams after the export")
+	}
+	if restored.CurPRG() != restored.prgTable[1] {
+		t.Error("ImportSharedPRGs left a shared PRG selected")
+	}
+
+	// Reseeding with the same salt keeps copies in step, but leaves the saved streams
+	a, b := testRandom(1), testRandom(1)
+	a.ImportSharedPRGs(states)
+	b.ImportSharedPRGs(states)
+	a.ReseedSharedPRGs([]byte("nonce"))
+	b.ReseedSharedPRGs([]byte("nonce"))
+	got := streams(a)
+	if !bytes.Equal(bytes.Join(got, nil), bytes.Join(streams(b), nil)) {
+		t.Error("PRGs reseeded with the same salt differ")
+	}
+	for i := range got {
+		if bytes.Equal(got[i], want[i]) {
+			t.Errorf("shared PRG %d repeats its saved stream after reseeding", i)
+		}
+	}
+
+	c := testRandom(1)
+	c.ImportSharedPRGs(states)
+	c.ReseedSharedPRGs([]byte("other"))
+	if bytes.Equal(bytes.Join(streams(c), nil), bytes.Join(got, nil)) {
+		t.Error("PRGs reseeded with different salts agree")
+	}
+}
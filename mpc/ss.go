@@ -2,7 +2,9 @@ package mpc
 
 import (
 	"fmt"
+	"math"
 	"math/big"
+	"sync"
 	"time"
 
 	mpc_core "github.com/hhcho/mpc-core"
@@ -26,15 +28,76 @@ func (mpcObj *MPC) SSTrigElem(a mpc_core.RElem) (mpc_core.RElem, mpc_core.RElem)
 	return sin, cos
 }
 
+// Inputs of SSSigmoidVec are clamped to [-sigmoidBound, sigmoidBound], where the sigmoid
+// is within 1e-5 of 0 or 1, and its minimax approximation on [0, sigmoidBound] is used
+const (
+	sigmoidBound        = 12
+	sigmoidApproxDegree = 15
+)
+
+var sigmoidApprox struct {
+	once sync.Once
+	ap   *Approximator
+}
+
+// SSSigmoidVec returns shares (at fracBits) of 1 / (1 + exp(-a[i])) for the whole vector.
+// Unlike the original version, which reconstructed the result, the output stays secret
+// shared. Inputs are clamped to [-sigmoidBound, sigmoidBound] and the symmetry
+// sigmoid(-x) = 1 - sigmoid(x) reduces them to [0, sigmoidBound], where a bounded minimax
+// polynomial is evaluated; the absolute error is below 1e-5 plus fixed-point rounding.
 func (mpcObj *MPC) SSSigmoidVec(a mpc_core.RVec) mpc_core.RVec {
-	ar, am := mpcObj.BeaverPartitionVec(a)
-	top := mpc_core.InitRVec(mpcObj.rtype.Zero(), len(a))
-	bottom := mpc_core.InitRVec(mpcObj.rtype.Zero(), len(a))
-	for i := range top {
-		top[i], bottom[i] = mpcObj.BeaverSigmoid(ar[i], am[i])
+	sigmoidApprox.once.Do(func() {
+		sigmoid := func(x float64) float64 { return 1 / (1 + math.Exp(-x)) }
+		sigmoidApprox.ap = NewApproximator(sigmoid, 0, sigmoidBound, ApproxRemez, sigmoidApproxDegree)
+	})
+
+	rtype := a.Type().Zero()
+	fracBits := mpcObj.GetFracBits()
+	pid := mpcObj.GetPid()
+	n := len(a)
+
+	bound := mpc_core.InitRVec(rtype, n)
+	if pid == 1 {
+		bound = mpc_core.InitRVec(rtype.FromFloat64(sigmoidBound, fracBits), n)
+	}
+	negBound := bound.Copy()
+	negBound.MulScalar(rtype.One().Neg())
+
+	// hi = [bound < a], lo = [a < -bound], neg = [a < 0] in one batch
+	lhs := append(append(bound.Copy(), a...), a...)
+	rhs := append(append(a.Copy(), negBound...), mpc_core.InitRVec(rtype, n)...)
+	bits := mpcObj.LessThan(lhs, rhs, mpcObj.GetBooleanShareFlag())
+
+	// hi * (bound - a), lo * (-bound - a) and neg * a in one batch
+	toHi, toLo := bound.Copy(), negBound.Copy()
+	toHi.Sub(a)
+	toLo.Sub(a)
+	prod := mpcObj.SSMultElemVec(bits, append(append(toHi, toLo...), a...))
+
+	// x = clamp(a), and neg * x = neg * a + lo * (-bound - a) since lo implies neg
+	x := a.Copy()
+	x.Add(prod[:n])
+	x.Add(prod[n : 2*n])
+	negX := prod[2*n:].Copy()
+	negX.Add(prod[n : 2*n])
+
+	// |x| = x - 2 * neg * x
+	u := x
+	negX.MulScalar(rtype.FromInt(2))
+	u.Sub(negX)
+
+	g := mpcObj.EvaluateApprox(sigmoidApprox.ap, u)
+
+	// sigmoid(x) = g + neg * (1 - 2g)
+	flip := g.Copy()
+	flip.MulScalar(rtype.FromInt(-2))
+	if pid == 1 {
+		flip.AddScalar(rtype.FromFloat64(1, fracBits))
 	}
-	res := mpcObj.Divide(top, bottom, false)
-	return mpcObj.BeaverReconstructVec(res)
+	res := g
+	res.Add(mpcObj.SSMultElemVec(bits[2*n:], flip))
+
+	return res
 }
 
 // func (mpcObj *MPC) SSTrigVec(a mpc_core.RVec) (mpc_core.RVec, mpc_core.RVec) {
@@ -47,6 +110,8 @@ func (mpcObj *MPC) SSSigmoidVec(a mpc_core.RVec) mpc_core.RVec {
 // 	return sin, cos
 // }
 
+// SSTrigVec computes sin and cos with the Beaver trig protocol. Its accuracy depends on the
+// ring wrapping around consistently with 2*pi; use SSSinCosVec for inputs of arbitrary range.
 func (mpcObj *MPC) SSTrigVec(a mpc_core.RVec) (mpc_core.RVec, mpc_core.RVec) {
 	// Partition the vector into ar (the masked part) and am (the mask)
 	ar, am := mpcObj.BeaverPartitionVec(a)
@@ -64,11 +129,7 @@ func (mpcObj *MPC) SSTrigVec(a mpc_core.RVec) (mpc_core.RVec, mpc_core.RVec) {
 	}
 
 	// Proceed with computing sin and cos using the Beaver method.
-	sin := mpc_core.InitRVec(mpcObj.rtype.Zero(), len(a))
-	cos := mpc_core.InitRVec(mpcObj.rtype.Zero(), len(a))
-	for i := range sin {
-		sin[i], cos[i] = mpcObj.BeaverSinCos(ar[i], am[i])
-	}
+	sin, cos := mpcObj.BeaverSinCosVec(ar, am)
 
 	if pid == 1 {
 		endTime := time.Now()
@@ -95,11 +156,7 @@ func (mpcObj *MPC) SSTrigVecRepeated(a mpc_core.RVec, iterations int) (mpc_core.
 
 		startTime := time.Now()
 		// Compute sin and cos using the Beaver method.
-		sin = mpc_core.InitRVec(mpcObj.rtype.Zero(), len(a))
-		cos = mpc_core.InitRVec(mpcObj.rtype.Zero(), len(a))
-		for j := range sin {
-			sin[j], cos[j] = mpcObj.BeaverSinCos(ar[j], am[j])
-		}
+		sin, cos = mpcObj.BeaverSinCosVec(ar, am)
 		// Synchronize again if you need to ensure everyone finished.
 		mpcObj.AssertSync()
 
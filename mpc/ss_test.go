@@ -0,0 +1,25 @@
+package mpc
+
+import (
+	"testing"
+
+	mpc_core "github.com/hhcho/mpc-core"
+)
+
+func TestSSSigmoidVec(t *testing.T) {
+	x := append(linspace(-10, 10, 81), -40, -12.5, 12.5, 40)
+	want := make([]float64, len(x))
+	for i := range x {
+		want[i] = sigmoid(x[i])
+	}
+
+	for _, rtype := range []mpc_core.RElem{mpc_core.LElem256Zero, mpc_core.LElem128Zero} {
+		runParties(t, rtype, 20, func(mpcObj *MPC) {
+			// The output stays secret shared until revealed here
+			got := revealVec(mpcObj, mpcObj.SSSigmoidVec(inputVec(mpcObj, x)))
+			if mpcObj.GetPid() == 1 {
+				checkVec(t, "SSSigmoidVec", x, got, want, 5e-5)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package mpc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeTestKeyPair writes a self-signed certificate for party1 and its key to dir
+func writeTestKeyPair(t *testing.T, dir string) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "party1"},
+		DNSNames:              []string{"party1"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile, keyFile = filepath.Join(dir, "party1.crt"), filepath.Join(dir, "party1.key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestTLSConfigLoad(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeTestKeyPair(t, dir)
+	missing := filepath.Join(dir, "missing.pem")
+	notPEM := filepath.Join(dir, "ca.txt")
+	if err := os.WriteFile(notPEM, []byte("not a certificate\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		conf    TLSConfig
+		errFile string // File the error must name; empty if loading succeeds
+	}{
+		{"valid", TLSConfig{CertFile: certFile, KeyFile: keyFile, CAFile: certFile}, ""},
+		{"missing cert", TLSConfig{CertFile: missing, KeyFile: keyFile, CAFile: certFile}, missing},
+		{"key is not a key", TLSConfig{CertFile: certFile, KeyFile: notPEM, CAFile: certFile}, notPEM},
+		{"missing CA", TLSConfig{CertFile: certFile, KeyFile: keyFile, CAFile: missing}, missing},
+		{"CA without certificates", TLSConfig{CertFile: certFile, KeyFile: keyFile, CAFile: notPEM}, notPEM},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := tt.conf.load()
+			if tt.errFile == "" {
+				if err != nil || m == nil {
+					t.Fatalf("load() = %v, %v; want TLS material", m, err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("load() succeeded; want an error")
+			}
+			if !strings.Contains(err.Error(), tt.errFile) {
+				t.Errorf("load() error %q does not name %s", err, tt.errFile)
+			}
+		})
+	}
+
+	if _, err := NewTransport(TransportTCP, "", nil, &TLSConfig{CertFile: missing, KeyFile: keyFile, CAFile: certFile}); err == nil {
+		t.Error("NewTransport succeeded with a missing certificate file")
+	}
+}
+
+func TestKeyExchangeRequiresTLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeTestKeyPair(t, dir)
+	plain, err := NewTransport(TransportTCP, "", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secured, err := NewTransport(TransportTCP, "", nil, &TLSConfig{CertFile: certFile, KeyFile: keyFile, CAFile: certFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	inproc, err := NewTransport(TransportInProc, "", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name string
+		tr   Transport
+		want bool
+	}{
+		{"tcp", plain, false},
+		{"tcp with TLS", secured, true},
+		{"inproc", inproc, true},
+	} {
+		if got := keyExchangeAuthenticated(tt.tr); got != tt.want {
+			t.Errorf("keyExchangeAuthenticated(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// Refused before any connection is attempted
+	if _, err := InitCommunication(plain, 1, 3, 1, SharedKeyConfig{Exchange: true}, Timeouts{}); err == nil ||
+		!strings.Contains(err.Error(), "use_tls") {
+		t.Errorf("InitCommunication over plaintext TCP with key exchange: err = %v, want a use_tls error", err)
+	}
+}
+
+// testCA issues party certificates signed by a fresh CA
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &testCA{cert: cert, key: key, pool: pool}
+}
+
+// material returns the TLS material of a party holding the certificate of party pid
+func (ca *testCA) material(t *testing.T, pid int) *tlsMaterial {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(int64(pid) + 2),
+		Subject:      pkix.Name{CommonName: pidString(pid)},
+		DNSNames:     []string{pidString(pid)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &tlsMaterial{cert: tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, caPool: ca.pool}
+}
+
+// handshake secures a loopback connection between parties 1 and 2 and returns the error each
+// side gets
+func handshake(t *testing.T, m1, m2 *tlsMaterial) (err1, err2 error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	done := make(chan error)
+	go func() {
+		c2, err := net.Dial("tcp", l.Addr().String())
+		if err == nil {
+			var conn net.Conn
+			if conn, err = m2.secureConn(c2, 2, 1, 5*time.Second); err == nil {
+				conn.Close()
+			}
+		}
+		done <- err
+	}()
+	c1, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err1 := m1.secureConn(c1, 1, 2, 5*time.Second)
+	if err1 == nil {
+		conn.Close()
+	}
+	return err1, <-done
+}
+
+func TestTLSHandshake(t *testing.T) {
+	ca := newTestCA(t)
+	party1, party2, party3 := ca.material(t, 1), ca.material(t, 2), ca.material(t, 3)
+	otherCA := newTestCA(t).material(t, 2)
+
+	if err1, err2 := handshake(t, party1, party2); err1 != nil || err2 != nil {
+		t.Fatalf("handshake with the expected certificates: %v, %v", err1, err2)
+	}
+
+	// Party 3 connecting as party 2: its certificate is signed by the CA, but is not party 2's
+	err1, _ := handshake(t, party1, party3)
+	if err1 == nil || !strings.Contains(err1.Error(), "wrong party certificate") {
+		t.Errorf("party 1 accepting the certificate of party 3: err = %v, want a wrong party certificate error", err1)
+	}
+
+	// Party 3 answering as party 1: the client checks the certificate against the hostname party1
+	_, err2 := handshake(t, party3, party2)
+	if err2 == nil || !strings.Contains(err2.Error(), "party1") {
+		t.Errorf("party 2 accepting the certificate of party 3: err = %v, want a hostname error for party1", err2)
+	}
+
+	// A certificate for party 2 from another CA
+	if err1, _ := handshake(t, party1, otherCA); err1 == nil || !strings.Contains(err1.Error(), "handshake") {
+		t.Errorf("party 1 accepting a certificate from another CA: err = %v, want a handshake error", err1)
+	}
+}
@@ -1 +0,0 @@
-python3 scripts/filterMatrix.py $1 $2 $3 $4 $5 $6
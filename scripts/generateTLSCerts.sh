@@ -0,0 +1,23 @@
+#!/bin/bash
+# Generates a toy CA and one certificate per party for use_tls = true
+# Warning: only meant for toy example generation purposes; each site should
+# keep its own private key and have its certificate signed by the agreed CA
+#
+# Usage: scripts/generateTLSCerts.sh <outdir> <num_parties including party0>
+
+outdir=$1
+np=$2
+
+mkdir -p $outdir
+
+openssl req -x509 -newkey ec -pkeyopt ec_paramgen_curve:prime256v1 -nodes -days 365 \
+    -keyout $outdir/ca.key -out $outdir/ca.crt -subj "/CN=sfgwas-ca"
+
+for ((i = 0; i < np; i++)); do
+    openssl req -newkey ec -pkeyopt ec_paramgen_curve:prime256v1 -nodes \
+        -keyout $outdir/party$i.key -out $outdir/party$i.csr -subj "/CN=party$i"
+    openssl x509 -req -in $outdir/party$i.csr -CA $outdir/ca.crt -CAkey $outdir/ca.key \
+        -CAcreateserial -days 365 -out $outdir/party$i.crt \
+        -extfile <(printf "subjectAltName=DNS:party$i\nextendedKeyUsage=serverAuth,clientAuth")
+    rm $outdir/party$i.csr
+done
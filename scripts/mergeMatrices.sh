@@ -1 +0,0 @@
-python3 scripts/mergeMatrices.py $1 $2 $3 $4
@@ -1 +0,0 @@
-python3 scripts/transposeMatrix.py $1 $2 $3 $4